@@ -10,7 +10,7 @@ import (
 func Load() map[string]string {
 	var env map[string]string = make(map[string]string)
 
-	validEnv := []string{"DB_USER", "DB_PASSWORD", "DB_NAME", "DB_HOST", "DB_PORT", "PORT", "SECRET"}
+	validEnv := []string{"DB_USER", "DB_PASSWORD", "DB_NAME", "DB_HOST", "DB_PORT", "PORT", "SECRET", "DB_VERSION", "DB_DRIVER", "DB_PATH"}
 
 	envpath := "./.env"
 
@@ -59,6 +59,20 @@ func checkDB(env map[string]string) {
 	required := []string{"DB_USER", "DB_PASSWORD", "DB_HOST", "DB_PORT"}
 	optional := []string{"DB_NAME"}
 	defaults := []string{"bookholder"}
+
+	switch env["DB_DRIVER"] {
+	case "", "postgres":
+		env["DB_DRIVER"] = "postgres"
+	case "sqlite":
+		// a file of its own needs no server to connect to
+		required = nil
+		optional = []string{"DB_PATH"}
+		defaults = []string{"bookholder.db"}
+	default:
+		fmt.Println("DB_DRIVER must be postgres or sqlite")
+		os.Exit(1)
+	}
+
 	for _, item := range required {
 		checkEnv(item, env)
 	}
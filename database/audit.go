@@ -0,0 +1,279 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audited entities.
+const (
+	EntityAccount      = "account"
+	EntityTransaction  = "transaction"
+	EntityJournalEntry = "journal_entry"
+	EntityUser         = "user"
+	EntityLedger       = "ledger"
+	EntityMember       = "member"
+	EntityFiscalYear   = "fiscal_year"
+	EntityFiscalPeriod = "fiscal_period"
+)
+
+// Audited actions.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// AuditEntry records one change: who made it, when, and the record before
+// and after. Before is empty for created records, After for deleted ones.
+// Ledger is 0 for changes that do not belong to a ledger, like users.
+//
+// Every ledger has a hash chain of its own: each entry carries the hash of
+// the entry of the ledger before it, so removing or changing an entry breaks
+// the chain from that point on. Changes outside of ledgers, which are those
+// of users, are chained per user.
+type AuditEntry struct {
+	ID       uint
+	Ledger   uint
+	User     string
+	Entity   string
+	EntityID string
+	Action   string
+	Before   json.RawMessage
+	After    json.RawMessage
+	Time     time.Time
+	PrevHash string
+	Hash     string
+}
+
+// AuditFilter narrows GetAuditLog down. Zero fields do not filter.
+type AuditFilter struct {
+	Entity string
+	User   string
+	From   time.Time
+	To     time.Time
+}
+
+// AuditVerification is the result of walking the hash chain. BrokenAt is the
+// id of the first entry that does not match, or 0 when the chain is intact.
+type AuditVerification struct {
+	Valid    bool
+	Checked  int
+	BrokenAt uint
+	Reason   string
+}
+
+// auditedUser is what the audit log keeps of a user; passwords stay out.
+type auditedUser struct {
+	ID   string
+	Name string
+}
+
+// hash computes the hash of the entry, which covers every field but the id
+// and the hash itself.
+func (entry AuditEntry) hash() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		entry.PrevHash,
+		strconv.FormatUint(uint64(entry.Ledger), 10),
+		entry.User,
+		entry.Entity,
+		entry.EntityID,
+		entry.Action,
+		string(entry.Before),
+		string(entry.After),
+		entry.Time.UTC().Format(time.RFC3339Nano),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordAudit appends an entry to the chain of its ledger, or of its user. It
+// has to run in the same database transaction as the change it records; the
+// row of the ledger, or the user, is updated first, which locks it and keeps
+// the chain in order until that transaction ends, while other ledgers go on
+// writing theirs.
+func recordAudit(database querier, ledger uint, user string, entity string, entityID any, action string, before any, after any) error {
+	entry := AuditEntry{
+		Ledger:   ledger,
+		User:     user,
+		Entity:   entity,
+		EntityID: toAuditID(entityID),
+		Action:   action,
+		// the database keeps microseconds, so the hash must not see more
+		Time: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	var err error
+	entry.Before, err = toAuditValue(before)
+	if err != nil {
+		return err
+	}
+	entry.After, err = toAuditValue(after)
+	if err != nil {
+		return err
+	}
+
+	if ledger != 0 {
+		_, err = database.Exec("UPDATE ledgers SET id = id WHERE id = $1", ledger)
+		if err == nil {
+			err = database.QueryRow("SELECT hash FROM audit_log WHERE ledger_id = $1 ORDER BY id DESC LIMIT 1", ledger).Scan(&entry.PrevHash)
+		}
+	} else {
+		_, err = database.Exec("UPDATE users SET id = id WHERE id = $1", entry.EntityID)
+		if err == nil {
+			err = database.QueryRow("SELECT hash FROM audit_log WHERE ledger_id IS NULL AND entity = $1 AND entity_id = $2 ORDER BY id DESC LIMIT 1", entry.Entity, entry.EntityID).Scan(&entry.PrevHash)
+		}
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	entry.Hash = entry.hash()
+	_, err = database.Exec("INSERT INTO audit_log (ledger_id, user_id, entity, entity_id, action, before, after, created_at, prev_hash, hash) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", nullID(entry.Ledger), entry.User, entry.Entity, entry.EntityID, entry.Action, nullText(entry.Before), nullText(entry.After), entry.Time, entry.PrevHash, entry.Hash)
+	return err
+}
+
+func toAuditID(id any) string {
+	switch id := id.(type) {
+	case string:
+		return id
+	case uint:
+		return strconv.FormatUint(uint64(id), 10)
+	case int:
+		return strconv.Itoa(id)
+	}
+	return ""
+}
+
+// toAuditValue encodes a record for the log. The JSON is stored as text, not
+// jsonb, so it comes back byte for byte and the hash can be checked.
+func toAuditValue(value any) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if user, ok := value.(User); ok {
+		value = auditedUser{ID: user.ID, Name: user.Name}
+	}
+	return json.Marshal(value)
+}
+
+func nullText(value json.RawMessage) any {
+	if value == nil {
+		return nil
+	}
+	return string(value)
+}
+
+const auditColumns = "id, COALESCE(ledger_id, 0), user_id, entity, entity_id, action, before, after, created_at, prev_hash, hash"
+
+// scanAuditEntry reads a row selected with auditColumns.
+func scanAuditEntry(row rowScanner) (AuditEntry, error) {
+	var entry AuditEntry
+	var before, after sql.NullString
+	err := row.Scan(&entry.ID, &entry.Ledger, &entry.User, &entry.Entity, &entry.EntityID, &entry.Action, &before, &after, &entry.Time, &entry.PrevHash, &entry.Hash)
+	if err != nil {
+		return entry, err
+	}
+	if before.Valid {
+		entry.Before = json.RawMessage(before.String)
+	}
+	if after.Valid {
+		entry.After = json.RawMessage(after.String)
+	}
+	return entry, nil
+}
+
+func scanAuditEntries(rows *sql.Rows) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetAuditLog returns the changes made to the ledger, oldest first, together
+// with the changes members of the ledger made to their users.
+func GetAuditLog(database *sql.DB, scope Scope, filter AuditFilter) ([]AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE (ledger_id = $1 OR (ledger_id IS NULL AND entity = $2 AND entity_id IN (SELECT CAST(user_id AS character varying) FROM ledger_members WHERE ledger_id = $1)))"
+	args := []any{scope.Ledger, EntityUser}
+
+	if filter.Entity != "" {
+		args = append(args, filter.Entity)
+		query += " AND entity = $" + strconv.Itoa(len(args))
+	}
+	if filter.User != "" {
+		args = append(args, filter.User)
+		query += " AND CAST(user_id AS character varying) = $" + strconv.Itoa(len(args))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += " AND created_at >= $" + strconv.Itoa(len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += " AND created_at <= $" + strconv.Itoa(len(args))
+	}
+
+	rows, err := database.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuditEntries(rows)
+}
+
+// VerifyAuditLog walks the hash chain of the ledger, one entry at a time
+// straight from the cursor, and reports the first entry that was changed, or
+// that follows a removed entry.
+func VerifyAuditLog(database *sql.DB, scope Scope) (AuditVerification, error) {
+	var verification AuditVerification
+
+	rows, err := database.Query("SELECT "+auditColumns+" FROM audit_log WHERE ledger_id = $1 ORDER BY id", scope.Ledger)
+	if err != nil {
+		return verification, err
+	}
+	defer rows.Close()
+
+	previous := ""
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return verification, err
+		}
+
+		verification.Checked++
+		if entry.PrevHash != previous {
+			verification.BrokenAt = entry.ID
+			verification.Reason = "previous hash does not match; an entry before it was changed or removed"
+			return verification, nil
+		}
+		if entry.hash() != entry.Hash {
+			verification.BrokenAt = entry.ID
+			verification.Reason = "hash does not match; the entry was changed"
+			return verification, nil
+		}
+		previous = entry.Hash
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return verification, err
+	}
+
+	verification.Valid = true
+	return verification, nil
+}
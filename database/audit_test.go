@@ -0,0 +1,204 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// insertAuditedChanges creates, updates and deletes an account.
+func insertAuditedChanges(t *testing.T) {
+	insertBooks(t, []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}}, nil, nil)
+
+	err := UpdateAccount(db, testScope, Account{ID: 1200, Name: "Bank account", Kind: KindAsset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DeleteAccount(db, testScope, 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetAuditLog(t *testing.T) {
+	insertAuditedChanges(t)
+
+	entries, err := GetAuditLog(db, testScope, AuditFilter{Entity: EntityAccount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, ActionCreate, entries[0].Action)
+		assert.Nil(t, entries[0].Before)
+		assert.Equal(t, ActionUpdate, entries[1].Action)
+		assert.JSONEq(t, `{"ID": 1200, "Name": "Bank", "Kind": "asset", "Parent": 0, "Group": false}`, string(entries[1].Before))
+		assert.JSONEq(t, `{"ID": 1200, "Name": "Bank account", "Kind": "asset", "Parent": 0, "Group": false}`, string(entries[1].After))
+		assert.Equal(t, ActionDelete, entries[2].Action)
+		assert.Nil(t, entries[2].After)
+		assert.Equal(t, testScope.User, entries[2].User)
+		assert.Equal(t, entries[1].Hash, entries[2].PrevHash)
+	}
+
+	entries, err = GetAuditLog(db, testScope, AuditFilter{From: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Empty(t, entries)
+}
+
+func TestGetAuditLogOtherLedger(t *testing.T) {
+	insertAuditedChanges(t)
+	other := insertOtherLedger(t)
+
+	entries, err := GetAuditLog(db, other, AuditFilter{Entity: EntityAccount})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Empty(t, entries)
+}
+
+func TestAuditLogUserPassword(t *testing.T) {
+	cleanTables()
+	err := NewUser(db, User{Name: "Audited User", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var after string
+	err = db.QueryRow("SELECT after FROM audit_log WHERE entity = $1", EntityUser).Scan(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, after, "secret")
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	insertAuditedChanges(t)
+
+	verification, err := VerifyAuditLog(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 3, verification.Checked)
+}
+
+func TestVerifyAuditLogChangedEntry(t *testing.T) {
+	insertAuditedChanges(t)
+
+	var id uint
+	err := db.QueryRow("UPDATE audit_log SET after = '{}' WHERE action = $1 RETURNING id", ActionUpdate).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err := VerifyAuditLog(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, verification.Valid)
+	assert.Equal(t, id, verification.BrokenAt)
+}
+
+func TestVerifyAuditLogRemovedEntry(t *testing.T) {
+	insertAuditedChanges(t)
+
+	_, err := db.Exec("DELETE FROM audit_log WHERE action = $1", ActionUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id uint
+	err = db.QueryRow("SELECT id FROM audit_log WHERE action = $1", ActionDelete).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err := VerifyAuditLog(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, verification.Valid)
+	assert.Equal(t, id, verification.BrokenAt)
+}
+
+func TestVerifyAuditLogOtherLedger(t *testing.T) {
+	insertAuditedChanges(t)
+	other := insertOtherLedger(t)
+
+	err := NewAccount(db, other, Account{ID: 1200, Name: "Bank", Kind: KindAsset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a broken chain of another ledger does not break this one
+	_, err = db.Exec("UPDATE audit_log SET after = '{}' WHERE ledger_id = $1", other.Ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verification, err := VerifyAuditLog(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 3, verification.Checked)
+
+	verification, err = VerifyAuditLog(db, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, verification.Valid)
+}
+
+func TestAuditLogLedgerSettings(t *testing.T) {
+	cleanTables()
+	other := insertOtherLedger(t)
+
+	year, err := NewFiscalYear(db, other, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, other, int(year.Periods[0].ID), PeriodSoftClosed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	err = db.QueryRow("SELECT name FROM users WHERE id = $1", testScope.User).Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = AddMember(db, other, name, RoleViewer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UpdateMember(db, other, testScope.User, RoleAuditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RemoveMember(db, other, testScope.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := GetAuditLog(db, other, AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var changes []string
+	for _, entry := range entries {
+		changes = append(changes, entry.Entity+" "+entry.Action)
+	}
+	assert.Equal(t, []string{"ledger create", "fiscal_year create", "fiscal_period update", "member create", "member update", "member delete"}, changes)
+	if assert.Len(t, entries, 6) {
+		assert.JSONEq(t, `{"User": "`+testScope.User+`", "Name": "`+name+`", "Role": "viewer"}`, string(entries[4].Before))
+	}
+}
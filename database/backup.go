@@ -0,0 +1,560 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BackupVersion is the version of the backup format. It goes up whenever the
+// records of a backup change, and only backups of this version are restored.
+const BackupVersion = 2
+
+var ErrBackupVersion = fmt.Errorf("unsupported backup version; must be %d", BackupVersion)
+
+// ErrBackupSchema is returned for a backup taken from a database that has
+// migrations this one lacks.
+var ErrBackupSchema = errors.New("backup is from a newer schema; migrate this database first")
+
+// Backup is the whole of a ledger as it moves between installations: the
+// chart of accounts, every transaction and journal entry, the fiscal years
+// with their periods and opening balances, the members and the settings,
+// rules and import profiles. Members are known by their user name, as user
+// ids differ between installations. Bank lines, reconciliation matches,
+// approvals and the audit log stay with the installation they were made in.
+// SchemaVersion is the schema version of the database the backup was taken
+// from; it is restored only into a database at that version or later.
+type Backup struct {
+	Version         int
+	SchemaVersion   int
+	Ledger          string
+	Created         time.Time
+	Accounts        []Account
+	Transactions    []Transaction
+	JournalEntries  []JournalEntry
+	FiscalYears     []FiscalYear
+	OpeningBalances []BackupOpeningBalance
+	Members         []Member
+	Rules           []Rule
+	ImportProfiles  []ImportProfile
+}
+
+// BackupOpeningBalance is an opening balance with the id of its fiscal year
+// in the backup.
+type BackupOpeningBalance struct {
+	FiscalYear uint
+	Account    uint
+	Balance    Money
+}
+
+// RestoreResult tells what a restore created. SkippedMembers are the user
+// names of members that have no user in this installation.
+type RestoreResult struct {
+	Ledger         uint
+	Accounts       int
+	Transactions   int
+	JournalEntries int
+	FiscalYears    int
+	SkippedMembers []string
+}
+
+// GetBackup loads the backup of the ledger. Everything is read in one
+// snapshot, so bookings made meanwhile are either wholly in it or not at all.
+func GetBackup(database *sql.DB, scope Scope) (Backup, error) {
+	backup := Backup{Version: BackupVersion, Created: time.Now().UTC()}
+
+	var err error
+	backup.SchemaVersion, err = SchemaVersion(database)
+	if err != nil {
+		return backup, err
+	}
+
+	tx, err := database.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return backup, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT name FROM ledgers WHERE id = $1", scope.Ledger).Scan(&backup.Ledger)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return backup, ErrLedgerNotExists
+		}
+		return backup, err
+	}
+
+	backup.Accounts, err = backupAccounts(tx, scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+
+	backup.Transactions, err = queryTransactions(tx, "SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 ORDER BY id", scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+
+	rows, err := tx.Query("SELECT "+journalEntryColumns+" FROM journal_entries e JOIN journal_lines l ON l.entry_id = e.id WHERE e.ledger_id = $1 ORDER BY e.id, l.id", scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+	backup.JournalEntries, err = scanJournalEntries(rows)
+	rows.Close()
+	if err != nil {
+		return backup, err
+	}
+
+	backup.FiscalYears, err = getFiscalYears(tx, scope, 0)
+	if err != nil {
+		return backup, err
+	}
+
+	backup.OpeningBalances, err = backupOpeningBalances(tx, scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+
+	backup.Members, err = backupMembers(tx, scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+
+	backup.Rules, err = getRules(tx, scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+
+	backup.ImportProfiles, err = backupImportProfiles(tx, scope.Ledger)
+	if err != nil {
+		return backup, err
+	}
+
+	return backup, tx.Commit()
+}
+
+func backupAccounts(database querier, ledger uint) ([]Account, error) {
+	rows, err := database.Query("SELECT id, name, kind, parent, is_group FROM accounts WHERE ledger_id = $1 ORDER BY id", ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []Account
+	for rows.Next() {
+		var account Account
+		var parent sql.NullInt64
+		err := rows.Scan(&account.ID, &account.Name, &account.Kind, &parent, &account.Group)
+		if err != nil {
+			return nil, err
+		}
+		account.Parent = uint(parent.Int64)
+		accounts = append(accounts, account)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func backupOpeningBalances(database querier, ledger uint) ([]BackupOpeningBalance, error) {
+	rows, err := database.Query("SELECT fiscal_year_id, account, balance FROM opening_balances WHERE ledger_id = $1 ORDER BY fiscal_year_id, account", ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []BackupOpeningBalance
+	for rows.Next() {
+		var balance BackupOpeningBalance
+		err := rows.Scan(&balance.FiscalYear, &balance.Account, &balance.Balance)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+func backupMembers(database querier, ledger uint) ([]Member, error) {
+	rows, err := database.Query("SELECT u.id, u.name, m.role FROM ledger_members m JOIN users u ON u.id = m.user_id WHERE m.ledger_id = $1 ORDER BY u.name", ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []Member
+	for rows.Next() {
+		var member Member
+		err := rows.Scan(&member.User, &member.Name, &member.Role)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func backupImportProfiles(database querier, ledger uint) ([]ImportProfile, error) {
+	rows, err := database.Query("SELECT id, definition FROM import_profiles WHERE ledger_id = $1 ORDER BY name", ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []ImportProfile
+	for rows.Next() {
+		var profile ImportProfile
+		var id uint
+		var definition string
+		err := rows.Scan(&id, &definition)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(definition), &profile)
+		if err != nil {
+			return nil, err
+		}
+		profile.ID = id
+		profiles = append(profiles, profile)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// ValidateBackup checks a backup before it is restored: its version, and
+// that its records are complete and refer to each other.
+func ValidateBackup(backup Backup) error {
+	if backup.Version != BackupVersion {
+		return ErrBackupVersion
+	}
+
+	if backup.SchemaVersion < 1 {
+		return errors.New("schema version is required")
+	}
+
+	if backup.Ledger == "" {
+		return errors.New("ledger name is required")
+	}
+
+	accounts := make(map[uint]bool)
+	for _, account := range backup.Accounts {
+		if account.ID < 1 {
+			return errors.New("account: id is required")
+		}
+		if accounts[account.ID] {
+			return fmt.Errorf("account %d: listed twice", account.ID)
+		}
+		if !ValidKind(account.Kind) {
+			return fmt.Errorf("account %d: %w", account.ID, ErrInvalidKind)
+		}
+		accounts[account.ID] = true
+	}
+	for _, account := range backup.Accounts {
+		if account.Parent != 0 && !accounts[account.Parent] {
+			return fmt.Errorf("account %d: parent %d is not in the backup", account.ID, account.Parent)
+		}
+	}
+
+	transactions := make(map[uint]bool)
+	for _, transaction := range backup.Transactions {
+		err := validateTransaction(transaction)
+		if err != nil {
+			return fmt.Errorf("transaction %d: %w", transaction.ID, err)
+		}
+		if !ValidStatus(transaction.Status) {
+			return fmt.Errorf("transaction %d: invalid status %q", transaction.ID, transaction.Status)
+		}
+		if !accounts[transaction.Account] || !accounts[transaction.OffsetAccount] {
+			return fmt.Errorf("transaction %d: account is not in the backup", transaction.ID)
+		}
+		if transactions[transaction.ID] {
+			return fmt.Errorf("transaction %d: listed twice", transaction.ID)
+		}
+		transactions[transaction.ID] = true
+	}
+	for _, transaction := range backup.Transactions {
+		if transaction.Reverses != 0 && !transactions[transaction.Reverses] {
+			return fmt.Errorf("transaction %d: reversed transaction %d is not in the backup", transaction.ID, transaction.Reverses)
+		}
+		if transaction.Replaces != 0 && !transactions[transaction.Replaces] {
+			return fmt.Errorf("transaction %d: replaced transaction %d is not in the backup", transaction.ID, transaction.Replaces)
+		}
+	}
+
+	entries := make(map[uint]bool)
+	for _, entry := range backup.JournalEntries {
+		err := ValidateJournalEntry(entry)
+		if err != nil {
+			return fmt.Errorf("journal entry %d: %w", entry.ID, err)
+		}
+		if entry.Type != EntryStandard && entry.Type != EntryClosing {
+			return fmt.Errorf("journal entry %d: invalid type %q", entry.ID, entry.Type)
+		}
+		if !ValidStatus(entry.Status) {
+			return fmt.Errorf("journal entry %d: invalid status %q", entry.ID, entry.Status)
+		}
+		for _, line := range entry.Lines {
+			if !accounts[line.Account] {
+				return fmt.Errorf("journal entry %d: account %d is not in the backup", entry.ID, line.Account)
+			}
+		}
+		entries[entry.ID] = true
+	}
+
+	years := make(map[uint]bool)
+	for _, year := range backup.FiscalYears {
+		if year.Start.IsZero() || year.End.Before(year.Start) {
+			return fmt.Errorf("fiscal year %d: invalid dates", year.ID)
+		}
+		if year.ClosingEntry != 0 && !entries[year.ClosingEntry] {
+			return fmt.Errorf("fiscal year %d: closing entry %d is not in the backup", year.ID, year.ClosingEntry)
+		}
+		for _, period := range year.Periods {
+			if !ValidPeriodStatus(period.Status) {
+				return fmt.Errorf("fiscal year %d: %w", year.ID, ErrInvalidPeriodStatus)
+			}
+		}
+		years[year.ID] = true
+	}
+
+	for _, balance := range backup.OpeningBalances {
+		if !years[balance.FiscalYear] || !accounts[balance.Account] {
+			return fmt.Errorf("opening balance of account %d: fiscal year or account is not in the backup", balance.Account)
+		}
+	}
+
+	for _, member := range backup.Members {
+		if !ValidRole(member.Role) {
+			return fmt.Errorf("member %s: %w", member.Name, ErrInvalidRole)
+		}
+	}
+
+	for _, rule := range backup.Rules {
+		err := ValidateRule(rule)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Name, err)
+		}
+		if rule.OffsetAccount != 0 && !accounts[rule.OffsetAccount] {
+			return fmt.Errorf("rule %s: offset account %d is not in the backup", rule.Name, rule.OffsetAccount)
+		}
+	}
+
+	for _, profile := range backup.ImportProfiles {
+		err := ValidateImportProfile(profile)
+		if err != nil {
+			return fmt.Errorf("import profile %s: %w", profile.Name, err)
+		}
+	}
+	return nil
+}
+
+// RestoreBackup creates a new ledger owned by user from a backup, in one
+// database transaction: either all of the backup is restored or nothing is.
+// Transactions, journal entries and fiscal years get new ids; accounts keep
+// theirs. Members are matched to the users of this installation by name and
+// the restoring user becomes an owner. Bookings are restored as they were,
+// into closed periods as well.
+func RestoreBackup(database *sql.DB, user string, backup Backup) (RestoreResult, error) {
+	var result RestoreResult
+
+	// records of a newer schema may not validate against this one
+	current, err := SchemaVersion(database)
+	if err != nil {
+		return result, err
+	}
+	if backup.SchemaVersion > current {
+		return result, fmt.Errorf("backup has schema version %d, this database %d: %w", backup.SchemaVersion, current, ErrBackupSchema)
+	}
+
+	err = ValidateBackup(backup)
+	if err != nil {
+		return result, err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("INSERT INTO ledgers (name, owner) VALUES ($1, $2) RETURNING id", backup.Ledger, user).Scan(&result.Ledger)
+	if err != nil {
+		return result, err
+	}
+	scope := Scope{Ledger: result.Ledger, User: user, Role: RoleOwner}
+
+	_, err = tx.Exec("INSERT INTO ledger_members (ledger_id, user_id, role) VALUES ($1, $2, $3)", scope.Ledger, user, RoleOwner)
+	if err != nil {
+		return result, err
+	}
+
+	// users are matched by name, the ids they had go to the ids they have
+	users := make(map[string]string)
+	for _, member := range backup.Members {
+		var id string
+		err := tx.QueryRow("SELECT id FROM users WHERE name = $1", member.Name).Scan(&id)
+		if err == sql.ErrNoRows {
+			result.SkippedMembers = append(result.SkippedMembers, member.Name)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		users[member.User] = id
+
+		if id == user {
+			continue
+		}
+		_, err = tx.Exec("INSERT INTO ledger_members (ledger_id, user_id, role) VALUES ($1, $2, $3)", scope.Ledger, id, member.Role)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// parents are set once every account exists
+	for _, account := range backup.Accounts {
+		_, err = tx.Exec("INSERT INTO accounts (ledger_id, id, name, kind, is_group) VALUES ($1, $2, $3, $4, $5)", scope.Ledger, account.ID, account.Name, account.Kind, account.Group)
+		if err != nil {
+			return result, err
+		}
+	}
+	for _, account := range backup.Accounts {
+		if account.Parent == 0 {
+			continue
+		}
+		_, err = tx.Exec("UPDATE accounts SET parent = $1 WHERE ledger_id = $2 AND id = $3", account.Parent, scope.Ledger, account.ID)
+		if err != nil {
+			return result, err
+		}
+	}
+	result.Accounts = len(backup.Accounts)
+
+	// references between transactions are set once every transaction exists
+	transactions := make(map[uint]uint)
+	for _, transaction := range backup.Transactions {
+		preparedBy, ok := users[transaction.PreparedBy]
+		if !ok {
+			preparedBy = user
+		}
+
+		var id uint
+		err = tx.QueryRow("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description, tax_code, status, prepared_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id", scope.Ledger, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description, transaction.TaxCode, transaction.Status, preparedBy).Scan(&id)
+		if err != nil {
+			return result, err
+		}
+		transactions[transaction.ID] = id
+	}
+	for _, transaction := range backup.Transactions {
+		if transaction.Reverses == 0 && transaction.Replaces == 0 {
+			continue
+		}
+		_, err = tx.Exec("UPDATE transactions SET reverses = $1, replaces = $2 WHERE id = $3", nullID(transactions[transaction.Reverses]), nullID(transactions[transaction.Replaces]), transactions[transaction.ID])
+		if err != nil {
+			return result, err
+		}
+	}
+	result.Transactions = len(backup.Transactions)
+
+	entries := make(map[uint]uint)
+	for _, entry := range backup.JournalEntries {
+		preparedBy, ok := users[entry.PreparedBy]
+		if !ok {
+			preparedBy = user
+		}
+
+		var id uint
+		err = tx.QueryRow("INSERT INTO journal_entries (ledger_id, date, description, type, status, prepared_by) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id", scope.Ledger, entry.Date, entry.Description, entry.Type, entry.Status, preparedBy).Scan(&id)
+		if err != nil {
+			return result, err
+		}
+
+		for _, line := range entry.Lines {
+			_, err = tx.Exec("INSERT INTO journal_lines (entry_id, ledger_id, account, amount, debit) VALUES ($1, $2, $3, $4, $5)", id, scope.Ledger, line.Account, line.Amount, line.Debit)
+			if err != nil {
+				return result, err
+			}
+		}
+		entries[entry.ID] = id
+	}
+	result.JournalEntries = len(backup.JournalEntries)
+
+	years := make(map[uint]uint)
+	for _, year := range backup.FiscalYears {
+		var id uint
+		err = tx.QueryRow("INSERT INTO fiscal_years (ledger_id, start_date, end_date, closed, closing_entry_id) VALUES ($1, $2, $3, $4, $5) RETURNING id", scope.Ledger, day(year.Start), day(year.End), year.Closed, nullID(entries[year.ClosingEntry])).Scan(&id)
+		if err != nil {
+			return result, err
+		}
+
+		for _, period := range year.Periods {
+			_, err = tx.Exec("INSERT INTO fiscal_periods (fiscal_year_id, ledger_id, number, start_date, end_date, status) VALUES ($1, $2, $3, $4, $5, $6)", id, scope.Ledger, period.Number, day(period.Start), day(period.End), period.Status)
+			if err != nil {
+				return result, err
+			}
+		}
+		years[year.ID] = id
+	}
+	result.FiscalYears = len(backup.FiscalYears)
+
+	for _, balance := range backup.OpeningBalances {
+		_, err = tx.Exec("INSERT INTO opening_balances (fiscal_year_id, ledger_id, account, balance) VALUES ($1, $2, $3, $4)", years[balance.FiscalYear], scope.Ledger, balance.Account, balance.Balance)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for _, rule := range backup.Rules {
+		conditions, err := json.Marshal(rule.Conditions)
+		if err != nil {
+			return result, err
+		}
+
+		_, err = tx.Exec("INSERT INTO rules (ledger_id, name, priority, conditions, offset_account, description, tax_code, assign, matches, last_matched) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", scope.Ledger, rule.Name, rule.Priority, string(conditions), nullAccount(rule.OffsetAccount), rule.Description, rule.TaxCode, rule.Assign, rule.Matches, nullDate(rule.LastMatched))
+		if err != nil {
+			return result, err
+		}
+	}
+
+	for _, profile := range backup.ImportProfiles {
+		profile.ID = 0
+		definition, err := json.Marshal(profile)
+		if err != nil {
+			return result, err
+		}
+
+		_, err = tx.Exec("INSERT INTO import_profiles (ledger_id, name, definition) VALUES ($1, $2, $3)", scope.Ledger, profile.Name, string(definition))
+		if err != nil {
+			return result, err
+		}
+	}
+
+	err = recordAudit(tx, scope.Ledger, user, EntityLedger, scope.Ledger, ActionCreate, nil, result)
+	if err != nil {
+		return result, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRestore(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertMemberUser(t, "Tax Advisor")
+	_, err = AddMember(db, testScope, "Tax Advisor", RoleAuditor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var corrected uint
+	for _, transaction := range []Transaction{
+		{Amount: 50000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 20000, Debit: true, Account: 4900, OffsetAccount: 1200, Date: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 7000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+	} {
+		corrected, err = NewTransaction(db, testScope, transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = CloseFiscalYear(db, testScope, int(year.ID), 860)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the correction reverses the booking and replaces it with a draft
+	_, err = UpdateTransaction(db, testScope, Transaction{ID: corrected, Amount: 7500, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewRule(db, testScope, Rule{Name: "Telephone", Conditions: []RuleCondition{{Field: FieldCounterparty, Operator: OperatorContains, Value: "telekom"}}, OffsetAccount: 4900})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewImportProfile(db, testScope, ImportProfile{Name: "Bank", BookingDate: "Date", Amount: "Amount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := GetBackup(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, BackupVersion, backup.Version)
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, version, backup.SchemaVersion)
+	assert.Equal(t, "Test Ledger", backup.Ledger)
+	assert.Len(t, backup.Accounts, 4)
+	assert.Len(t, backup.Transactions, 5)
+	assert.Len(t, backup.JournalEntries, 1)
+	assert.Len(t, backup.FiscalYears, 2)
+	assert.Len(t, backup.OpeningBalances, 2)
+	assert.Len(t, backup.Members, 2)
+	assert.Len(t, backup.Rules, 1)
+	assert.Len(t, backup.ImportProfiles, 1)
+
+	backup.Members = append(backup.Members, Member{User: "00000000-0000-0000-0000-000000000000", Name: "Gone", Role: RoleViewer})
+	result, err := RestoreBackup(db, testScope.User, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, testScope.Ledger, result.Ledger)
+	assert.Equal(t, 5, result.Transactions)
+	assert.Equal(t, []string{"Gone"}, result.SkippedMembers)
+
+	restored := Scope{Ledger: result.Ledger, User: testScope.User, Role: RoleOwner}
+	original, err := GetTrialBalance(db, testScope, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, err := GetTrialBalance(db, restored, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, original.Lines, copied.Lines)
+
+	members, err := GetMembers(db, restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, members, 2)
+
+	again, err := GetBackup(db, restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, again.Transactions[2].ID, again.Transactions[3].Reverses)
+	assert.Equal(t, again.Transactions[2].ID, again.Transactions[4].Replaces)
+	assert.Equal(t, again.JournalEntries[0].ID, again.FiscalYears[0].ClosingEntry)
+	assert.Equal(t, PeriodLocked, again.FiscalYears[0].Periods[0].Status)
+}
+
+func TestRestoreBackupRejected(t *testing.T) {
+	cleanTables()
+	backup := Backup{
+		Version:       BackupVersion,
+		SchemaVersion: 1,
+		Ledger:        "Broken",
+		Accounts:      []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}},
+		Transactions: []Transaction{
+			{ID: 1, Amount: 100, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		},
+	}
+
+	_, err := RestoreBackup(db, testScope.User, backup)
+	assert.ErrorContains(t, err, "account is not in the backup")
+
+	backup.Version = BackupVersion + 1
+	_, err = RestoreBackup(db, testScope.User, backup)
+	assert.ErrorIs(t, err, ErrBackupVersion)
+
+	backup.Version = BackupVersion
+	backup.SchemaVersion = 99
+	_, err = RestoreBackup(db, testScope.User, backup)
+	assert.ErrorIs(t, err, ErrBackupSchema)
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM ledgers WHERE name = 'Broken'").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, count)
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// postings lists every booking line of every ledger with its debit and credit
+// amount. A transaction contributes one line for its account and one for its
+// offset account, so balances do not care how a booking was entered.
+// Transactions and journal entries that are not posted are left out. Queries
+// over postings have to filter on ledger_id.
+const postings = `SELECT ledger_id, 'transaction' AS source, id AS entry_id, 1 AS line_id, account, date, COALESCE(description, '') AS description, CASE WHEN debit THEN amount ELSE 0 END AS debit_amount, CASE WHEN debit THEN 0 ELSE amount END AS credit_amount, 'standard' AS entry_type FROM transactions WHERE status = 'posted'
+UNION ALL
+SELECT ledger_id, 'transaction', id, 2, offset_account, date, COALESCE(description, ''), CASE WHEN debit THEN 0 ELSE amount END, CASE WHEN debit THEN amount ELSE 0 END, 'standard' FROM transactions WHERE status = 'posted'
+UNION ALL
+SELECT e.ledger_id, 'journal', e.id, l.id, l.account, e.date, COALESCE(e.description, ''), CASE WHEN l.debit THEN l.amount ELSE 0 END, CASE WHEN l.debit THEN 0 ELSE l.amount END, e.type FROM journal_lines l JOIN journal_entries e ON e.id = l.entry_id WHERE e.status = 'posted'`
+
+// Balance is the debit total, credit total and net balance (debit minus
+// credit) of an account.
+type Balance struct {
+	Account uint
+	AsOf    time.Time
+	Debit   Money
+	Credit  Money
+	Balance Money
+}
+
+type AccountLedgerLine struct {
+	Source      string
+	EntryID     uint
+	Date        time.Time
+	Description string
+	Debit       Money
+	Credit      Money
+	Balance     Money
+}
+
+// AccountLedger is the account statement for a period: the balance brought
+// forward, every booking with the running balance after it, and the closing
+// balance.
+type AccountLedger struct {
+	Account uint
+	From    time.Time
+	To      time.Time
+	Opening Money
+	Lines   []AccountLedgerLine
+	Closing Money
+}
+
+// endOfDay returns the first instant after the given day, so that a date
+// bound includes every booking made on that day.
+func endOfDay(date time.Time) time.Time {
+	return day(date).AddDate(0, 0, 1)
+}
+
+// day returns the day of date at midnight, the way date columns hold it, so
+// it compares with them as a day on every backend.
+func day(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+// dateRange returns the first day of the year, or of the month when month is
+// not 0, and the first day after it.
+func dateRange(year int, month int) (time.Time, time.Time) {
+	if month == 0 {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0)
+	}
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// GetBalance sums the bookings of an account up to and including asOf. A zero
+// asOf includes every booking.
+func GetBalance(database *sql.DB, scope Scope, account int, asOf time.Time) (Balance, error) {
+	balance := Balance{Account: uint(account), AsOf: asOf}
+
+	exists, err := existAccount(database, scope.Ledger, account)
+	if err != nil {
+		return balance, err
+	}
+	if !exists {
+		return balance, ErrAccountNotExists
+	}
+
+	query := "SELECT CAST(COALESCE(SUM(debit_amount), 0) AS bigint), CAST(COALESCE(SUM(credit_amount), 0) AS bigint) FROM (" + postings + ") p WHERE ledger_id = $1 AND account = $2"
+	args := []any{scope.Ledger, account}
+	if !asOf.IsZero() {
+		query += " AND date < $3"
+		args = append(args, endOfDay(asOf))
+	}
+
+	err = database.QueryRow(query, args...).Scan(&balance.Debit, &balance.Credit)
+	if err != nil {
+		return balance, err
+	}
+
+	balance.Balance = balance.Debit - balance.Credit
+	return balance, nil
+}
+
+// GetAccountLedger returns the bookings of an account between from and to,
+// both inclusive, each with the running balance after it. Zero bounds are
+// open.
+func GetAccountLedger(database *sql.DB, scope Scope, account int, from time.Time, to time.Time) (AccountLedger, error) {
+	ledger := AccountLedger{Account: uint(account), From: from, To: to}
+
+	err := StreamAccountLedger(database, scope, account, from, to, func(line AccountLedgerLine) error {
+		ledger.Lines = append(ledger.Lines, line)
+		return nil
+	})
+	if err != nil {
+		return ledger, err
+	}
+
+	if !from.IsZero() {
+		opening, err := GetBalance(database, scope, account, from.AddDate(0, 0, -1))
+		if err != nil {
+			return ledger, err
+		}
+		ledger.Opening = opening.Balance
+	}
+
+	ledger.Closing = ledger.Opening
+	if len(ledger.Lines) > 0 {
+		ledger.Closing = ledger.Lines[len(ledger.Lines)-1].Balance
+	}
+
+	return ledger, nil
+}
+
+// StreamAccountLedger hands the lines GetAccountLedger returns to fn one at a
+// time, straight from the cursor. It stops at the first error fn returns.
+func StreamAccountLedger(database *sql.DB, scope Scope, account int, from time.Time, to time.Time, fn func(AccountLedgerLine) error) error {
+	exists, err := existAccount(database, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAccountNotExists
+	}
+
+	// the window runs over the whole history up to the end of the period, so
+	// the running balance already includes everything brought forward
+	inner := "SELECT source, entry_id, line_id, date, description, debit_amount, credit_amount, CAST(SUM(debit_amount - credit_amount) OVER (ORDER BY date, entry_id, source, line_id ROWS UNBOUNDED PRECEDING) AS bigint) AS running FROM (" + postings + ") p WHERE ledger_id = $1 AND account = $2"
+	args := []any{scope.Ledger, account}
+	if !to.IsZero() {
+		args = append(args, endOfDay(to))
+		inner += " AND date < $" + strconv.Itoa(len(args))
+	}
+
+	query := "SELECT source, entry_id, date, description, debit_amount, credit_amount, running FROM (" + inner + ") l"
+	if !from.IsZero() {
+		args = append(args, from)
+		query += " WHERE date >= $" + strconv.Itoa(len(args))
+	}
+	query += " ORDER BY date, entry_id, source, line_id"
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line AccountLedgerLine
+		err := rows.Scan(&line.Source, &line.EntryID, &line.Date, &line.Description, &line.Debit, &line.Credit, &line.Balance)
+		if err != nil {
+			return err
+		}
+
+		err = fn(line)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The books the balance tests share: a sale in March and a purchase with
+// input VAT in April.
+var (
+	balanceAccounts = []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}, {ID: 1576, Name: "Input VAT", Kind: KindAsset}, {ID: 3400, Name: "Goods", Kind: KindExpense}, {ID: 8400, Name: "Revenue", Kind: KindRevenue}}
+
+	balanceTransactions = []Transaction{
+		{Amount: 50000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), Description: "Sale", Status: StatusPosted},
+	}
+
+	balanceEntries = []JournalEntry{
+		{Date: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC), Description: "Purchase", Lines: []JournalLine{
+			{Account: 3400, Amount: 10000, Debit: true},
+			{Account: 1576, Amount: 1900, Debit: true},
+			{Account: 1200, Amount: 11900, Debit: false},
+		}, Status: StatusPosted},
+	}
+)
+
+func TestGetBalance(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, Money(50000), balance.Debit)
+	assert.Equal(t, Money(11900), balance.Credit)
+	assert.Equal(t, Money(38100), balance.Balance)
+}
+
+func TestGetBalanceAsOf(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	asOf, _ := time.Parse("2006-01-02", "2024-03-31")
+	balance, err := GetBalance(db, testScope, 1200, asOf)
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, Money(50000), balance.Balance)
+}
+
+func TestGetBalanceAccountNotExists(t *testing.T) {
+	_, err := GetBalance(db, testScope, 9999, time.Time{})
+
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+}
+
+func TestGetLedger(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	ledger, err := GetAccountLedger(db, testScope, 1200, time.Time{}, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, ledger.Lines, 2)
+	assert.Equal(t, Money(50000), ledger.Lines[0].Balance)
+	assert.Equal(t, Money(38100), ledger.Lines[1].Balance)
+	assert.Equal(t, Money(38100), ledger.Closing)
+}
+
+func TestGetLedgerFrom(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	from, _ := time.Parse("2006-01-02", "2024-04-01")
+	ledger, err := GetAccountLedger(db, testScope, 1200, from, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, Money(50000), ledger.Opening)
+	assert.Len(t, ledger.Lines, 1)
+	assert.Equal(t, Money(38100), ledger.Lines[0].Balance)
+}
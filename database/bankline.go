@@ -0,0 +1,474 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBankLineNotExists      = errors.New("bank line does not exist")
+	ErrBankLineBooked         = errors.New("bank line has already been booked")
+	ErrImportProfileNotExists = errors.New("import profile does not exist")
+	ErrImportProfileExists    = errors.New("an import profile with this name already exists")
+	ErrOffsetAccountRequired  = errors.New("offset account is required; no rule suggests one")
+)
+
+// BankLine is one line of a bank statement, staged until it is booked. Amount
+// is positive for money coming in and negative for money going out. Hash
+// identifies the content of the line, so a statement imported twice does not
+// stage its lines twice. Rule is the id of the rule that fired for the line
+// when it was imported, or 0. Transaction is the id of the booking made from
+// the line, or 0 while it is open.
+type BankLine struct {
+	ID               uint
+	Account          uint
+	BookingDate      time.Time
+	ValueDate        time.Time
+	Amount           Money
+	Counterparty     string
+	CounterpartyIBAN string
+	Remittance       string
+	Reference        string
+	Source           string
+	Hash             string
+	Rule             uint
+	Transaction      uint
+}
+
+// ImportResult tells which lines of an import were staged and which were
+// left out because they had been imported before. Bookings holds, for every
+// imported line, the draft it is booked as with what its rule suggests.
+type ImportResult struct {
+	Imported   []BankLine
+	Duplicates []BankLine
+	Bookings   []Transaction
+}
+
+// ImportProfile describes the CSV export of one bank. Columns are named by
+// their header. The amount is either a signed Amount column, separate Debit
+// and Credit columns, or an unsigned Amount column with an Indicator column
+// that holds DebitIndicator for money going out. InvertSign is for exports
+// that show money going out as positive.
+type ImportProfile struct {
+	ID             uint
+	Name           string
+	Separator      string
+	SkipRows       int
+	Encoding       string
+	DateFormat     string
+	DecimalComma   bool
+	InvertSign     bool
+	BookingDate    string
+	ValueDate      string
+	Amount         string
+	Debit          string
+	Credit         string
+	Indicator      string
+	DebitIndicator string
+	Counterparty   string
+	IBAN           string
+	Remittance     string
+	Reference      string
+}
+
+// ValidateImportProfile checks that a profile names the columns every
+// import needs.
+func ValidateImportProfile(profile ImportProfile) error {
+	if profile.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len([]rune(profile.Separator)) > 1 {
+		return errors.New("separator must be a single character")
+	}
+
+	if profile.SkipRows < 0 {
+		return errors.New("skip rows cannot be negative")
+	}
+
+	if profile.Encoding != "" && profile.Encoding != "utf-8" && profile.Encoding != "latin1" {
+		return errors.New("encoding must be utf-8 or latin1")
+	}
+
+	if profile.BookingDate == "" {
+		return errors.New("booking date column is required")
+	}
+
+	if profile.Amount == "" && (profile.Debit == "" || profile.Credit == "") {
+		return errors.New("either an amount column or debit and credit columns are required")
+	}
+
+	if profile.Indicator != "" && (profile.Amount == "" || profile.DebitIndicator == "") {
+		return errors.New("an indicator column needs an amount column and a debit indicator")
+	}
+	return nil
+}
+
+// NewImportProfile stores a profile under a name that is unique within the
+// ledger.
+func NewImportProfile(database *sql.DB, scope Scope, profile ImportProfile) (uint, error) {
+	err := ValidateImportProfile(profile)
+	if err != nil {
+		return 0, err
+	}
+
+	definition, err := json.Marshal(profile)
+	if err != nil {
+		return 0, err
+	}
+
+	var id uint
+	err = database.QueryRow("INSERT INTO import_profiles (ledger_id, name, definition) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING RETURNING id", scope.Ledger, profile.Name, string(definition)).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrImportProfileExists
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func GetImportProfile(database *sql.DB, scope Scope, id int) (ImportProfile, error) {
+	var profile ImportProfile
+	var definition string
+	err := database.QueryRow("SELECT definition FROM import_profiles WHERE ledger_id = $1 AND id = $2", scope.Ledger, id).Scan(&definition)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return profile, ErrImportProfileNotExists
+		}
+		return profile, err
+	}
+
+	err = json.Unmarshal([]byte(definition), &profile)
+	if err != nil {
+		return profile, err
+	}
+	profile.ID = uint(id)
+	return profile, nil
+}
+
+// GetImportProfiles returns the profiles of the ledger ordered by name.
+func GetImportProfiles(database *sql.DB, scope Scope) ([]ImportProfile, error) {
+	rows, err := database.Query("SELECT id, definition FROM import_profiles WHERE ledger_id = $1 ORDER BY name", scope.Ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []ImportProfile
+	for rows.Next() {
+		var profile ImportProfile
+		var id uint
+		var definition string
+		err := rows.Scan(&id, &definition)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(definition), &profile)
+		if err != nil {
+			return nil, err
+		}
+		profile.ID = id
+		profiles = append(profiles, profile)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func DeleteImportProfile(database *sql.DB, scope Scope, id int) error {
+	result, err := database.Exec("DELETE FROM import_profiles WHERE ledger_id = $1 AND id = $2", scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrImportProfileNotExists
+	}
+	return nil
+}
+
+// bankLineHash hashes what a bank reports about a line. occurrence counts
+// identical lines within one statement, so two equal payments on the same
+// day stay apart while a second import of the statement matches the first.
+func bankLineHash(line BankLine, occurrence int) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		strconv.FormatUint(uint64(line.Account), 10),
+		line.BookingDate.Format("2006-01-02"),
+		line.ValueDate.Format("2006-01-02"),
+		line.Amount.String(),
+		line.Counterparty,
+		line.CounterpartyIBAN,
+		line.Remittance,
+		line.Reference,
+		strconv.Itoa(occurrence),
+	}, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// StageBankLines stages the lines of a statement for the bank account.
+// Lines that were imported before are reported as duplicates and skipped.
+// The rules of the ledger are tried on every new line; lines a rule with
+// Assign fires for are booked as drafts right away.
+func StageBankLines(database *sql.DB, scope Scope, account uint, source string, lines []BankLine) (ImportResult, error) {
+	return stageBankLines(database, scope, account, source, lines, false)
+}
+
+// PreviewBankLines tells what StageBankLines would do with the lines of a
+// statement without staging anything. The lines reported as imported have
+// no id yet.
+func PreviewBankLines(database *sql.DB, scope Scope, account uint, source string, lines []BankLine) (ImportResult, error) {
+	return stageBankLines(database, scope, account, source, lines, true)
+}
+
+func stageBankLines(database *sql.DB, scope Scope, account uint, source string, lines []BankLine, preview bool) (ImportResult, error) {
+	var result ImportResult
+
+	tx, err := database.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	err = checkPostable(sqlAccounts{tx}, scope.Ledger, account)
+	if err != nil {
+		return result, err
+	}
+
+	rules, err := getRules(tx, scope.Ledger)
+	if err != nil {
+		return result, err
+	}
+
+	occurrences := make(map[string]int)
+	for _, line := range lines {
+		line.Account = account
+		line.Source = source
+		line.Transaction = 0
+
+		content := bankLineHash(line, 0)
+		line.Hash = bankLineHash(line, occurrences[content])
+		occurrences[content]++
+
+		rule, matched := MatchRule(rules, line)
+		line.Rule = rule.ID
+		booking := BankLineBooking(line, rule, 0, "")
+
+		if preview {
+			var exists bool
+			err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM bank_lines WHERE ledger_id = $1 AND account = $2 AND hash = $3)", scope.Ledger, account, line.Hash).Scan(&exists)
+			if err != nil {
+				return result, err
+			}
+			if exists {
+				result.Duplicates = append(result.Duplicates, line)
+			} else {
+				result.Imported = append(result.Imported, line)
+				result.Bookings = append(result.Bookings, booking)
+			}
+			continue
+		}
+
+		err = tx.QueryRow("INSERT INTO bank_lines (ledger_id, account, booking_date, value_date, amount, counterparty, counterparty_iban, remittance, reference, source, hash, rule_id, imported_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) ON CONFLICT DO NOTHING RETURNING id", scope.Ledger, account, day(line.BookingDate), nullDate(line.ValueDate), line.Amount, line.Counterparty, line.CounterpartyIBAN, line.Remittance, line.Reference, line.Source, line.Hash, nullAccount(line.Rule), time.Now().UTC()).Scan(&line.ID)
+		if err == sql.ErrNoRows {
+			result.Duplicates = append(result.Duplicates, line)
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if matched {
+			err = countRuleMatch(tx, scope.Ledger, rule.ID)
+			if err != nil {
+				return result, err
+			}
+		}
+
+		if matched && rule.Assign {
+			booking.ID, err = bookBankLine(tx, scope, line, booking)
+			if err != nil {
+				return result, fmt.Errorf("booking line %d with rule %q: %w", len(result.Imported)+1, rule.Name, err)
+			}
+			line.Transaction = booking.ID
+		}
+		result.Imported = append(result.Imported, line)
+		result.Bookings = append(result.Bookings, booking)
+	}
+
+	if preview {
+		return result, nil
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func nullDate(date time.Time) any {
+	if date.IsZero() {
+		return nil
+	}
+	return day(date)
+}
+
+const bankLineColumns = "id, account, booking_date, value_date, amount, counterparty, counterparty_iban, remittance, reference, source, hash, rule_id, transaction_id"
+
+func scanBankLine(row rowScanner) (BankLine, error) {
+	var line BankLine
+	var valueDate sql.NullTime
+	var rule, transaction sql.NullInt64
+	err := row.Scan(&line.ID, &line.Account, &line.BookingDate, &valueDate, &line.Amount, &line.Counterparty, &line.CounterpartyIBAN, &line.Remittance, &line.Reference, &line.Source, &line.Hash, &rule, &transaction)
+	if err != nil {
+		return line, err
+	}
+	line.ValueDate = valueDate.Time
+	line.Rule = uint(rule.Int64)
+	line.Transaction = uint(transaction.Int64)
+	return line, nil
+}
+
+// GetBankLines returns the staged lines of a bank account by booking date.
+// With open set, lines that have been booked are left out.
+func GetBankLines(database *sql.DB, scope Scope, account int, open bool) ([]BankLine, error) {
+	query := "SELECT " + bankLineColumns + " FROM bank_lines WHERE ledger_id = $1 AND account = $2"
+	if open {
+		query += " AND transaction_id IS NULL"
+	}
+
+	rows, err := database.Query(query+" ORDER BY booking_date, id", scope.Ledger, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []BankLine
+	for rows.Next() {
+		line, err := scanBankLine(rows)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// BankLineTransaction is the booking a bank line turns into: money coming in
+// is a debit on the bank account, money going out a credit.
+func BankLineTransaction(line BankLine, offsetAccount uint) Transaction {
+	transaction := Transaction{
+		Amount:        line.Amount,
+		Debit:         line.Amount > 0,
+		OffsetAccount: offsetAccount,
+		Account:       line.Account,
+		Date:          line.BookingDate,
+		Description:   line.Remittance,
+	}
+	if line.Amount < 0 {
+		transaction.Amount = -line.Amount
+	}
+	if line.Counterparty != "" {
+		transaction.Description = strings.TrimSpace(line.Counterparty + " " + line.Remittance)
+	}
+	return transaction
+}
+
+// BankLineBooking is the draft a bank line is booked as. What the user chose
+// comes first, then what the rule suggests, then what the bank reported.
+func BankLineBooking(line BankLine, rule Rule, offsetAccount uint, description string) Transaction {
+	transaction := BankLineTransaction(line, offsetAccount)
+	transaction.Status = StatusDraft
+	if rule.Description != "" {
+		transaction.Description = rule.Description
+	}
+	if description != "" {
+		transaction.Description = description
+	}
+	return rule.Apply(transaction)
+}
+
+// BookBankLine turns a staged line into a draft transaction against the
+// offset account and returns the id of the transaction. Without an offset
+// account or description, the ones the line's rule suggests are used.
+func BookBankLine(database *sql.DB, scope Scope, id int, offsetAccount uint, description string) (uint, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	line, err := scanBankLine(tx.QueryRow("SELECT "+bankLineColumns+" FROM bank_lines WHERE ledger_id = $1 AND id = $2"+dialectOf(database).forUpdate, scope.Ledger, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrBankLineNotExists
+		}
+		return 0, err
+	}
+	if line.Transaction != 0 {
+		return 0, ErrBankLineBooked
+	}
+
+	var rule Rule
+	if line.Rule != 0 {
+		rule, err = getRule(tx, scope.Ledger, line.Rule)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	transactionID, err := bookBankLine(tx, scope, line, BankLineBooking(line, rule, offsetAccount, description))
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return transactionID, nil
+}
+
+// bookBankLine inserts the booking of a staged line and links the line to it.
+func bookBankLine(database querier, scope Scope, line BankLine, transaction Transaction) (uint, error) {
+	if transaction.OffsetAccount == 0 {
+		return 0, ErrOffsetAccountRequired
+	}
+
+	err := validateTransaction(transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	transactionID, err := insertTransaction(database, scope, transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = database.Exec("UPDATE bank_lines SET transaction_id = $1 WHERE ledger_id = $2 AND id = $3", transactionID, scope.Ledger, line.ID)
+	if err != nil {
+		return 0, err
+	}
+	return transactionID, nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bankAccounts are the accounts bank lines are booked on.
+var bankAccounts = []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}, {ID: 4900, Name: "Other expenses", Kind: KindExpense}, {ID: 8400, Name: "Revenue", Kind: KindRevenue}}
+
+// bankLines are a statement of March 2024 with the same card payment twice.
+var bankLines = []BankLine{
+	{BookingDate: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Amount: 119000, Counterparty: "ACME GmbH", Remittance: "Invoice 2024-017"},
+	{BookingDate: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Amount: -320, Counterparty: "Bakery", Remittance: "Card payment"},
+	{BookingDate: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Amount: -320, Counterparty: "Bakery", Remittance: "Card payment"},
+}
+
+func TestStageBankLinesDuplicates(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	result, err := StageBankLines(db, testScope, 1200, "csv", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// equal lines within one statement are separate payments
+	assert.Len(t, result.Imported, 3)
+	assert.Empty(t, result.Duplicates)
+
+	result, err = StageBankLines(db, testScope, 1200, "csv", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, result.Imported)
+	assert.Len(t, result.Duplicates, 3)
+
+	staged, err := GetBankLines(db, testScope, 1200, true)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, staged, 3)
+}
+
+func TestPreviewBankLines(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	_, err := StageBankLines(db, testScope, 1200, "ofx", bankLines[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := PreviewBankLines(db, testScope, 1200, "ofx", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, result.Imported, 2)
+	assert.Len(t, result.Duplicates, 1)
+
+	// nothing is written by a preview
+	staged, err := GetBankLines(db, testScope, 1200, false)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, staged, 1)
+}
+
+func TestStageBankLinesAccountNotExists(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	_, err := StageBankLines(db, testScope, 1800, "csv", bankLines)
+
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+}
+
+func TestBookBankLine(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	result, err := StageBankLines(db, testScope, 1200, "csv", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := BookBankLine(db, testScope, int(result.Imported[1].ID), 4900, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := GetTransaction(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Money(320), transaction.Amount)
+	assert.False(t, transaction.Debit)
+	assert.Equal(t, uint(1200), transaction.Account)
+	assert.Equal(t, uint(4900), transaction.OffsetAccount)
+	assert.Equal(t, "Bakery Card payment", transaction.Description)
+	assert.Equal(t, StatusDraft, transaction.Status)
+
+	_, err = BookBankLine(db, testScope, int(result.Imported[1].ID), 4900, "")
+	assert.ErrorIs(t, err, ErrBankLineBooked)
+
+	open, err := GetBankLines(db, testScope, 1200, true)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, open, 2)
+}
+
+func TestImportProfiles(t *testing.T) {
+	cleanTables()
+	profile := ImportProfile{Name: "Sparkasse", Separator: ";", DateFormat: "DD.MM.YYYY", DecimalComma: true, BookingDate: "Buchungstag", Amount: "Betrag"}
+
+	id, err := NewImportProfile(db, testScope, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewImportProfile(db, testScope, profile)
+	assert.ErrorIs(t, err, ErrImportProfileExists)
+
+	stored, err := GetImportProfile(db, testScope, int(id))
+	if err != nil {
+		t.Error(err)
+	}
+	profile.ID = id
+	assert.Equal(t, profile, stored)
+
+	err = DeleteImportProfile(db, testScope, int(id))
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = GetImportProfile(db, testScope, int(id))
+	assert.ErrorIs(t, err, ErrImportProfileNotExists)
+}
+
+func TestValidateImportProfileAmountRequired(t *testing.T) {
+	err := ValidateImportProfile(ImportProfile{Name: "Broken", BookingDate: "Date", Debit: "Withdrawals"})
+
+	assert.Error(t, err)
+}
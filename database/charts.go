@@ -0,0 +1,183 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed charts/*.csv
+var chartFiles embed.FS
+
+var (
+	ErrChartTemplateNotExists = errors.New("chart template does not exist")
+	ErrLedgerNotEmpty         = errors.New("ledger already has accounts")
+)
+
+// ChartTemplate is a predefined chart of accounts. Accounts are ordered so
+// that every group account comes before its children.
+type ChartTemplate struct {
+	Name        string
+	Description string
+	Accounts    []Account
+}
+
+var chartDescriptions = map[string]string{
+	"generic": "Minimal chart of accounts for small businesses and personal books",
+	"skr03":   "DATEV SKR03, process-oriented standard chart of accounts",
+	"skr04":   "DATEV SKR04, balance-sheet-oriented standard chart of accounts",
+}
+
+// ChartTemplates returns every template shipped with the binary, sorted by
+// name.
+func ChartTemplates() ([]ChartTemplate, error) {
+	files, err := chartFiles.ReadDir("charts")
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []ChartTemplate
+	for _, file := range files {
+		template, err := GetChartTemplate(strings.TrimSuffix(file.Name(), ".csv"))
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// GetChartTemplate loads a template by name. The files are semicolon
+// separated with the columns id, name, kind, parent and group; lines starting
+// with # are comments.
+func GetChartTemplate(name string) (ChartTemplate, error) {
+	template := ChartTemplate{Name: name, Description: chartDescriptions[name]}
+
+	if name == "" || strings.ContainsAny(name, "/.") {
+		return template, ErrChartTemplateNotExists
+	}
+
+	file, err := chartFiles.Open("charts/" + name + ".csv")
+	if err != nil {
+		return template, ErrChartTemplateNotExists
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 5
+
+	// skip the header
+	_, err = reader.Read()
+	if err != nil {
+		return template, fmt.Errorf("chart template %s: %w", name, err)
+	}
+
+	seen := make(map[uint]Account)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return template, fmt.Errorf("chart template %s: %w", name, err)
+		}
+
+		account, err := parseChartRecord(record)
+		if err != nil {
+			return template, fmt.Errorf("chart template %s: %w", name, err)
+		}
+
+		if _, ok := seen[account.ID]; ok {
+			return template, fmt.Errorf("chart template %s: account %d is listed twice", name, account.ID)
+		}
+		if account.Parent != 0 && !seen[account.Parent].Group {
+			return template, fmt.Errorf("chart template %s: account %d: %w", name, account.ID, ErrParentNotGroup)
+		}
+
+		seen[account.ID] = account
+		template.Accounts = append(template.Accounts, account)
+	}
+
+	return template, nil
+}
+
+func parseChartRecord(record []string) (Account, error) {
+	var account Account
+
+	id, err := strconv.ParseUint(record[0], 10, 32)
+	if err != nil || id == 0 {
+		return account, fmt.Errorf("invalid account id %q", record[0])
+	}
+	account.ID = uint(id)
+	account.Name = record[1]
+	account.Kind = record[2]
+
+	if !ValidKind(account.Kind) {
+		return account, fmt.Errorf("account %d: %w", account.ID, ErrInvalidKind)
+	}
+
+	if record[3] != "" {
+		parent, err := strconv.ParseUint(record[3], 10, 32)
+		if err != nil {
+			return account, fmt.Errorf("account %d: invalid parent %q", account.ID, record[3])
+		}
+		account.Parent = uint(parent)
+	}
+
+	account.Group = record[4] == "true"
+	return account, nil
+}
+
+// ApplyChartTemplate creates every account of a template in one transaction.
+// It only works on a ledger without accounts, so it never mixes charts.
+func ApplyChartTemplate(database *sql.DB, scope Scope, name string) (int, error) {
+	template, err := GetChartTemplate(name)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE ledger_id = $1)", scope.Ledger).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrLedgerNotEmpty
+	}
+
+	for _, account := range template.Accounts {
+		_, err = tx.Exec("INSERT INTO accounts (ledger_id, id, name, kind, parent, is_group) VALUES ($1, $2, $3, $4, $5, $6)", scope.Ledger, account.ID, account.Name, account.Kind, nullID(account.Parent), account.Group)
+		if err != nil {
+			return 0, err
+		}
+
+		err = recordAudit(tx, scope.Ledger, scope.User, EntityAccount, account.ID, ActionCreate, nil, account)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return len(template.Accounts), nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChartTemplates(t *testing.T) {
+	templates, err := ChartTemplates()
+	if err != nil {
+		t.Error(err)
+	}
+
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.Name)
+		assert.NotEmpty(t, template.Accounts, template.Name)
+		assert.NotEmpty(t, template.Description, template.Name)
+	}
+	assert.Equal(t, []string{"generic", "skr03", "skr04"}, names)
+}
+
+func TestGetChartTemplateNotExists(t *testing.T) {
+	_, err := GetChartTemplate("skr99")
+
+	assert.ErrorIs(t, err, ErrChartTemplateNotExists)
+}
+
+func TestApplyChartTemplate(t *testing.T) {
+	cleanTables()
+
+	count, err := ApplyChartTemplate(db, testScope, "skr03")
+	if err != nil {
+		t.Error(err)
+	}
+
+	var stored int
+	err = db.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&stored)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, count, stored)
+
+	bank, err := GetAccount(db, testScope, 1200)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "Bank", bank.Name)
+	assert.Equal(t, uint(900001), bank.Parent)
+}
+
+func TestApplyChartTemplateLedgerNotEmpty(t *testing.T) {
+	cleanTables()
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, 1, "Test Account", "asset")
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = ApplyChartTemplate(db, testScope, "generic")
+
+	assert.ErrorIs(t, err, ErrLedgerNotEmpty)
+}
@@ -1,360 +1,707 @@
-package database
-
-import (
-	"database/sql"
-	"errors"
-	"os"
-	"strings"
-
-	_ "github.com/jackc/pgx/v5/stdlib"
-)
-
-type DB struct {
-	Host     string
-	User     string
-	Password string
-	Name     string
-	Port     string
-}
-
-var (
-	database DB
-)
-
-const (
-	SqlFile = "./database/bookholder.sql"
-)
-
-func SetEnv(env map[string]string) *DB {
-	var db DB
-
-	db.Host = env["DB_HOST"]
-	db.User = env["DB_USER"]
-	db.Password = env["DB_PASSWORD"]
-	db.Name = env["DB_NAME"]
-	db.Port = env["DB_PORT"]
-
-	database = db
-
-	checkDatabase()
-
-	return &db
-}
-
-func New() (*sql.DB, error) {
-	conn, err := sql.Open("pgx", connect())
-	if err != nil {
-		return nil, err
-	}
-	return conn, nil
-}
-
-func connect() string {
-	return "postgres://" + database.User + ":" + database.Password + "@" + database.Host + ":" + database.Port + "/" + database.Name + "?sslmode=disable"
-	//return "user=" + database.User + " password=" + database.Password + " host=" + database.Host + " dbname=" + database.Name + " sslmode=disable"
-}
-
-func checkDatabase() {
-	conn, err := sql.Open("pgx", "postgres://"+database.User+":"+database.Password+"@"+database.Host+":"+database.Port+"/postgres?sslmode=disable")
-	if err != nil {
-		panic(err)
-	}
-	defer conn.Close()
-
-	err = conn.QueryRow("SELECT datname FROM pg_database WHERE datname = $1", database.Name).Scan(&database.Name)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			createDatabase()
-		} else {
-			panic(err)
-		}
-	}
-}
-
-func createDatabase() {
-	conn, err := sql.Open("pgx", "postgres://"+database.User+":"+database.Password+"@"+database.Host+":"+database.Port+"/postgres?sslmode=disable")
-	if err != nil {
-		panic(err)
-	}
-	defer conn.Close()
-
-	_, err = conn.Exec("CREATE DATABASE " + database.Name)
-	if err != nil {
-		panic(err)
-	}
-
-	createTables()
-}
-
-func checkTables() {
-
-}
-
-func createTables() {
-	sqlFile, err := os.ReadFile(SqlFile)
-	if err != nil {
-		panic(err)
-	}
-
-	sqlStatements := strings.Split(string(sqlFile), ";")
-
-	conn, err := sql.Open("pgx", connect())
-
-	if err != nil {
-		panic(err)
-	}
-
-	defer conn.Close()
-
-	for _, statement := range sqlStatements {
-		_, err := conn.Exec(statement)
-		if err != nil {
-			panic(err)
-		}
-	}
-
-}
-
-func updateTables() {
-
-}
-
-func existAccount(database *sql.DB, id int) (bool, error) {
-	err := database.QueryRow("SELECT id FROM accounts WHERE id = $1", id).Scan(&id)
-	if err != nil {
-		if err != sql.ErrNoRows {
-			return false, err
-		}
-		return false, nil
-	}
-	return true, nil
-
-}
-
-func NewAccount(database *sql.DB, account Account) error {
-
-	exists, err := existAccount(database, int(account.ID))
-	if err != nil {
-		return err
-	}
-	if exists {
-		return errors.New("account already exists")
-	}
-
-	_, err = database.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", int(account.ID), string(account.Name), account.Kind)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func UpdateAccount(database *sql.DB, account Account) error {
-	exists, err := existAccount(database, int(account.ID))
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("account does not exist")
-	}
-
-	_, err = database.Exec("UPDATE accounts SET name = $1, kind = $2 WHERE id = $3", account.Name, account.Kind, account.ID)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func DeleteAccount(database *sql.DB, id int) error {
-	exists, err := existAccount(database, id)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("account does not exist")
-	}
-
-	_, err = database.Exec("DELETE FROM accounts WHERE id = $1", id)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func GetAccount(database *sql.DB, id int) (Account, error) {
-	var account Account
-	err := database.QueryRow("SELECT * FROM accounts WHERE id = $1", id).Scan(&account.ID, &account.Name, &account.Kind)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return account, errors.New("account does not exist")
-		}
-		return account, err
-	}
-	return account, nil
-}
-
-func existTransaction(database *sql.DB, id int) (bool, error) {
-	err := database.QueryRow("SELECT id FROM transactions WHERE id = $1", id).Scan(&id)
-	if err != nil {
-		if err != sql.ErrNoRows {
-			return false, err
-		}
-		return false, nil
-	}
-	return true, nil
-
-}
-
-func NewTransaction(database *sql.DB, transaction Transaction) error {
-	if transaction.OffsetAccount == transaction.Account {
-		return errors.New("offset account and account cannot be the same")
-	}
-
-	if transaction.Amount == 0 {
-		return errors.New("amount cannot be 0")
-	}
-
-	if transaction.Date.IsZero() {
-		return errors.New("date is required")
-	}
-
-	_, err := database.Exec("INSERT INTO transactions (amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func UpdateTransaction(database *sql.DB, transaction Transaction) error {
-	exists, err := existTransaction(database, int(transaction.ID))
-	if err != nil {
-		return err
-	}
-
-	if !exists {
-		return errors.New("transaction already exists")
-	}
-	_, err = database.Exec("UPDATE transactions SET amount = $1, debit = $2, offset_account = $3, account = $4, date = $5, description = $6 WHERE id = $7", transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description, transaction.ID)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func DeleteTransaction(database *sql.DB, id int) error {
-	exists, err := existTransaction(database, id)
-	if err != nil {
-		return err
-	}
-
-	if !exists {
-		return errors.New("transaction already exists")
-	}
-	_, err = database.Exec("DELETE FROM transactions WHERE id = $1", id)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func GetTransaction(database *sql.DB, id int) (Transaction, error) {
-	var transaction Transaction
-	err := database.QueryRow("SELECT id, amount, debit, offset_account, account, date, description FROM transactions WHERE id = $1", id).Scan(&transaction.ID, &transaction.Amount, &transaction.Debit, &transaction.OffsetAccount, &transaction.Account, &transaction.Date, &transaction.Description)
-	if err != nil {
-		return transaction, err
-	}
-	return transaction, nil
-}
-
-func GetTransactions(database *sql.DB, account int, year int, month int) ([]Transaction, error) {
-	var transactions []Transaction
-	var row *sql.Rows
-	var err error
-	if year == 0 {
-		return nil, errors.New("year is required")
-	}
-
-	if month == 0 {
-		row, err = database.Query("SELECT id, amount, debit, offset_account, account, date, description FROM transactions WHERE (account = $1 OR offset_account = $1) AND EXTRACT(YEAR FROM date) = $2", account, year)
-	} else {
-		row, err = database.Query("SELECT id, amount, debit, offset_account, account, date, description FROM transactions WHERE (account = $1 OR offset_account = $1) AND EXTRACT(YEAR FROM date) = $2 AND EXTRACT(MONTH FROM date) = $3", account, year, month)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	defer row.Close()
-
-	for row.Next() {
-		var transaction Transaction
-		err := row.Scan(&transaction.ID, &transaction.Amount, &transaction.Debit, &transaction.OffsetAccount, &transaction.Account, &transaction.Date, &transaction.Description)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, transaction)
-	}
-
-	return transactions, nil
-
-}
-
-func NewUser(database *sql.DB, user User) error {
-	_, err := database.Exec("INSERT INTO users (name, password) VALUES ($1, $2)", user.Name, user.Password)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func UpdateUser(database *sql.DB, user User) error {
-	_, err := database.Exec("UPDATE users SET name = $1, password = $2 WHERE id = $3", user.Name, user.Password, user.ID)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func DeleteUser(database *sql.DB, id string) error {
-	_, err := database.Exec("DELETE FROM users WHERE id = $1", id)
-	if err != nil {
-		return err
-	}
-	return nil
-
-}
-
-func GetUser(database *sql.DB, id string) (User, error) {
-	var user User
-	err := database.QueryRow("SELECT * FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Password)
-	if err != nil {
-		return user, err
-	}
-	return user, nil
-}
-
-func GetUserByName(database *sql.DB, name string) (User, error) {
-	var user User
-	err := database.QueryRow("SELECT * FROM users WHERE name = $1", name).Scan(&user.ID, &user.Name, &user.Password)
-	if err != nil {
-		return user, err
-	}
-	return user, nil
-
-}
-
-func AuthenticateUser(database *sql.DB, name string, password string) (User, error) {
-	var user User
-	err := database.QueryRow("SELECT * FROM users WHERE name = $1 AND password = $2", name, password).Scan(&user.ID, &user.Name, &user.Password)
-	if err != nil {
-		return user, err
-	}
-	return user, nil
-
-}
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type DB struct {
+	Driver   string
+	Path     string
+	Host     string
+	User     string
+	Password string
+	Name     string
+	Port     string
+}
+
+var (
+	database DB
+)
+
+var (
+	ErrAccountNotExists     = errors.New("account does not exist")
+	ErrInvalidKind          = errors.New("invalid kind; must be one of asset, liability, equity, revenue or expense")
+	ErrTransactionNotExists = errors.New("transaction does not exist")
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so lookups can run
+// inside or outside of a transaction.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// SetEnv connects to the database of the environment, creates it if it does
+// not exist yet and migrates its schema up: to DB_VERSION if it is set, else
+// to the latest version. DB_DRIVER picks Postgres, the default, or SQLite in
+// the file DB_PATH.
+func SetEnv(env map[string]string) *DB {
+	db := configure(env)
+
+	if db.Driver != DriverSQLite {
+		checkDatabase()
+	}
+	migrateDatabase(env["DB_VERSION"])
+
+	return &db
+}
+
+// Downgrade connects to the database of the environment and takes its schema
+// back to version. It is run on its own, never on start.
+func Downgrade(env map[string]string, version int) error {
+	configure(env)
+
+	conn, err := New()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return MigrateDown(conn, version)
+}
+
+func configure(env map[string]string) DB {
+	var db DB
+
+	db.Driver = env["DB_DRIVER"]
+	db.Path = env["DB_PATH"]
+	db.Host = env["DB_HOST"]
+	db.User = env["DB_USER"]
+	db.Password = env["DB_PASSWORD"]
+	db.Name = env["DB_NAME"]
+	db.Port = env["DB_PORT"]
+
+	database = db
+	return db
+}
+
+func New() (*sql.DB, error) {
+	if database.Driver == DriverSQLite {
+		return OpenSQLite(database.Path)
+	}
+
+	conn, err := sql.Open("pgx", connect())
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func connect() string {
+	return "postgres://" + database.User + ":" + database.Password + "@" + database.Host + ":" + database.Port + "/" + database.Name + "?sslmode=disable"
+	//return "user=" + database.User + " password=" + database.Password + " host=" + database.Host + " dbname=" + database.Name + " sslmode=disable"
+}
+
+func checkDatabase() {
+	conn, err := sql.Open("pgx", "postgres://"+database.User+":"+database.Password+"@"+database.Host+":"+database.Port+"/postgres?sslmode=disable")
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	err = conn.QueryRow("SELECT datname FROM pg_database WHERE datname = $1", database.Name).Scan(&database.Name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			createDatabase()
+		} else {
+			panic(err)
+		}
+	}
+}
+
+func createDatabase() {
+	conn, err := sql.Open("pgx", "postgres://"+database.User+":"+database.Password+"@"+database.Host+":"+database.Port+"/postgres?sslmode=disable")
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Exec("CREATE DATABASE " + database.Name)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func migrateDatabase(version string) {
+	conn, err := New()
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	if version == "" {
+		err = Migrate(conn)
+	} else {
+		var target int
+		target, err = strconv.Atoi(version)
+		if err != nil {
+			panic("DB_VERSION must be a number")
+		}
+		err = MigrateTo(conn, target)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func existAccount(database querier, ledger uint, id int) (bool, error) {
+	err := database.QueryRow("SELECT id FROM accounts WHERE ledger_id = $1 AND id = $2", ledger, id).Scan(&id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+
+}
+
+func NewAccount(database *sql.DB, scope Scope, account Account) error {
+	if !ValidKind(account.Kind) {
+		return ErrInvalidKind
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	exists, err := existAccount(tx, scope.Ledger, int(account.ID))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("account already exists")
+	}
+
+	err = validateParent(sqlAccounts{tx}, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO accounts (ledger_id, id, name, kind, parent, is_group) VALUES ($1, $2, $3, $4, $5, $6)", scope.Ledger, int(account.ID), string(account.Name), account.Kind, nullID(account.Parent), account.Group)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityAccount, account.ID, ActionCreate, nil, account)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func UpdateAccount(database *sql.DB, scope Scope, account Account) error {
+	if !ValidKind(account.Kind) {
+		return ErrInvalidKind
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := getAccount(tx, scope.Ledger, int(account.ID))
+	if err != nil {
+		return err
+	}
+
+	err = validateParent(sqlAccounts{tx}, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+
+	err = validateGroupChange(sqlAccounts{tx}, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE accounts SET name = $1, kind = $2, parent = $3, is_group = $4 WHERE ledger_id = $5 AND id = $6", account.Name, account.Kind, nullID(account.Parent), account.Group, scope.Ledger, account.ID)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityAccount, account.ID, ActionUpdate, current, account)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func DeleteAccount(database *sql.DB, scope Scope, id int) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := getAccount(tx, scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	err = validateGroupChange(sqlAccounts{tx}, scope.Ledger, Account{ID: uint(id)})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM accounts WHERE ledger_id = $1 AND id = $2", scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityAccount, id, ActionDelete, current, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetAccount(database *sql.DB, scope Scope, id int) (Account, error) {
+	return getAccount(database, scope.Ledger, id)
+}
+
+func getAccount(database querier, ledger uint, id int) (Account, error) {
+	var account Account
+	var parent sql.NullInt64
+	err := database.QueryRow("SELECT id, name, kind, parent, is_group FROM accounts WHERE ledger_id = $1 AND id = $2", ledger, id).Scan(&account.ID, &account.Name, &account.Kind, &parent, &account.Group)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, ErrAccountNotExists
+		}
+		return account, err
+	}
+	account.Parent = uint(parent.Int64)
+	return account, nil
+}
+
+func existTransaction(database *sql.DB, ledger uint, id int) (bool, error) {
+	err := database.QueryRow("SELECT id FROM transactions WHERE ledger_id = $1 AND id = $2", ledger, id).Scan(&id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+		return false, nil
+	}
+	return true, nil
+
+}
+
+// NewTransaction stores a transaction and returns its id. Transactions start
+// as drafts and only count once they are approved and posted; callers that
+// book without review can pass StatusPosted.
+func NewTransaction(database *sql.DB, scope Scope, transaction Transaction) (uint, error) {
+	if transaction.Status == "" {
+		transaction.Status = StatusDraft
+	}
+
+	if transaction.Status != StatusDraft && transaction.Status != StatusPosted {
+		return 0, ErrInvalidTransactionStatus
+	}
+
+	err := validateTransaction(transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertTransaction(tx, scope, transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	if transaction.Rule != 0 {
+		err = countRuleMatch(tx, scope.Ledger, transaction.Rule)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+
+}
+
+// insertTransaction stores a validated transaction and records it in the
+// audit log. It checks the accounts and, for a posted transaction, the period
+// in the same transaction as the insert.
+func insertTransaction(database querier, scope Scope, transaction Transaction) (uint, error) {
+	err := checkPostable(sqlAccounts{database}, scope.Ledger, transaction.Account, transaction.OffsetAccount)
+	if err != nil {
+		return 0, err
+	}
+
+	if transaction.Status == StatusPosted {
+		err = checkPeriods(database, scope, transaction.Date)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	transaction.Reverses = 0
+	transaction.Replaces = 0
+	transaction.PreparedBy = scope.User
+	err = database.QueryRow("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description, tax_code, status, prepared_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id", scope.Ledger, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description, transaction.TaxCode, transaction.Status, transaction.PreparedBy).Scan(&transaction.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordAudit(database, scope.Ledger, scope.User, EntityTransaction, transaction.ID, ActionCreate, nil, transaction)
+	if err != nil {
+		return 0, err
+	}
+	return transaction.ID, nil
+}
+
+// UpdateTransaction changes a transaction that is not posted yet in place and
+// sends it back to draft, so it has to be approved again. A posted
+// transaction is never changed: it is reversed and the new values become a
+// draft that replaces it. The id of the changed transaction or of the
+// replacement is returned.
+func UpdateTransaction(database *sql.DB, scope Scope, transaction Transaction) (uint, error) {
+	err := validateTransaction(transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockTransaction(tx, dialectOf(database), scope, int(transaction.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkPostable(sqlAccounts{tx}, scope.Ledger, transaction.Account, transaction.OffsetAccount)
+	if err != nil {
+		return 0, err
+	}
+
+	id := current.ID
+	transaction.Status = StatusDraft
+	transaction.Reverses = 0
+	transaction.PreparedBy = scope.User
+	if current.Status != StatusPosted {
+		_, err = tx.Exec("UPDATE transactions SET amount = $1, debit = $2, offset_account = $3, account = $4, date = $5, description = $6, tax_code = $7, status = $8, prepared_by = $9 WHERE ledger_id = $10 AND id = $11", transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description, transaction.TaxCode, transaction.Status, transaction.PreparedBy, scope.Ledger, transaction.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		transaction.Replaces = current.Replaces
+		err = recordAudit(tx, scope.Ledger, scope.User, EntityTransaction, id, ActionUpdate, current, transaction)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		// the reversal is posted right away
+		_, err = reverseTransaction(tx, scope, current)
+		if err != nil {
+			return 0, err
+		}
+
+		transaction.Replaces = current.ID
+		err = tx.QueryRow("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description, tax_code, status, replaces, prepared_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id", scope.Ledger, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description, transaction.TaxCode, transaction.Status, transaction.Replaces, transaction.PreparedBy).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+
+		transaction.ID = id
+		err = recordAudit(tx, scope.Ledger, scope.User, EntityTransaction, id, ActionCreate, nil, transaction)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// DeleteTransaction deletes a transaction that is not posted yet. A posted
+// transaction is reversed instead, and the id of the reversal is returned.
+func DeleteTransaction(database *sql.DB, scope Scope, id int) (uint, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockTransaction(tx, dialectOf(database), scope, id)
+	if err != nil {
+		return 0, err
+	}
+
+	var reversal uint
+	if current.Status != StatusPosted {
+		_, err = tx.Exec("DELETE FROM transactions WHERE ledger_id = $1 AND id = $2", scope.Ledger, id)
+		if err != nil {
+			return 0, err
+		}
+
+		err = recordAudit(tx, scope.Ledger, scope.User, EntityTransaction, id, ActionDelete, current, nil)
+		if err != nil {
+			return 0, err
+		}
+	} else {
+		reversal, err = reverseTransaction(tx, scope, current)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return reversal, nil
+}
+
+func GetTransaction(database *sql.DB, scope Scope, id int) (Transaction, error) {
+	transaction, err := scanTransaction(database.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND id = $2", scope.Ledger, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return transaction, ErrTransactionNotExists
+		}
+		return transaction, err
+	}
+	return transaction, nil
+}
+
+// GetTransactions returns the transactions of the account in the given year,
+// or month when month is not 0, including reversals. Only those in status are
+// returned, or those in every status, drafts as well, when status is "".
+func GetTransactions(database *sql.DB, scope Scope, account int, year int, month int, status string) ([]Transaction, error) {
+	var transactions []Transaction
+	err := StreamTransactions(database, scope, account, year, month, status, func(transaction Transaction) error {
+		transactions = append(transactions, transaction)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StreamTransactions hands the transactions GetTransactions returns to fn one
+// at a time, straight from the cursor, so listings of any length can be
+// exported. It stops at the first error fn returns.
+func StreamTransactions(database *sql.DB, scope Scope, account int, year int, month int, status string, fn func(Transaction) error) error {
+	if year == 0 {
+		return errors.New("year is required")
+	}
+
+	start, end := dateRange(year, month)
+	row, err := database.Query("SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND (account = $2 OR offset_account = $2) AND date >= $3 AND date < $4 AND ($5 = '' OR status = $5) ORDER BY date, id", scope.Ledger, account, start, end, status)
+	if err != nil {
+		return err
+	}
+
+	defer row.Close()
+
+	for row.Next() {
+		transaction, err := scanTransaction(row)
+		if err != nil {
+			return err
+		}
+
+		err = fn(transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return row.Err()
+}
+
+// GetPostedTransactions returns the posted transactions of the ledger dated
+// between from and to, both inclusive, oldest first.
+func GetPostedTransactions(database *sql.DB, scope Scope, from time.Time, to time.Time) ([]Transaction, error) {
+	rows, err := database.Query("SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND status = 'posted' AND date >= $2 AND date < $3 ORDER BY date, id", scope.Ledger, from, endOfDay(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// NewUser, UpdateUser and DeleteUser are recorded in the audit log as
+// changes the user made to themselves.
+func NewUser(database *sql.DB, user User) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = insertUser(tx, user)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+
+}
+
+// NewUserWithLedger stores a user together with a ledger of their own named
+// ledger, in one database transaction, so no user is ever left without one.
+func NewUserWithLedger(database *sql.DB, user User, ledger string) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := insertUser(tx, user)
+	if err != nil {
+		return err
+	}
+
+	_, err = insertLedger(tx, Ledger{Name: ledger, Owner: id})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertUser stores a user as part of the database transaction of the caller
+// and returns the id it was given.
+func insertUser(database querier, user User) (string, error) {
+	err := database.QueryRow("INSERT INTO users (name, password) VALUES ($1, $2) RETURNING id", user.Name, user.Password).Scan(&user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	err = recordAudit(database, 0, user.ID, EntityUser, user.ID, ActionCreate, nil, user)
+	if err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+func UpdateUser(database *sql.DB, user User) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current User
+	err = tx.QueryRow("SELECT id, name FROM users WHERE id = $1", user.ID).Scan(&current.ID, &current.Name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotExists
+		}
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE users SET name = $1, password = $2 WHERE id = $3", user.Name, user.Password, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(tx, 0, user.ID, EntityUser, user.ID, ActionUpdate, current, user)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+
+}
+
+func DeleteUser(database *sql.DB, id string) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current User
+	err = tx.QueryRow("SELECT id, name FROM users WHERE id = $1", id).Scan(&current.ID, &current.Name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotExists
+		}
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(tx, 0, id, EntityUser, id, ActionDelete, current, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+
+}
+
+func GetUser(database *sql.DB, id string) (User, error) {
+	var user User
+	err := database.QueryRow("SELECT * FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Password)
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+func GetUserByName(database *sql.DB, name string) (User, error) {
+	var user User
+	err := database.QueryRow("SELECT * FROM users WHERE name = $1", name).Scan(&user.ID, &user.Name, &user.Password)
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+
+}
+
+func AuthenticateUser(database *sql.DB, name string, password string) (User, error) {
+	var user User
+	err := database.QueryRow("SELECT * FROM users WHERE name = $1 AND password = $2", name, password).Scan(&user.ID, &user.Name, &user.Password)
+	if err != nil {
+		return user, err
+	}
+	return user, nil
+
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"strings"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -16,7 +16,85 @@ import (
 
 var db *sql.DB
 
+// hostAndPort is the address of the database server the tests run against.
+var hostAndPort string
+
+// testScope is the ledger every test works on. Its owner survives
+// cleanTables, so the ledger does too.
+var testScope Scope
+
 func TestMain(m *testing.M) {
+	// TEST_DB_DRIVER runs the tests against one of the backends only
+	drivers := []string{DriverSQLite, DriverPostgres}
+	if driver := os.Getenv("TEST_DB_DRIVER"); driver != "" {
+		drivers = []string{driver}
+	}
+
+	code := 0
+	for _, driver := range drivers {
+		log.Println("Running tests on", driver)
+		code = max(code, runTests(m, driver))
+	}
+	os.Exit(code)
+}
+
+// testDriver is the backend the tests run against.
+var testDriver string
+
+// runTests runs the tests on a new database of the backend.
+func runTests(m *testing.M, driver string) int {
+	testDriver = driver
+	testScope = Scope{}
+
+	var err error
+	if driver == DriverSQLite {
+		dir, err := os.MkdirTemp("", "bookholder")
+		if err != nil {
+			log.Fatalf("Could not create directory: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		db, err = OpenSQLite(filepath.Join(dir, "dbname.db"))
+		if err != nil {
+			log.Fatalf("Could not open database: %s", err)
+		}
+	} else {
+		purge := startPostgres()
+		defer purge()
+	}
+	defer db.Close()
+
+	// setup database
+
+	err = Migrate(db)
+	if err != nil {
+		log.Fatalf("Could not create tables: %s", err)
+	}
+
+	// load sample data
+	err = db.QueryRow("INSERT INTO users (name, password) VALUES ('Ledger Owner', 'secret') RETURNING id").Scan(&testScope.User)
+	if err != nil {
+		log.Fatalf("Could not insert sample data: %s", err)
+	}
+
+	testScope.Ledger, err = NewLedger(db, Ledger{Name: "Test Ledger", Owner: testScope.User})
+	if err != nil {
+		log.Fatalf("Could not insert sample data: %s", err)
+	}
+	testScope.Role = RoleOwner
+
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, 1, 'Test Account', 'asset')", testScope.Ledger)
+	if err != nil {
+		log.Fatalf("Could not insert sample data: %s", err)
+	}
+
+	// run tests
+	return m.Run()
+}
+
+// startPostgres starts a Postgres container and connects db to it. The
+// returned function removes the container.
+func startPostgres() func() {
 	// uses a sensible default on windows (tcp/http) and linux/osx (socket)
 	pool, err := dockertest.NewPool("")
 	if err != nil {
@@ -47,7 +125,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Could not start resource: %s", err)
 	}
 
-	hostAndPort := resource.GetHostPort("5432/tcp")
+	hostAndPort = resource.GetHostPort("5432/tcp")
 	databaseUrl := fmt.Sprintf("postgres://user_name:secret@%s/dbname?sslmode=disable", hostAndPort)
 
 	log.Println("Connecting to database on url: ", databaseUrl)
@@ -66,39 +144,43 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Could not connect to docker: %s", err)
 	}
 
-	defer func() {
+	return func() {
 		if err := pool.Purge(resource); err != nil {
 			log.Fatalf("Could not purge resource: %s", err)
 		}
-	}()
-
-	// setup database
+	}
+}
 
-	sqlFile, err := os.ReadFile("../database/bookholder.sql")
+func cleanTables() {
+	_, err := db.Exec("DELETE FROM audit_log")
 	if err != nil {
-		log.Fatalf("Could not read sql file: %s", err)
+		log.Fatalf("Could not clean tables: %s", err)
 	}
-
-	sqlStatements := strings.Split(string(sqlFile), ";")
-	for _, statement := range sqlStatements {
-		_, err = db.Exec(statement)
-		if err != nil {
-			log.Fatalf("Could not create tables: %s", err)
-		}
+	_, err = db.Exec("DELETE FROM import_profiles")
+	if err != nil {
+		log.Fatalf("Could not clean tables: %s", err)
 	}
-
-	// load sample data
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES (1, 'Test Account', '1000.00')")
+	_, err = db.Exec("DELETE FROM bank_matches")
 	if err != nil {
-		log.Fatalf("Could not insert sample data: %s", err)
+		log.Fatalf("Could not clean tables: %s", err)
 	}
-
-	// run tests
-	m.Run()
-}
-
-func cleanTables() {
-	_, err := db.Exec("DELETE FROM transactions")
+	_, err = db.Exec("DELETE FROM bank_lines")
+	if err != nil {
+		log.Fatalf("Could not clean tables: %s", err)
+	}
+	_, err = db.Exec("DELETE FROM rules")
+	if err != nil {
+		log.Fatalf("Could not clean tables: %s", err)
+	}
+	_, err = db.Exec("DELETE FROM fiscal_years")
+	if err != nil {
+		log.Fatalf("Could not clean tables: %s", err)
+	}
+	_, err = db.Exec("DELETE FROM journal_entries")
+	if err != nil {
+		log.Fatalf("Could not clean tables: %s", err)
+	}
+	_, err = db.Exec("DELETE FROM transactions")
 	if err != nil {
 		log.Fatalf("Could not clean tables: %s", err)
 	}
@@ -107,17 +189,47 @@ func cleanTables() {
 		log.Fatalf("Could not clean tables: %s", err)
 	}
 
-	_, err = db.Exec("DELETE FROM users")
+	_, err = db.Exec("DELETE FROM users WHERE id <> $1", testScope.User)
 	if err != nil {
 		log.Fatalf("Could not clean tables: %s", err)
 	}
 
 }
 
+// insertBooks cleans the tables and books the accounts, transactions and
+// journal entries into the test ledger, in that order. It returns the ids of
+// the transactions.
+func insertBooks(t *testing.T, accounts []Account, transactions []Transaction, entries []JournalEntry) []uint {
+	cleanTables()
+	for _, account := range accounts {
+		err := NewAccount(db, testScope, account)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var ids []uint
+	for _, transaction := range transactions {
+		id, err := NewTransaction(db, testScope, transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	for _, entry := range entries {
+		_, err := NewJournalEntry(db, testScope, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ids
+}
+
 func TestNewAccount(t *testing.T) {
 	cleanTables()
-	account := Account{ID: 1000, Name: "Test Account 2", Kind: "1000.00"}
-	err := NewAccount(db, account)
+	account := Account{ID: 1000, Name: "Test Account 2", Kind: "asset"}
+	err := NewAccount(db, testScope, account)
 	if err != nil {
 		t.Error(err)
 	}
@@ -135,14 +247,14 @@ func TestNewAccount(t *testing.T) {
 
 func TestNewAccountAlreadyExists(t *testing.T) {
 	cleanTables()
-	iniAccount := Account{ID: 1, Name: "Test Account", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", iniAccount.ID, iniAccount.Name, iniAccount.Kind)
+	iniAccount := Account{ID: 1, Name: "Test Account", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, iniAccount.ID, iniAccount.Name, iniAccount.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account := Account{ID: 1, Name: "Test Account 2", Kind: "1000.00"}
-	err = NewAccount(db, account)
+	account := Account{ID: 1, Name: "Test Account 2", Kind: "asset"}
+	err = NewAccount(db, testScope, account)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -153,7 +265,7 @@ func TestNewAccountAlreadyExists(t *testing.T) {
 }
 
 func TestExistAccount(t *testing.T) {
-	exists, err := existAccount(db, 1)
+	exists, err := existAccount(db, testScope.Ledger, 1)
 	if err != nil {
 		t.Error(err)
 	}
@@ -164,7 +276,7 @@ func TestExistAccount(t *testing.T) {
 }
 
 func TestExistAccountNotExists(t *testing.T) {
-	exists, err := existAccount(db, 2)
+	exists, err := existAccount(db, testScope.Ledger, 2)
 	if err != nil {
 		t.Error(err)
 	}
@@ -176,14 +288,14 @@ func TestExistAccountNotExists(t *testing.T) {
 
 func TestUpdateAccount(t *testing.T) {
 	cleanTables()
-	iniAccount := Account{ID: 3, Name: "Test Account", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", iniAccount.ID, iniAccount.Name, iniAccount.Kind)
+	iniAccount := Account{ID: 3, Name: "Test Account", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, iniAccount.ID, iniAccount.Name, iniAccount.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account := Account{ID: 3, Name: "Test Account 3", Kind: "1000.00"}
-	err = UpdateAccount(db, account)
+	account := Account{ID: 3, Name: "Test Account 3", Kind: "asset"}
+	err = UpdateAccount(db, testScope, account)
 	if err != nil {
 		t.Error(err)
 	}
@@ -200,8 +312,8 @@ func TestUpdateAccount(t *testing.T) {
 }
 
 func TestUpdateAccountNotExists(t *testing.T) {
-	account := Account{ID: 4, Name: "Test Account 4", Kind: "1000.00"}
-	err := UpdateAccount(db, account)
+	account := Account{ID: 4, Name: "Test Account 4", Kind: "asset"}
+	err := UpdateAccount(db, testScope, account)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -213,13 +325,13 @@ func TestUpdateAccountNotExists(t *testing.T) {
 
 func TestDeleteAccount(t *testing.T) {
 	cleanTables()
-	account := Account{ID: 5, Name: "Test Account 5", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account.ID, account.Name, account.Kind)
+	account := Account{ID: 5, Name: "Test Account 5", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = DeleteAccount(db, int(account.ID))
+	err = DeleteAccount(db, testScope, int(account.ID))
 	if err != nil {
 		t.Error(err)
 	}
@@ -233,7 +345,7 @@ func TestDeleteAccount(t *testing.T) {
 }
 
 func TestDeleteAccountNotExists(t *testing.T) {
-	err := DeleteAccount(db, 6)
+	err := DeleteAccount(db, testScope, 6)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -244,13 +356,13 @@ func TestDeleteAccountNotExists(t *testing.T) {
 }
 
 func TestGetAccount(t *testing.T) {
-	account := Account{ID: 7, Name: "Test Account 7", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account.ID, account.Name, account.Kind)
+	account := Account{ID: 7, Name: "Test Account 7", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	acc, err := GetAccount(db, int(account.ID))
+	acc, err := GetAccount(db, testScope, int(account.ID))
 	if err != nil {
 		t.Error(err)
 	}
@@ -261,7 +373,7 @@ func TestGetAccount(t *testing.T) {
 }
 
 func TestGetAccountNotExists(t *testing.T) {
-	_, err := GetAccount(db, 8)
+	_, err := GetAccount(db, testScope, 8)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -272,25 +384,25 @@ func TestGetAccountNotExists(t *testing.T) {
 }
 
 func TestExistTransaction(t *testing.T) {
-	account1 := Account{ID: 9, Name: "Test Account 9", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 9, Name: "Test Account 9", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 10, Name: "Test Account 10", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 10, Name: "Test Account 10", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 0, Amount: 1234.56, Debit: true, OffsetAccount: 9, Account: 10, Date: time.Now()}
-	_, err = db.Exec("INSERT INTO transactions (amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description)
+	transaction := Transaction{ID: 0, Amount: 123456, Debit: true, OffsetAccount: 9, Account: 10, Date: time.Now()}
+	err = db.QueryRow("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id", testScope.Ledger, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description).Scan(&transaction.ID)
 	if err != nil {
 		t.Error(err)
 	}
 
-	exists, err := existTransaction(db, 1)
+	exists, err := existTransaction(db, testScope.Ledger, int(transaction.ID))
 	if err != nil {
 		t.Error(err)
 	}
@@ -301,7 +413,7 @@ func TestExistTransaction(t *testing.T) {
 }
 
 func TestExistTransactionNotExists(t *testing.T) {
-	exists, err := existTransaction(db, 2)
+	exists, err := existTransaction(db, testScope.Ledger, 2)
 	if err != nil {
 		t.Error(err)
 	}
@@ -313,20 +425,20 @@ func TestExistTransactionNotExists(t *testing.T) {
 
 func TestNewTransaction(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 11, Name: "Test Account 11", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 11, Name: "Test Account 11", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 12, Name: "Test Account 12", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 12, Name: "Test Account 12", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 1, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
-	err = NewTransaction(db, transaction)
+	transaction := Transaction{ID: 1, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = NewTransaction(db, testScope, transaction)
 	if err != nil {
 		t.Error(err)
 	}
@@ -349,8 +461,8 @@ func TestNewTransaction(t *testing.T) {
 }
 
 func TestNewTransactionAccountNotExists(t *testing.T) {
-	transaction := Transaction{ID: 2, Amount: 1234.56, Debit: true, OffsetAccount: 13, Account: 14, Date: time.Now(), Description: "Test Transaction"}
-	err := NewTransaction(db, transaction)
+	transaction := Transaction{ID: 2, Amount: 123456, Debit: true, OffsetAccount: 13, Account: 14, Date: time.Now(), Description: "Test Transaction"}
+	_, err := NewTransaction(db, testScope, transaction)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -359,14 +471,14 @@ func TestNewTransactionAccountNotExists(t *testing.T) {
 }
 
 func TestNewTransactionOffsetAccountNotExists(t *testing.T) {
-	account := Account{ID: 15, Name: "Test Account 15", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account.ID, account.Name, account.Kind)
+	account := Account{ID: 15, Name: "Test Account 15", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 3, Amount: 1234.56, Debit: true, OffsetAccount: 16, Account: account.ID, Date: time.Now(), Description: "Test Transaction"}
-	err = NewTransaction(db, transaction)
+	transaction := Transaction{ID: 3, Amount: 123456, Debit: true, OffsetAccount: 16, Account: account.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = NewTransaction(db, testScope, transaction)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -375,14 +487,14 @@ func TestNewTransactionOffsetAccountNotExists(t *testing.T) {
 }
 
 func TestNewTransactionOffsetAccountEqualsAccount(t *testing.T) {
-	account := Account{ID: 17, Name: "Test Account 17", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account.ID, account.Name, account.Kind)
+	account := Account{ID: 17, Name: "Test Account 17", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 4, Amount: 1234.56, Debit: true, OffsetAccount: account.ID, Account: account.ID, Date: time.Now(), Description: "Test Transaction"}
-	err = NewTransaction(db, transaction)
+	transaction := Transaction{ID: 4, Amount: 123456, Debit: true, OffsetAccount: account.ID, Account: account.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = NewTransaction(db, testScope, transaction)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -391,20 +503,20 @@ func TestNewTransactionOffsetAccountEqualsAccount(t *testing.T) {
 }
 
 func TestNewTransactionAmountZero(t *testing.T) {
-	account1 := Account{ID: 18, Name: "Test Account 18", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 18, Name: "Test Account 18", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 19, Name: "Test Account 19", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 19, Name: "Test Account 19", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
 	transaction := Transaction{ID: 5, Amount: 0, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
-	err = NewTransaction(db, transaction)
+	_, err = NewTransaction(db, testScope, transaction)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -413,20 +525,20 @@ func TestNewTransactionAmountZero(t *testing.T) {
 }
 
 func TestNewTransactionDateZero(t *testing.T) {
-	account1 := Account{ID: 20, Name: "Test Account 20", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 20, Name: "Test Account 20", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 21, Name: "Test Account 21", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 21, Name: "Test Account 21", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 6, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Time{}, Description: "Test Transaction"}
-	err = NewTransaction(db, transaction)
+	transaction := Transaction{ID: 6, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Time{}, Description: "Test Transaction"}
+	_, err = NewTransaction(db, testScope, transaction)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -436,29 +548,31 @@ func TestNewTransactionDateZero(t *testing.T) {
 
 func TestUpdateTransaction(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 22, Name: "Test Account 22", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 22, Name: "Test Account 22", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 23, Name: "Test Account 23", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 23, Name: "Test Account 23", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	iniTransaction := Transaction{ID: 7, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
-	_, err = db.Exec("INSERT INTO transactions (id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", iniTransaction.ID, iniTransaction.Amount, iniTransaction.Debit, iniTransaction.OffsetAccount, iniTransaction.Account, iniTransaction.Date, iniTransaction.Description)
+	// drafts are updated in place
+	iniTransaction := Transaction{ID: 7, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, id, amount, debit, offset_account, account, date, description, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", testScope.Ledger, iniTransaction.ID, iniTransaction.Amount, iniTransaction.Debit, iniTransaction.OffsetAccount, iniTransaction.Account, iniTransaction.Date, iniTransaction.Description, StatusDraft)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 7, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction 2"}
-	err = UpdateTransaction(db, transaction)
+	transaction := Transaction{ID: 7, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction 2"}
+	id, err := UpdateTransaction(db, testScope, transaction)
 	if err != nil {
 		t.Error(err)
 	}
+	assert.Equal(t, transaction.ID, id)
 
 	// check if transaction was updated
 	var description string
@@ -472,20 +586,20 @@ func TestUpdateTransaction(t *testing.T) {
 }
 
 func TestUpdateTransactionNotExists(t *testing.T) {
-	account1 := Account{ID: 24, Name: "Test Account 24", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 24, Name: "Test Account 24", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 25, Name: "Test Account 25", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 25, Name: "Test Account 25", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 8, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
-	err = UpdateTransaction(db, transaction)
+	transaction := Transaction{ID: 8, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = UpdateTransaction(db, testScope, transaction)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -495,25 +609,26 @@ func TestUpdateTransactionNotExists(t *testing.T) {
 
 func TestDeleteTransaction(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 26, Name: "Test Account 26", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 26, Name: "Test Account 26", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 27, Name: "Test Account 27", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 27, Name: "Test Account 27", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 9, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
-	_, err = db.Exec("INSERT INTO transactions (id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", transaction.ID, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description)
+	// drafts are deleted, posted transactions are reversed
+	transaction := Transaction{ID: 9, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, id, amount, debit, offset_account, account, date, description, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", testScope.Ledger, transaction.ID, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description, StatusDraft)
 	if err != nil {
 		t.Error(err)
 	}
 
-	err = DeleteTransaction(db, int(transaction.ID))
+	_, err = DeleteTransaction(db, testScope, int(transaction.ID))
 	if err != nil {
 		t.Error(err)
 	}
@@ -527,7 +642,7 @@ func TestDeleteTransaction(t *testing.T) {
 }
 
 func TestDeleteTransactionNotExists(t *testing.T) {
-	err := DeleteTransaction(db, 10)
+	_, err := DeleteTransaction(db, testScope, 10)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -537,20 +652,20 @@ func TestDeleteTransactionNotExists(t *testing.T) {
 
 func TestGetTransaction(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 28, Name: "Test Account 28", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 28, Name: "Test Account 28", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 29, Name: "Test Account 29", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 29, Name: "Test Account 29", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction := Transaction{ID: 1, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description)
+	transaction := Transaction{ID: 1, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: time.Now(), Description: "Test Transaction"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction.Amount, transaction.Debit, transaction.OffsetAccount, transaction.Account, transaction.Date, transaction.Description)
 	if err != nil {
 		t.Error(err)
 	}
@@ -561,7 +676,7 @@ func TestGetTransaction(t *testing.T) {
 		t.Error(err)
 	}
 
-	result, err := GetTransaction(db, id)
+	result, err := GetTransaction(db, testScope, id)
 	if err != nil {
 		t.Error(err)
 	}
@@ -572,7 +687,7 @@ func TestGetTransaction(t *testing.T) {
 }
 
 func TestGetTransactionNotExists(t *testing.T) {
-	_, err := GetTransaction(db, 2)
+	_, err := GetTransaction(db, testScope, 2)
 	if err == nil {
 		t.Error("expected error")
 	}
@@ -582,33 +697,33 @@ func TestGetTransactionNotExists(t *testing.T) {
 
 func TestGetTransactionsAccountYear(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
 	date, _ := time.Parse("2006-01-02", "2024-01-01")
 
-	transaction1 := Transaction{ID: 1, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
+	transaction1 := Transaction{ID: 1, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction2 := Transaction{ID: 2, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
+	transaction2 := Transaction{ID: 2, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transactions, err := GetTransactions(db, 31, 2024, 0)
+	transactions, err := GetTransactions(db, testScope, 31, 2024, 0, "")
 	if err != nil {
 		t.Error(err)
 	}
@@ -621,33 +736,33 @@ func TestGetTransactionsAccountYear(t *testing.T) {
 
 func TestGetTransactionsOfffsetAccountYear(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
 	date, _ := time.Parse("2006-01-02", "2024-01-01")
 
-	transaction1 := Transaction{ID: 1, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
+	transaction1 := Transaction{ID: 1, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction2 := Transaction{ID: 2, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
+	transaction2 := Transaction{ID: 2, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transactions, err := GetTransactions(db, 30, 2024, 0)
+	transactions, err := GetTransactions(db, testScope, 30, 2024, 0, "")
 	if err != nil {
 		t.Error(err)
 	}
@@ -660,33 +775,33 @@ func TestGetTransactionsOfffsetAccountYear(t *testing.T) {
 
 func TestGetTransactionsAccountYearMonth(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
 	date, _ := time.Parse("2006-01-02", "2024-01-01")
 
-	transaction1 := Transaction{ID: 1, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
+	transaction1 := Transaction{ID: 1, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction2 := Transaction{ID: 2, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
+	transaction2 := Transaction{ID: 2, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transactions, err := GetTransactions(db, 31, 2024, 1)
+	transactions, err := GetTransactions(db, testScope, 31, 2024, 1, "")
 	if err != nil {
 		t.Error(err)
 	}
@@ -699,33 +814,33 @@ func TestGetTransactionsAccountYearMonth(t *testing.T) {
 
 func TestGetTransactionsOfffsetAccountYearMonth(t *testing.T) {
 	cleanTables()
-	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "1000.00"}
-	_, err := db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account1.ID, account1.Name, account1.Kind)
+	account1 := Account{ID: 30, Name: "Test Account 30", Kind: "asset"}
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account1.ID, account1.Name, account1.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
-	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "1000.00"}
-	_, err = db.Exec("INSERT INTO accounts (id, name, kind) VALUES ($1, $2, $3)", account2.ID, account2.Name, account2.Kind)
+	account2 := Account{ID: 31, Name: "Test Account 31", Kind: "asset"}
+	_, err = db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account2.ID, account2.Name, account2.Kind)
 	if err != nil {
 		t.Error(err)
 	}
 
 	date, _ := time.Parse("2006-01-02", "2024-01-01")
 
-	transaction1 := Transaction{ID: 1, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
+	transaction1 := Transaction{ID: 1, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 1"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction1.Amount, transaction1.Debit, transaction1.OffsetAccount, transaction1.Account, transaction1.Date, transaction1.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transaction2 := Transaction{ID: 2, Amount: 1234.56, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
-	_, err = db.Exec("INSERT INTO transactions ( amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6)", transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
+	transaction2 := Transaction{ID: 2, Amount: 123456, Debit: true, OffsetAccount: account1.ID, Account: account2.ID, Date: date, Description: "Test Transaction 2"}
+	_, err = db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description) VALUES ($1, $2, $3, $4, $5, $6, $7)", testScope.Ledger, transaction2.Amount, transaction2.Debit, transaction2.OffsetAccount, transaction2.Account, transaction2.Date, transaction2.Description)
 	if err != nil {
 		t.Error(err)
 	}
 
-	transactions, err := GetTransactions(db, 30, 2024, 1)
+	transactions, err := GetTransactions(db, testScope, 30, 2024, 1, "")
 	if err != nil {
 		t.Error(err)
 	}
@@ -772,6 +887,33 @@ func TestNewUserAlreadyExists(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestNewUserWithLedger(t *testing.T) {
+	cleanTables()
+	err := NewUserWithLedger(db, User{Name: "Ledger User", Password: "password"}, "Default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := GetUserByName(db, "Ledger User")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, err := DefaultLedger(db, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Default", ledger.Name)
+	assert.Equal(t, RoleOwner, ledger.Role)
+
+	// a ledger that cannot be created takes the user with it
+	err = NewUserWithLedger(db, User{Name: "No Ledger User", Password: "password"}, "")
+	assert.Error(t, err)
+
+	_, err = GetUserByName(db, "No Ledger User")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestUpdateUser(t *testing.T) {
 	cleanTables()
 	iniUser := User{ID: "2", Name: "Test User", Password: "password"}
@@ -907,3 +1049,28 @@ func TestAuthenticateUser(t *testing.T) {
 	t.Log("Expected user:", user.Name)
 	assert.Equal(t, user.Name, result.Name)
 }
+
+func TestGetPostedTransactions(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	for _, transaction := range []Transaction{
+		{Amount: 1000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 2000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 31, 18, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 3000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 4000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+	} {
+		_, err := NewTransaction(db, testScope, transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transactions, err := GetPostedTransactions(db, testScope, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, transactions, 2) {
+		assert.Equal(t, Money(3000), transactions[0].Amount)
+		assert.Equal(t, Money(2000), transactions[1].Amount)
+	}
+}
@@ -0,0 +1,437 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Period statuses. Anyone who may book can book into an open period. A
+// soft-closed period only takes bookings from owners and can be reopened. A
+// locked period takes no bookings at all and stays locked.
+const (
+	PeriodOpen       = "open"
+	PeriodSoftClosed = "soft_closed"
+	PeriodLocked     = "locked"
+)
+
+var (
+	ErrFiscalYearNotExists   = errors.New("fiscal year does not exist")
+	ErrFiscalYearOverlap     = errors.New("fiscal year overlaps an existing fiscal year")
+	ErrFiscalYearClosed      = errors.New("fiscal year is already closed")
+	ErrFiscalPeriodNotExists = errors.New("fiscal period does not exist")
+	ErrInvalidPeriodStatus   = errors.New("invalid status; must be one of open, soft_closed or locked")
+	ErrPeriodClosed          = errors.New("period is soft-closed; only owners can book into it")
+	ErrPeriodLocked          = errors.New("period is locked")
+	ErrRetainedEarnings      = errors.New("retained earnings must be an equity account")
+)
+
+// FiscalYear is a business year of twelve monthly periods. ClosingEntry is the
+// journal entry the year-end close posted, or 0.
+type FiscalYear struct {
+	ID           uint
+	Start        time.Time
+	End          time.Time
+	Closed       bool
+	ClosingEntry uint
+	Periods      []FiscalPeriod
+}
+
+type FiscalPeriod struct {
+	ID     uint
+	Number int
+	Start  time.Time
+	End    time.Time
+	Status string
+}
+
+// OpeningBalance is the balance, debit minus credit, an asset, liability or
+// equity account carried into a fiscal year when the year before was closed.
+type OpeningBalance struct {
+	Account uint
+	Name    string
+	Kind    string
+	Balance Money
+}
+
+func ValidPeriodStatus(status string) bool {
+	return status == PeriodOpen || status == PeriodSoftClosed || status == PeriodLocked
+}
+
+// checkPeriods makes sure bookings dated on the given days may be written.
+// Days outside of every fiscal year are not restricted.
+func checkPeriods(database querier, scope Scope, dates ...time.Time) error {
+	for _, date := range dates {
+		var start, end time.Time
+		var status string
+		err := database.QueryRow("SELECT start_date, end_date, status FROM fiscal_periods WHERE ledger_id = $1 AND start_date <= $2 AND end_date >= $2", scope.Ledger, day(date)).Scan(&start, &end, &status)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return err
+		}
+
+		if status == PeriodLocked || (status == PeriodSoftClosed && scope.Role != RoleOwner) {
+			cause := ErrPeriodLocked
+			if status == PeriodSoftClosed {
+				cause = ErrPeriodClosed
+			}
+			return fmt.Errorf("date %s is in the period %s to %s: %w", date.Format(time.DateOnly), start.Format(time.DateOnly), end.Format(time.DateOnly), cause)
+		}
+	}
+	return nil
+}
+
+// FiscalYearStart returns the first day of the fiscal year date falls in.
+// Outside of every fiscal year the fiscal year is the calendar year.
+func FiscalYearStart(database *sql.DB, scope Scope, date time.Time) (time.Time, error) {
+	var start time.Time
+	err := database.QueryRow("SELECT start_date FROM fiscal_years WHERE ledger_id = $1 AND start_date <= $2 AND end_date >= $2", scope.Ledger, day(date)).Scan(&start)
+	if err == sql.ErrNoRows {
+		return time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return start, nil
+}
+
+// NewFiscalYear creates a fiscal year starting on start, with twelve open
+// monthly periods.
+func NewFiscalYear(database *sql.DB, scope Scope, start time.Time) (FiscalYear, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return FiscalYear{}, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertFiscalYear(tx, scope, start)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return FiscalYear{}, err
+	}
+	return GetFiscalYear(database, scope, int(id))
+}
+
+func insertFiscalYear(database querier, scope Scope, start time.Time) (uint, error) {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, -1)
+
+	var overlap bool
+	err := database.QueryRow("SELECT EXISTS (SELECT 1 FROM fiscal_years WHERE ledger_id = $1 AND start_date <= $3 AND end_date >= $2)", scope.Ledger, start, end).Scan(&overlap)
+	if err != nil {
+		return 0, err
+	}
+	if overlap {
+		return 0, ErrFiscalYearOverlap
+	}
+
+	var id uint
+	err = database.QueryRow("INSERT INTO fiscal_years (ledger_id, start_date, end_date) VALUES ($1, $2, $3) RETURNING id", scope.Ledger, start, end).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	year := FiscalYear{ID: id, Start: start, End: end}
+	for number := 1; number <= 12; number++ {
+		periodStart := start.AddDate(0, number-1, 0)
+		periodEnd := start.AddDate(0, number, -1)
+		year.Periods = append(year.Periods, FiscalPeriod{Number: number, Start: periodStart, End: periodEnd, Status: PeriodOpen})
+		_, err = database.Exec("INSERT INTO fiscal_periods (fiscal_year_id, ledger_id, number, start_date, end_date, status) VALUES ($1, $2, $3, $4, $5, $6)", id, scope.Ledger, number, periodStart, periodEnd, PeriodOpen)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = recordAudit(database, scope.Ledger, scope.User, EntityFiscalYear, id, ActionCreate, nil, year)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func GetFiscalYear(database *sql.DB, scope Scope, id int) (FiscalYear, error) {
+	years, err := getFiscalYears(database, scope, id)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+	if len(years) == 0 {
+		return FiscalYear{}, ErrFiscalYearNotExists
+	}
+	return years[0], nil
+}
+
+// GetFiscalYears returns every fiscal year of the ledger with its periods,
+// oldest first.
+func GetFiscalYears(database *sql.DB, scope Scope) ([]FiscalYear, error) {
+	return getFiscalYears(database, scope, 0)
+}
+
+// getFiscalYears loads one fiscal year, or all of them when id is 0.
+func getFiscalYears(database querier, scope Scope, id int) ([]FiscalYear, error) {
+	query := "SELECT y.id, y.start_date, y.end_date, y.closed, y.closing_entry_id, p.id, p.number, p.start_date, p.end_date, p.status FROM fiscal_years y JOIN fiscal_periods p ON p.fiscal_year_id = y.id WHERE y.ledger_id = $1"
+	args := []any{scope.Ledger}
+	if id != 0 {
+		args = append(args, id)
+		query += " AND y.id = $" + strconv.Itoa(len(args))
+	}
+	query += " ORDER BY y.start_date, p.number"
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var years []FiscalYear
+	for rows.Next() {
+		var year FiscalYear
+		var closingEntry sql.NullInt64
+		var period FiscalPeriod
+		err := rows.Scan(&year.ID, &year.Start, &year.End, &year.Closed, &closingEntry, &period.ID, &period.Number, &period.Start, &period.End, &period.Status)
+		if err != nil {
+			return nil, err
+		}
+		year.ClosingEntry = uint(closingEntry.Int64)
+
+		if len(years) == 0 || years[len(years)-1].ID != year.ID {
+			years = append(years, year)
+		}
+		last := &years[len(years)-1]
+		last.Periods = append(last.Periods, period)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return years, nil
+}
+
+// SetPeriodStatus opens, soft-closes or locks a period. Locking cannot be
+// undone.
+func SetPeriodStatus(database *sql.DB, scope Scope, id int, status string) error {
+	if !ValidPeriodStatus(status) {
+		return ErrInvalidPeriodStatus
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current FiscalPeriod
+	err = tx.QueryRow("SELECT id, number, start_date, end_date, status FROM fiscal_periods WHERE ledger_id = $1 AND id = $2"+dialectOf(database).forUpdate, scope.Ledger, id).Scan(&current.ID, &current.Number, &current.Start, &current.End, &current.Status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrFiscalPeriodNotExists
+		}
+		return err
+	}
+
+	if current.Status == PeriodLocked && status != PeriodLocked {
+		return ErrPeriodLocked
+	}
+
+	_, err = tx.Exec("UPDATE fiscal_periods SET status = $1 WHERE ledger_id = $2 AND id = $3", status, scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	changed := current
+	changed.Status = status
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityFiscalPeriod, id, ActionUpdate, current, changed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CloseFiscalYear posts the year-end close. One closing entry, dated on the
+// last day of the year, brings every revenue and expense account to zero and
+// books the result to retainedEarnings. The periods of the year are locked,
+// the next fiscal year is created if it does not exist yet, and the balances
+// of the asset, liability and equity accounts are stored as its opening
+// balances.
+func CloseFiscalYear(database *sql.DB, scope Scope, id int, retainedEarnings uint) (FiscalYear, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return FiscalYear{}, err
+	}
+	defer tx.Rollback()
+
+	var start, end time.Time
+	var closed bool
+	err = tx.QueryRow("SELECT start_date, end_date, closed FROM fiscal_years WHERE ledger_id = $1 AND id = $2"+dialectOf(database).forUpdate, scope.Ledger, id).Scan(&start, &end, &closed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return FiscalYear{}, ErrFiscalYearNotExists
+		}
+		return FiscalYear{}, err
+	}
+	if closed {
+		return FiscalYear{}, ErrFiscalYearClosed
+	}
+
+	var kind string
+	err = tx.QueryRow("SELECT kind FROM accounts WHERE ledger_id = $1 AND id = $2", scope.Ledger, retainedEarnings).Scan(&kind)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return FiscalYear{}, fmt.Errorf("account %d: %w", retainedEarnings, ErrAccountNotExists)
+		}
+		return FiscalYear{}, err
+	}
+	if kind != KindEquity {
+		return FiscalYear{}, ErrRetainedEarnings
+	}
+
+	balances, err := buildTrialBalance(tx, scope, time.Time{}, end, false)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	// the close is the owner's decision, its entry needs no approval
+	entry := JournalEntry{
+		Type:        EntryClosing,
+		Status:      StatusPosted,
+		Date:        end,
+		Description: "Year-end close " + start.Format(time.DateOnly) + " to " + end.Format(time.DateOnly),
+	}
+
+	// every line takes the opposite side of the account balance, the
+	// result goes to retained earnings
+	var result Money
+	for _, line := range balances.Lines {
+		if line.Group || line.Closing == 0 || (line.Kind != KindRevenue && line.Kind != KindExpense) {
+			continue
+		}
+		entry.Lines = append(entry.Lines, JournalLine{Account: line.Account, Amount: abs(line.Closing), Debit: line.Closing < 0})
+		result += line.Closing
+	}
+	if result != 0 {
+		entry.Lines = append(entry.Lines, JournalLine{Account: retainedEarnings, Amount: abs(result), Debit: result > 0})
+	}
+
+	var closingEntry any
+	if len(entry.Lines) > 0 {
+		err = ValidateJournalEntry(entry)
+		if err != nil {
+			return FiscalYear{}, err
+		}
+
+		closingEntry, err = insertJournalEntry(tx, scope, entry)
+		if err != nil {
+			return FiscalYear{}, err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE fiscal_periods SET status = $1 WHERE fiscal_year_id = $2", PeriodLocked, id)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	_, err = tx.Exec("UPDATE fiscal_years SET closed = true, closing_entry_id = $1 WHERE id = $2", closingEntry, id)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	// locking its periods is part of closing the year
+	before := FiscalYear{ID: uint(id), Start: start, End: end}
+	after := before
+	after.Closed = true
+	if closingEntry != nil {
+		after.ClosingEntry = closingEntry.(uint)
+	}
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityFiscalYear, id, ActionUpdate, before, after)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	nextStart := end.AddDate(0, 0, 1)
+	var next uint
+	err = tx.QueryRow("SELECT id FROM fiscal_years WHERE ledger_id = $1 AND start_date = $2", scope.Ledger, nextStart).Scan(&next)
+	if err == sql.ErrNoRows {
+		next, err = insertFiscalYear(tx, scope, nextStart)
+	}
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	balances, err = buildTrialBalance(tx, scope, time.Time{}, end, false)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	_, err = tx.Exec("DELETE FROM opening_balances WHERE fiscal_year_id = $1", next)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	for _, line := range balances.Lines {
+		if line.Group || line.Closing == 0 || line.Kind == KindRevenue || line.Kind == KindExpense {
+			continue
+		}
+		_, err = tx.Exec("INSERT INTO opening_balances (fiscal_year_id, ledger_id, account, balance) VALUES ($1, $2, $3, $4)", next, scope.Ledger, line.Account, line.Closing)
+		if err != nil {
+			return FiscalYear{}, err
+		}
+	}
+
+	years, err := getFiscalYears(tx, scope, id)
+	if err != nil {
+		return FiscalYear{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return FiscalYear{}, err
+	}
+	return years[0], nil
+}
+
+// GetOpeningBalances returns the balances carried into a fiscal year by the
+// close of the year before. The list is empty until that close.
+func GetOpeningBalances(database *sql.DB, scope Scope, year int) ([]OpeningBalance, error) {
+	_, err := GetFiscalYear(database, scope, year)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.Query("SELECT o.account, a.name, a.kind, o.balance FROM opening_balances o JOIN accounts a ON a.ledger_id = o.ledger_id AND a.id = o.account WHERE o.ledger_id = $1 AND o.fiscal_year_id = $2 ORDER BY o.account", scope.Ledger, year)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []OpeningBalance
+	for rows.Next() {
+		var balance OpeningBalance
+		err := rows.Scan(&balance.Account, &balance.Name, &balance.Kind, &balance.Balance)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+func abs(amount Money) Money {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
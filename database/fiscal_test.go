@@ -0,0 +1,276 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fiscalAccounts are the accounts a year needs to be closed.
+var fiscalAccounts = []Account{
+	{ID: 860, Name: "Retained earnings", Kind: KindEquity},
+	{ID: 1200, Name: "Bank", Kind: KindAsset},
+	{ID: 4900, Name: "Other expenses", Kind: KindExpense},
+	{ID: 8400, Name: "Revenue", Kind: KindRevenue},
+}
+
+func TestNewFiscalYear(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), year.End.UTC())
+	assert.Len(t, year.Periods, 12)
+	assert.Equal(t, time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC), year.Periods[1].End.UTC())
+	assert.Equal(t, PeriodOpen, year.Periods[11].Status)
+}
+
+func TestNewFiscalYearOverlap(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	_, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewFiscalYear(db, testScope, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.ErrorIs(t, err, ErrFiscalYearOverlap)
+}
+
+func TestLockedPeriodRejectsBookings(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewTransaction(db, testScope, Transaction{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), Status: StatusPosted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id int
+	err = db.QueryRow("SELECT id FROM transactions WHERE ledger_id = $1", testScope.Ledger).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[2].ID), PeriodLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewTransaction(db, testScope, Transaction{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC), Status: StatusPosted})
+	assert.ErrorIs(t, err, ErrPeriodLocked)
+
+	_, err = NewJournalEntry(db, testScope, JournalEntry{Date: time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC), Lines: []JournalLine{{Account: 4900, Amount: 500, Debit: true}, {Account: 1200, Amount: 500}}, Status: StatusPosted})
+	assert.ErrorIs(t, err, ErrPeriodLocked)
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[1].ID), PeriodLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a booking in a locked period is reversed in the period of today
+	reversal, err := DeleteTransaction(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reversed, err := GetTransaction(db, testScope, int(reversal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Now().UTC().Truncate(24*time.Hour), reversed.Date.UTC())
+}
+
+func TestReversalNeedsOpenPeriod(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := NewTransaction(db, testScope, Transaction{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), Status: StatusPosted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[1].ID), PeriodLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := time.Now().UTC()
+	current, err := NewFiscalYear(db, testScope, time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetPeriodStatus(db, testScope, int(current.Periods[today.Month()-1].ID), PeriodLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = DeleteTransaction(db, testScope, int(id))
+	assert.ErrorIs(t, err, ErrPeriodLocked)
+}
+
+func TestSoftClosedPeriod(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[0].ID), PeriodSoftClosed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction := Transaction{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 1, 20, 0, 0, 0, 0, time.UTC), Status: StatusPosted}
+
+	accountant := testScope
+	accountant.Role = RoleAccountant
+	_, err = NewTransaction(db, accountant, transaction)
+	assert.ErrorIs(t, err, ErrPeriodClosed)
+
+	// owners may still post adjustments
+	_, err = NewTransaction(db, testScope, transaction)
+	assert.NoError(t, err)
+
+	// and the period can be reopened
+	err = SetPeriodStatus(db, testScope, int(year.Periods[0].ID), PeriodOpen)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewTransaction(db, accountant, transaction)
+	assert.NoError(t, err)
+}
+
+func TestSetPeriodStatusLockedCannotReopen(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[0].ID), PeriodLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[0].ID), PeriodOpen)
+
+	assert.ErrorIs(t, err, ErrPeriodLocked)
+}
+
+func TestCloseFiscalYear(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, transaction := range []Transaction{
+		{Amount: 50000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 20000, Debit: true, Account: 4900, OffsetAccount: 1200, Date: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+		{Amount: 7000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), Status: StatusPosted},
+	} {
+		_, err := NewTransaction(db, testScope, transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	closed, err := CloseFiscalYear(db, testScope, int(year.ID), 860)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, closed.Closed)
+	assert.NotZero(t, closed.ClosingEntry)
+	for _, period := range closed.Periods {
+		assert.Equal(t, PeriodLocked, period.Status)
+	}
+
+	entry, err := GetJournalEntry(db, testScope, int(closed.ClosingEntry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, EntryClosing, entry.Type)
+	assert.Len(t, entry.Lines, 3)
+
+	end := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	revenue, err := GetBalance(db, testScope, 8400, end)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(0), revenue.Balance)
+
+	retained, err := GetBalance(db, testScope, 860, end)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(-30000), retained.Balance)
+
+	// the statement of the closed year still shows its result
+	profitAndLoss, err := GetProfitAndLoss(db, testScope, year.Start, end)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(30000), profitAndLoss.NetIncome)
+
+	years, err := GetFiscalYears(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, years, 2)
+
+	openingBalances, err := GetOpeningBalances(db, testScope, int(years[1].ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []OpeningBalance{
+		{Account: 860, Name: "Retained earnings", Kind: KindEquity, Balance: -30000},
+		{Account: 1200, Name: "Bank", Kind: KindAsset, Balance: 30000},
+	}, openingBalances)
+
+	_, err = CloseFiscalYear(db, testScope, int(year.ID), 860)
+	assert.ErrorIs(t, err, ErrFiscalYearClosed)
+}
+
+func TestCloseFiscalYearRetainedEarningsKind(t *testing.T) {
+	insertBooks(t, fiscalAccounts, nil, nil)
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CloseFiscalYear(db, testScope, int(year.ID), 1200)
+
+	assert.ErrorIs(t, err, ErrRetainedEarnings)
+}
+
+func TestFiscalYearStart(t *testing.T) {
+	cleanTables()
+	_, err := NewFiscalYear(db, testScope, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, err := FiscalYearStart(db, testScope, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), start.UTC())
+
+	// without a fiscal year the calendar year counts
+	start, err = FiscalYearStart(db, testScope, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), start)
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrParentNotExists   = errors.New("parent account does not exist")
+	ErrParentNotGroup    = errors.New("parent account must be a group account")
+	ErrAccountCycle      = errors.New("parent account would create a cycle")
+	ErrGroupAccount      = errors.New("cannot post to a group account")
+	ErrGroupHasChildren  = errors.New("group account still has child accounts")
+	ErrAccountHasEntries = errors.New("account has bookings and cannot become a group account")
+)
+
+// AccountNode is an account in the chart of accounts tree. Balance is debit
+// minus credit; for group accounts it is the sum of all descendants.
+type AccountNode struct {
+	Account
+	Balance  Money
+	Children []AccountNode
+}
+
+// nullID stores a zero id as NULL.
+func nullID(id uint) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// accountSource is where the account checks look the accounts of a ledger
+// up: the database for the SQL store, the maps for MemoryStore.
+type accountSource interface {
+	// lookupAccount returns an account and whether it exists.
+	lookupAccount(ledger uint, id uint) (Account, bool, error)
+	// hasBookings reports whether an account is booked on.
+	hasBookings(ledger uint, id uint) (bool, error)
+	// hasChildren reports whether an account is the parent of others.
+	hasChildren(ledger uint, id uint) (bool, error)
+}
+
+// sqlAccounts is the accountSource of the SQL store.
+type sqlAccounts struct {
+	database querier
+}
+
+func (s sqlAccounts) lookupAccount(ledger uint, id uint) (Account, bool, error) {
+	account, err := getAccount(s.database, ledger, int(id))
+	if err == ErrAccountNotExists {
+		return account, false, nil
+	}
+	return account, err == nil, err
+}
+
+func (s sqlAccounts) hasBookings(ledger uint, id uint) (bool, error) {
+	var booked bool
+	err := s.database.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE ledger_id = $1 AND (account = $2 OR offset_account = $2)) OR EXISTS (SELECT 1 FROM journal_lines WHERE ledger_id = $1 AND account = $2)", ledger, id).Scan(&booked)
+	return booked, err
+}
+
+func (s sqlAccounts) hasChildren(ledger uint, id uint) (bool, error) {
+	var children bool
+	err := s.database.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE ledger_id = $1 AND parent = $2)", ledger, id).Scan(&children)
+	return children, err
+}
+
+// validateParent makes sure the parent of an account exists, is a group
+// account and is not the account itself or one of its descendants.
+func validateParent(source accountSource, ledger uint, account Account) error {
+	if account.Parent == 0 {
+		return nil
+	}
+
+	if account.Parent == account.ID {
+		return ErrAccountCycle
+	}
+
+	parent, ok, err := source.lookupAccount(ledger, account.Parent)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrParentNotExists
+	}
+	if !parent.Group {
+		return ErrParentNotGroup
+	}
+
+	// the visited accounts stop the walk should a cycle already exist
+	visited := make(map[uint]bool)
+	for id := parent.Parent; id != 0 && !visited[id]; {
+		if id == account.ID {
+			return ErrAccountCycle
+		}
+		visited[id] = true
+
+		ancestor, ok, err := source.lookupAccount(ledger, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		id = ancestor.Parent
+	}
+	return nil
+}
+
+// validateGroupChange makes sure an account only becomes a group account when
+// it has no bookings, and only stops being one when it has no children.
+func validateGroupChange(source accountSource, ledger uint, account Account) error {
+	if account.Group {
+		booked, err := source.hasBookings(ledger, account.ID)
+		if err != nil {
+			return err
+		}
+		if booked {
+			return ErrAccountHasEntries
+		}
+		return nil
+	}
+
+	children, err := source.hasChildren(ledger, account.ID)
+	if err != nil {
+		return err
+	}
+	if children {
+		return ErrGroupHasChildren
+	}
+	return nil
+}
+
+// checkPostable rejects bookings on group accounts, whose balances only roll
+// up from their children, and on accounts outside of the ledger.
+func checkPostable(source accountSource, ledger uint, accounts ...uint) error {
+	for _, id := range accounts {
+		account, ok, err := source.lookupAccount(ledger, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("account %d: %w", id, ErrAccountNotExists)
+		}
+		if account.Group {
+			return fmt.Errorf("account %d: %w", id, ErrGroupAccount)
+		}
+	}
+	return nil
+}
+
+// GetAccountTree returns the chart of accounts as a tree with the balances
+// as of asOf rolled up into the group accounts. A zero asOf includes every
+// booking.
+func GetAccountTree(database *sql.DB, scope Scope, asOf time.Time) ([]AccountNode, error) {
+	trialBalance, err := GetTrialBalance(database, scope, time.Time{}, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]TrialBalanceLine)
+	known := make(map[uint]bool)
+	for _, line := range trialBalance.Lines {
+		known[line.Account] = true
+	}
+
+	var roots []TrialBalanceLine
+	for _, line := range trialBalance.Lines {
+		if line.Parent == 0 || !known[line.Parent] {
+			roots = append(roots, line)
+			continue
+		}
+		children[line.Parent] = append(children[line.Parent], line)
+	}
+
+	var build func(line TrialBalanceLine) AccountNode
+	build = func(line TrialBalanceLine) AccountNode {
+		node := AccountNode{
+			Account: Account{ID: line.Account, Name: line.Name, Kind: line.Kind, Parent: line.Parent, Group: line.Group},
+			Balance: line.Closing,
+		}
+
+		for _, child := range children[line.Account] {
+			childNode := build(child)
+			node.Balance += childNode.Balance
+			node.Children = append(node.Children, childNode)
+		}
+		return node
+	}
+
+	var tree []AccountNode
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+	return tree, nil
+}
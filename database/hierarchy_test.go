@@ -0,0 +1,118 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hierarchyAccounts are two groups with accounts below them.
+var hierarchyAccounts = []Account{
+	{ID: 1000, Name: "Current assets", Kind: KindAsset, Group: true},
+	{ID: 1200, Name: "Bank", Kind: KindAsset, Parent: 1000},
+	{ID: 1210, Name: "Savings", Kind: KindAsset, Parent: 1000},
+	{ID: 8000, Name: "Revenue", Kind: KindRevenue, Group: true},
+	{ID: 8400, Name: "Sales", Kind: KindRevenue, Parent: 8000},
+}
+
+func TestNewAccountWithParent(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	account, err := GetAccount(db, testScope, 1200)
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, uint(1000), account.Parent)
+	assert.False(t, account.Group)
+}
+
+func TestNewAccountParentNotGroup(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	err := NewAccount(db, testScope, Account{ID: 1220, Name: "Petty cash", Kind: KindAsset, Parent: 1200})
+
+	assert.ErrorIs(t, err, ErrParentNotGroup)
+}
+
+func TestNewAccountParentNotExists(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	err := NewAccount(db, testScope, Account{ID: 1220, Name: "Petty cash", Kind: KindAsset, Parent: 1111})
+
+	assert.ErrorIs(t, err, ErrParentNotExists)
+}
+
+func TestUpdateAccountCycle(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	err := NewAccount(db, testScope, Account{ID: 1100, Name: "Liquid assets", Kind: KindAsset, Parent: 1000, Group: true})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = UpdateAccount(db, testScope, Account{ID: 1000, Name: "Current assets", Kind: KindAsset, Parent: 1100, Group: true})
+
+	assert.ErrorIs(t, err, ErrAccountCycle)
+}
+
+func TestUpdateAccountGroupWithChildren(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	err := UpdateAccount(db, testScope, Account{ID: 1000, Name: "Current assets", Kind: KindAsset})
+
+	assert.ErrorIs(t, err, ErrGroupHasChildren)
+}
+
+func TestNewTransactionGroupAccount(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	_, err := NewTransaction(db, testScope, Transaction{Amount: 1000, Debit: true, Account: 1000, OffsetAccount: 8400, Date: time.Now()})
+
+	assert.ErrorIs(t, err, ErrGroupAccount)
+}
+
+func TestGetAccountTree(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	_, err := NewTransaction(db, testScope, Transaction{Amount: 1000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Now(), Status: StatusPosted})
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = NewTransaction(db, testScope, Transaction{Amount: 500, Debit: true, Account: 1210, OffsetAccount: 8400, Date: time.Now(), Status: StatusPosted})
+	if err != nil {
+		t.Error(err)
+	}
+
+	tree, err := GetAccountTree(db, testScope, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, tree, 2)
+	assert.Equal(t, uint(1000), tree[0].ID)
+	assert.Equal(t, Money(1500), tree[0].Balance)
+	assert.Len(t, tree[0].Children, 2)
+	assert.Equal(t, Money(-1500), tree[1].Balance)
+}
+
+func TestGetBalanceSheetHierarchy(t *testing.T) {
+	insertBooks(t, hierarchyAccounts, nil, nil)
+
+	_, err := NewTransaction(db, testScope, Transaction{Amount: 1000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Now(), Status: StatusPosted})
+	if err != nil {
+		t.Error(err)
+	}
+
+	balanceSheet, err := GetBalanceSheet(db, testScope, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Len(t, balanceSheet.Assets.Lines, 1)
+	assert.Equal(t, uint(1000), balanceSheet.Assets.Lines[0].Account)
+	assert.Equal(t, Money(1000), balanceSheet.Assets.Lines[0].Amount)
+	assert.Len(t, balanceSheet.Assets.Lines[0].Children, 1)
+	assert.True(t, balanceSheet.Balanced)
+}
@@ -0,0 +1,306 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	SourceJournal     = "journal"
+	SourceTransaction = "transaction"
+)
+
+// Entry types. Closing entries carry the revenue and expense balances into
+// retained earnings at the end of a fiscal year; the profit and loss
+// statement leaves them out.
+const (
+	EntryStandard = "standard"
+	EntryClosing  = "closing"
+)
+
+var (
+	ErrJournalEntryNotExists  = errors.New("journal entry does not exist")
+	ErrJournalEntryUnbalanced = errors.New("journal entry is not balanced; debits must equal credits")
+)
+
+// ValidateJournalEntry checks an entry before it is stored: it needs a date,
+// at least one debit and one credit line with positive amounts, and the sum
+// of its debit lines must equal the sum of its credit lines.
+func ValidateJournalEntry(entry JournalEntry) error {
+	if entry.Date.IsZero() {
+		return errors.New("date is required")
+	}
+
+	if len(entry.Lines) < 2 {
+		return errors.New("journal entry needs at least two lines")
+	}
+
+	var debits, credits Money
+	for i, line := range entry.Lines {
+		if line.Account < 1 {
+			return fmt.Errorf("line %d: account is required", i+1)
+		}
+		if line.Amount <= 0 {
+			return fmt.Errorf("line %d: amount must be greater than 0", i+1)
+		}
+
+		if line.Debit {
+			debits += line.Amount
+		} else {
+			credits += line.Amount
+		}
+	}
+
+	if debits == 0 || credits == 0 {
+		return errors.New("journal entry needs at least one debit and one credit line")
+	}
+
+	if debits != credits {
+		return ErrJournalEntryUnbalanced
+	}
+
+	return nil
+}
+
+// NewJournalEntry stores a journal entry and returns its id. Like
+// transactions, entries start as drafts and only count once they are
+// approved and posted; callers that book without review can pass
+// StatusPosted.
+func NewJournalEntry(database *sql.DB, scope Scope, entry JournalEntry) (uint, error) {
+	if entry.Status == "" {
+		entry.Status = StatusDraft
+	}
+
+	if entry.Status != StatusDraft && entry.Status != StatusPosted {
+		return 0, ErrInvalidTransactionStatus
+	}
+
+	err := ValidateJournalEntry(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if entry.Status == StatusPosted {
+		err = checkPeriods(tx, scope, entry.Date)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// only the year-end close writes closing entries
+	entry.Type = EntryStandard
+	id, err := insertJournalEntry(tx, scope, entry)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertJournalEntry stores a validated entry and its lines, prepared by the
+// user of scope.
+func insertJournalEntry(database querier, scope Scope, entry JournalEntry) (uint, error) {
+	for _, line := range entry.Lines {
+		err := checkPostable(sqlAccounts{database}, scope.Ledger, line.Account)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	entry.PreparedBy = scope.User
+	var id uint
+	err := database.QueryRow("INSERT INTO journal_entries (ledger_id, date, description, type, status, prepared_by) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id", scope.Ledger, entry.Date, entry.Description, entry.Type, entry.Status, entry.PreparedBy).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range entry.Lines {
+		_, err = database.Exec("INSERT INTO journal_lines (entry_id, ledger_id, account, amount, debit) VALUES ($1, $2, $3, $4, $5)", id, scope.Ledger, line.Account, line.Amount, line.Debit)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	entry.ID = id
+	err = recordAudit(database, scope.Ledger, scope.User, EntityJournalEntry, id, ActionCreate, nil, entry)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func GetJournalEntry(database *sql.DB, scope Scope, id int) (JournalEntry, error) {
+	rows, err := database.Query("SELECT "+journalEntryColumns+" FROM journal_entries e JOIN journal_lines l ON l.entry_id = e.id WHERE e.ledger_id = $1 AND e.id = $2 ORDER BY l.id", scope.Ledger, id)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+	defer rows.Close()
+
+	entries, err := scanJournalEntries(rows)
+	if err != nil {
+		return JournalEntry{}, err
+	}
+
+	if len(entries) == 0 {
+		return JournalEntry{}, ErrJournalEntryNotExists
+	}
+	return entries[0], nil
+}
+
+// GetJournalEntries returns every entry touching the account in the given
+// year, or month when month is not 0. Two-sided transactions are included as
+// two-line entries, so the result is the complete set of bookings. Like
+// GetTransactions, it returns only the entries in status, or those in every
+// status when status is "".
+func GetJournalEntries(database *sql.DB, scope Scope, account int, year int, month int, status string) ([]JournalEntry, error) {
+	if year == 0 {
+		return nil, errors.New("year is required")
+	}
+
+	start, end := dateRange(year, month)
+	rows, err := database.Query("SELECT "+journalEntryColumns+" FROM journal_entries e JOIN journal_lines l ON l.entry_id = e.id WHERE e.ledger_id = $1 AND e.id IN (SELECT entry_id FROM journal_lines WHERE ledger_id = $1 AND account = $2) AND e.date >= $3 AND e.date < $4 AND ($5 = '' OR e.status = $5) ORDER BY e.date, e.id, l.id", scope.Ledger, account, start, end, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries, err := scanJournalEntries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := GetTransactions(database, scope, account, year, month, status)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transaction := range transactions {
+		entries = append(entries, TransactionEntry(transaction))
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+
+	return entries, nil
+}
+
+// GetPostedJournalEntries returns the posted standard journal entries from
+// from to to, ordered by date, for exports. Closing entries are left out.
+func GetPostedJournalEntries(database *sql.DB, scope Scope, from time.Time, to time.Time) ([]JournalEntry, error) {
+	rows, err := database.Query("SELECT "+journalEntryColumns+" FROM journal_entries e JOIN journal_lines l ON l.entry_id = e.id WHERE e.ledger_id = $1 AND e.status = $2 AND e.type = $3 AND e.date >= $4 AND e.date < $5 ORDER BY e.date, e.id, l.id", scope.Ledger, StatusPosted, EntryStandard, from, endOfDay(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanJournalEntries(rows)
+}
+
+// JournalRow is one line of a booking as a flat listing shows it: a line of a
+// journal entry, or one side of a two-sided transaction.
+type JournalRow struct {
+	Source      string
+	EntryID     uint
+	Date        time.Time
+	Description string
+	Account     uint
+	Debit       Money
+	Credit      Money
+}
+
+// StreamJournal hands every line of the posted bookings GetJournalEntries
+// returns to fn one at a time, straight from the cursor, ordered by date and
+// entry. It stops at the first error fn returns.
+func StreamJournal(database *sql.DB, scope Scope, account int, year int, month int, fn func(JournalRow) error) error {
+	if year == 0 {
+		return errors.New("year is required")
+	}
+
+	query := "SELECT p.source, p.entry_id, p.date, p.description, p.account, p.debit_amount, p.credit_amount FROM (" + postings + ") p WHERE p.ledger_id = $1 AND EXISTS (SELECT 1 FROM (" + postings + ") q WHERE q.ledger_id = p.ledger_id AND q.source = p.source AND q.entry_id = p.entry_id AND q.account = $2) AND p.date >= $3 AND p.date < $4 ORDER BY p.date, p.entry_id, p.source, p.line_id"
+
+	start, end := dateRange(year, month)
+	rows, err := database.Query(query, scope.Ledger, account, start, end)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row JournalRow
+		err := rows.Scan(&row.Source, &row.EntryID, &row.Date, &row.Description, &row.Account, &row.Debit, &row.Credit)
+		if err != nil {
+			return err
+		}
+
+		err = fn(row)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// TransactionEntry presents a two-sided transaction as a journal entry. Debit
+// means Account is debited and OffsetAccount is credited.
+func TransactionEntry(transaction Transaction) JournalEntry {
+	return JournalEntry{
+		ID:          transaction.ID,
+		Source:      SourceTransaction,
+		Type:        EntryStandard,
+		Date:        transaction.Date,
+		Description: transaction.Description,
+		Status:      transaction.Status,
+		PreparedBy:  transaction.PreparedBy,
+		Lines: []JournalLine{
+			{Account: transaction.Account, Amount: transaction.Amount, Debit: transaction.Debit},
+			{Account: transaction.OffsetAccount, Amount: transaction.Amount, Debit: !transaction.Debit},
+		},
+	}
+}
+
+// journalEntryColumns are the columns of a journal entry e joined with its
+// lines l.
+const journalEntryColumns = "e.id, e.type, e.date, COALESCE(e.description, ''), e.status, e.prepared_by, l.id, l.account, l.amount, l.debit"
+
+// scanJournalEntries folds rows of (entry, line) pairs selected with
+// journalEntryColumns, ordered by entry, into entries.
+func scanJournalEntries(rows *sql.Rows) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var line JournalLine
+		var preparedBy sql.NullString
+		err := rows.Scan(&entry.ID, &entry.Type, &entry.Date, &entry.Description, &entry.Status, &preparedBy, &line.ID, &line.Account, &line.Amount, &line.Debit)
+		if err != nil {
+			return nil, err
+		}
+		entry.PreparedBy = preparedBy.String
+
+		if len(entries) == 0 || entries[len(entries)-1].ID != entry.ID {
+			entry.Source = SourceJournal
+			entries = append(entries, entry)
+		}
+		last := &entries[len(entries)-1]
+		last.Lines = append(last.Lines, line)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
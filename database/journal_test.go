@@ -0,0 +1,273 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJournalEntry(t *testing.T) {
+	cleanTables()
+	for _, account := range []Account{{ID: 1200, Name: "Bank", Kind: "asset"}, {ID: 1576, Name: "Input VAT", Kind: "asset"}, {ID: 3400, Name: "Goods", Kind: "expense"}} {
+		_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	entry := JournalEntry{Date: time.Now(), Description: "Purchase with input VAT", Lines: []JournalLine{
+		{Account: 3400, Amount: 10000, Debit: true},
+		{Account: 1576, Amount: 1900, Debit: true},
+		{Account: 1200, Amount: 11900, Debit: false},
+	}}
+	id, err := NewJournalEntry(db, testScope, entry)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM journal_lines WHERE entry_id = $1", id).Scan(&count)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, 3, count)
+}
+
+func TestNewJournalEntryUnbalanced(t *testing.T) {
+	entry := JournalEntry{Date: time.Now(), Lines: []JournalLine{
+		{Account: 3400, Amount: 10000, Debit: true},
+		{Account: 1200, Amount: 11900, Debit: false},
+	}}
+	_, err := NewJournalEntry(db, testScope, entry)
+
+	assert.ErrorIs(t, err, ErrJournalEntryUnbalanced)
+}
+
+func TestNewJournalEntrySingleLine(t *testing.T) {
+	entry := JournalEntry{Date: time.Now(), Lines: []JournalLine{
+		{Account: 3400, Amount: 10000, Debit: true},
+	}}
+	_, err := NewJournalEntry(db, testScope, entry)
+
+	assert.Error(t, err)
+}
+
+func TestNewJournalEntryAccountNotExists(t *testing.T) {
+	entry := JournalEntry{Date: time.Now(), Lines: []JournalLine{
+		{Account: 9998, Amount: 10000, Debit: true},
+		{Account: 9999, Amount: 10000, Debit: false},
+	}}
+	_, err := NewJournalEntry(db, testScope, entry)
+
+	assert.Error(t, err)
+}
+
+func TestGetJournalEntry(t *testing.T) {
+	cleanTables()
+	for _, account := range []Account{{ID: 40, Name: "Test Account 40", Kind: "asset"}, {ID: 41, Name: "Test Account 41", Kind: "asset"}} {
+		_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	entry := JournalEntry{Date: time.Now(), Description: "Test Entry", Lines: []JournalLine{
+		{Account: 40, Amount: 5000, Debit: true},
+		{Account: 41, Amount: 5000, Debit: false},
+	}}
+	id, err := NewJournalEntry(db, testScope, entry)
+	if err != nil {
+		t.Error(err)
+	}
+
+	result, err := GetJournalEntry(db, testScope, int(id))
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, SourceJournal, result.Source)
+	assert.Equal(t, entry.Description, result.Description)
+	assert.Len(t, result.Lines, 2)
+}
+
+func TestGetJournalEntryNotExists(t *testing.T) {
+	_, err := GetJournalEntry(db, testScope, 99999)
+
+	assert.ErrorIs(t, err, ErrJournalEntryNotExists)
+}
+
+func TestGetJournalEntriesIncludesTransactions(t *testing.T) {
+	cleanTables()
+	for _, account := range []Account{{ID: 42, Name: "Test Account 42", Kind: "asset"}, {ID: 43, Name: "Test Account 43", Kind: "asset"}} {
+		_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, account.ID, account.Name, account.Kind)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	date, _ := time.Parse("2006-01-02", "2024-03-01")
+
+	_, err := NewJournalEntry(db, testScope, JournalEntry{Date: date, Lines: []JournalLine{
+		{Account: 42, Amount: 1000, Debit: true},
+		{Account: 43, Amount: 1000, Debit: false},
+	}, Status: StatusPosted})
+	if err != nil {
+		t.Error(err)
+	}
+
+	transaction := Transaction{Amount: 2000, Debit: false, OffsetAccount: 43, Account: 42, Date: date, Description: "Test Transaction", Status: StatusPosted}
+	_, err = NewTransaction(db, testScope, transaction)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// a draft of each kind
+	_, err = NewJournalEntry(db, testScope, JournalEntry{Date: date, Lines: []JournalLine{
+		{Account: 42, Amount: 300, Debit: true},
+		{Account: 43, Amount: 300, Debit: false},
+	}})
+	if err != nil {
+		t.Error(err)
+	}
+	transaction.Status = StatusDraft
+	_, err = NewTransaction(db, testScope, transaction)
+	if err != nil {
+		t.Error(err)
+	}
+
+	entries, err := GetJournalEntries(db, testScope, 42, 2024, 3, "")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, entries, 4)
+
+	// the entries and the transactions are filtered alike
+	entries, err = GetJournalEntries(db, testScope, 42, 2024, 3, StatusPosted)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, entries, 2)
+
+	entries, err = GetJournalEntries(db, testScope, 42, 2024, 3, StatusDraft)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, entries, 2)
+
+	transactions, err := GetTransactions(db, testScope, 42, 2024, 3, StatusDraft)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, transactions, 1)
+}
+
+func TestGetJournalEntryWithoutDescription(t *testing.T) {
+	insertBooks(t, balanceAccounts, nil, nil)
+
+	// entries of old databases and of restores may have none
+	var id int
+	err := db.QueryRow("INSERT INTO journal_entries (ledger_id, date, status) VALUES ($1, '2024-03-01 00:00:00', 'posted') RETURNING id", testScope.Ledger).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("INSERT INTO journal_lines (entry_id, ledger_id, account, amount, debit) VALUES ($1, $2, 1200, 500, true), ($1, $2, 3400, 500, false)", id, testScope.Ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := GetJournalEntry(db, testScope, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, entry.Description)
+	assert.Len(t, entry.Lines, 2)
+}
+
+func TestGetPostedJournalEntries(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	april, _ := time.Parse("2006-01-02", "2024-04-02")
+	_, err := NewJournalEntry(db, testScope, JournalEntry{Date: april, Description: "Draft", Lines: []JournalLine{
+		{Account: 3400, Amount: 500, Debit: true},
+		{Account: 1200, Amount: 500, Debit: false},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from, _ := time.Parse("2006-01-02", "2024-04-01")
+	to, _ := time.Parse("2006-01-02", "2024-04-30")
+	entries, err := GetPostedJournalEntries(db, testScope, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the purchase, not the draft
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "Purchase", entries[0].Description)
+		assert.Len(t, entries[0].Lines, 3)
+	}
+
+	entries, err = GetPostedJournalEntries(db, testScope, from, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, entries)
+}
+
+func TestTransactionEntry(t *testing.T) {
+	transaction := Transaction{ID: 7, Amount: 2000, Debit: true, OffsetAccount: 2, Account: 1, Date: time.Now()}
+	entry := TransactionEntry(transaction)
+
+	assert.Equal(t, SourceTransaction, entry.Source)
+	assert.Equal(t, []JournalLine{
+		{Account: 1, Amount: 2000, Debit: true},
+		{Account: 2, Amount: 2000, Debit: false},
+	}, entry.Lines)
+}
+
+func TestStreamJournal(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	var rows []JournalRow
+	err := StreamJournal(db, testScope, 3400, 2024, 0, func(row JournalRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every line of the purchase, none of the sale
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, SourceJournal, rows[0].Source)
+		assert.Equal(t, uint(3400), rows[0].Account)
+		assert.Equal(t, Money(10000), rows[0].Debit)
+		assert.Equal(t, Money(11900), rows[2].Credit)
+	}
+
+	rows = nil
+	err = StreamJournal(db, testScope, 1200, 2024, 3, func(row JournalRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, rows, 2) {
+		assert.Equal(t, SourceTransaction, rows[0].Source)
+		assert.Equal(t, uint(8400), rows[1].Account)
+	}
+}
+
+func TestStreamTransactionsStops(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	stop := errors.New("stop")
+	err := StreamTransactions(db, testScope, 1200, 2024, 0, "", func(Transaction) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+}
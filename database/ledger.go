@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrLedgerNotExists = errors.New("ledger does not exist")
+
+// Ledger is a set of books, for example one company. Accounts, transactions
+// and journal entries always belong to exactly one ledger. Owner is the user
+// who created it; Role is the role of the user the ledger was loaded for.
+type Ledger struct {
+	ID    uint
+	Name  string
+	Owner string
+	Role  string
+}
+
+// Scope is the ledger a request works on, the user making it and the role
+// of that user in the ledger. Every account and booking function only sees
+// the rows of Scope.Ledger, so records of other ledgers behave as if they did
+// not exist.
+type Scope struct {
+	Ledger uint
+	User   string
+	Role   string
+}
+
+func NewLedger(database *sql.DB, ledger Ledger) (uint, error) {
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertLedger(tx, ledger)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertLedger creates a ledger with its owner as the first member, as part
+// of the database transaction of the caller.
+func insertLedger(database querier, ledger Ledger) (uint, error) {
+	if ledger.Name == "" {
+		return 0, errors.New("name is required")
+	}
+
+	if ledger.Owner == "" {
+		return 0, errors.New("owner is required")
+	}
+
+	var id uint
+	err := database.QueryRow("INSERT INTO ledgers (name, owner) VALUES ($1, $2) RETURNING id", ledger.Name, ledger.Owner).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = database.Exec("INSERT INTO ledger_members (ledger_id, user_id, role) VALUES ($1, $2, $3)", id, ledger.Owner, RoleOwner)
+	if err != nil {
+		return 0, err
+	}
+
+	ledger.ID = id
+	ledger.Role = ""
+	err = recordAudit(database, id, ledger.Owner, EntityLedger, id, ActionCreate, nil, ledger)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const memberLedgers = "SELECT l.id, l.name, l.owner, m.role FROM ledgers l JOIN ledger_members m ON m.ledger_id = l.id WHERE m.user_id = $1"
+
+// GetLedger returns a ledger user is a member of. Ledgers the user has no
+// access to are reported as not existing.
+func GetLedger(database *sql.DB, id uint, user string) (Ledger, error) {
+	var ledger Ledger
+	err := database.QueryRow(memberLedgers+" AND l.id = $2", user, id).Scan(&ledger.ID, &ledger.Name, &ledger.Owner, &ledger.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ledger, ErrLedgerNotExists
+		}
+		return ledger, err
+	}
+	return ledger, nil
+}
+
+// GetLedgers returns every ledger user is a member of, oldest first.
+func GetLedgers(database *sql.DB, user string) ([]Ledger, error) {
+	rows, err := database.Query(memberLedgers+" ORDER BY l.id", user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ledgers []Ledger
+	for rows.Next() {
+		var ledger Ledger
+		err := rows.Scan(&ledger.ID, &ledger.Name, &ledger.Owner, &ledger.Role)
+		if err != nil {
+			return nil, err
+		}
+		ledgers = append(ledgers, ledger)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return ledgers, nil
+}
+
+// DefaultLedger returns the oldest ledger user is a member of, which is used
+// when a request does not name a ledger.
+func DefaultLedger(database *sql.DB, user string) (Ledger, error) {
+	var ledger Ledger
+	err := database.QueryRow(memberLedgers+" ORDER BY l.id LIMIT 1", user).Scan(&ledger.ID, &ledger.Name, &ledger.Owner, &ledger.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ledger, ErrLedgerNotExists
+		}
+		return ledger, err
+	}
+	return ledger, nil
+}
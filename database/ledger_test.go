@@ -0,0 +1,141 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// insertOtherLedger creates a second user with a ledger of their own.
+func insertOtherLedger(t *testing.T) Scope {
+	var other Scope
+	err := db.QueryRow("INSERT INTO users (name, password) VALUES ('Other Owner', 'secret') RETURNING id").Scan(&other.User)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other.Ledger, err = NewLedger(db, Ledger{Name: "Other Ledger", Owner: other.User})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return other
+}
+
+func TestNewLedger(t *testing.T) {
+	cleanTables()
+	id, err := NewLedger(db, Ledger{Name: "Second Ledger", Owner: testScope.User})
+	if err != nil {
+		t.Error(err)
+	}
+
+	ledger, err := GetLedger(db, id, testScope.User)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "Second Ledger", ledger.Name)
+
+	ledgers, err := GetLedgers(db, testScope.User)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.GreaterOrEqual(t, len(ledgers), 2)
+
+	// the fixture ledger is older, so it stays the default
+	defaultLedger, err := DefaultLedger(db, testScope.User)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, testScope.Ledger, defaultLedger.ID)
+}
+
+func TestNewLedgerNameRequired(t *testing.T) {
+	_, err := NewLedger(db, Ledger{Owner: testScope.User})
+
+	assert.Error(t, err)
+}
+
+func TestGetLedgerOtherOwner(t *testing.T) {
+	cleanTables()
+	other := insertOtherLedger(t)
+
+	_, err := GetLedger(db, other.Ledger, testScope.User)
+
+	assert.ErrorIs(t, err, ErrLedgerNotExists)
+}
+
+func TestLedgerIsolation(t *testing.T) {
+	cleanTables()
+	other := insertOtherLedger(t)
+
+	// account numbers only have to be unique within a ledger
+	for _, scope := range []Scope{testScope, other} {
+		for _, account := range []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}, {ID: 8400, Name: "Revenue", Kind: KindRevenue}} {
+			err := NewAccount(db, scope, account)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	_, err := NewTransaction(db, other, Transaction{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Status: StatusPosted})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id int
+	err = db.QueryRow("SELECT id FROM transactions WHERE ledger_id = $1", other.Ledger).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = GetTransaction(db, testScope, id)
+	assert.ErrorIs(t, err, ErrTransactionNotExists)
+
+	_, err = DeleteTransaction(db, testScope, id)
+	assert.ErrorIs(t, err, ErrTransactionNotExists)
+
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(0), balance.Balance)
+
+	balance, err = GetBalance(db, other, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(10000), balance.Balance)
+
+	trialBalance, err := GetTrialBalance(db, testScope, time.Time{}, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, trialBalance.Lines, 2)
+	assert.Equal(t, Money(0), trialBalance.TotalDebit)
+}
+
+func TestLedgerIsolationAccounts(t *testing.T) {
+	cleanTables()
+	other := insertOtherLedger(t)
+
+	err := NewAccount(db, other, Account{ID: 1576, Name: "Input VAT", Kind: KindAsset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = GetAccount(db, testScope, 1576)
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+
+	err = DeleteAccount(db, testScope, 1576)
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+
+	// bookings can only use accounts of their own ledger
+	err = NewAccount(db, testScope, Account{ID: 1200, Name: "Bank", Kind: KindAsset})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewTransaction(db, testScope, Transaction{Amount: 100, Debit: true, Account: 1576, OffsetAccount: 1200, Date: time.Now()})
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+}
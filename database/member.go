@@ -0,0 +1,216 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Roles a user can hold in a ledger. Owners manage members, accountants
+// book, approvers approve what others booked, viewers read. Auditors read as
+// well, including the audit trail.
+const (
+	RoleOwner      = "owner"
+	RoleAccountant = "accountant"
+	RoleApprover   = "approver"
+	RoleViewer     = "viewer"
+	RoleAuditor    = "auditor"
+)
+
+var Roles = []string{RoleOwner, RoleAccountant, RoleApprover, RoleViewer, RoleAuditor}
+
+var (
+	ErrInvalidRole     = errors.New("invalid role; must be one of owner, accountant, approver, viewer or auditor")
+	ErrUserNotExists   = errors.New("user does not exist")
+	ErrMemberExists    = errors.New("user is already a member of the ledger")
+	ErrMemberNotExists = errors.New("user is not a member of the ledger")
+	ErrLastOwner       = errors.New("a ledger needs at least one owner")
+)
+
+func ValidRole(role string) bool {
+	for _, valid := range Roles {
+		if role == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Member is a user with access to a ledger.
+type Member struct {
+	User string
+	Name string
+	Role string
+}
+
+// GetMembers lists the members of the ledger ordered by user name.
+func GetMembers(database *sql.DB, scope Scope) ([]Member, error) {
+	rows, err := database.Query("SELECT u.id, u.name, m.role FROM ledger_members m JOIN users u ON u.id = m.user_id WHERE m.ledger_id = $1 ORDER BY u.name", scope.Ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []Member
+	for rows.Next() {
+		var member Member
+		err := rows.Scan(&member.User, &member.Name, &member.Role)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddMember grants the user with the given name a role in the ledger.
+func AddMember(database *sql.DB, scope Scope, name string, role string) (Member, error) {
+	member := Member{Name: name, Role: role}
+	if !ValidRole(role) {
+		return member, ErrInvalidRole
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return member, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("SELECT id FROM users WHERE name = $1", name).Scan(&member.User)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return member, ErrUserNotExists
+		}
+		return member, err
+	}
+
+	result, err := tx.Exec("INSERT INTO ledger_members (ledger_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", scope.Ledger, member.User, role)
+	if err != nil {
+		return member, err
+	}
+
+	added, err := result.RowsAffected()
+	if err != nil {
+		return member, err
+	}
+	if added == 0 {
+		return member, ErrMemberExists
+	}
+
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityMember, member.User, ActionCreate, nil, member)
+	if err != nil {
+		return member, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return member, err
+	}
+	return member, nil
+}
+
+// getMember loads a member of the ledger for a change.
+func getMember(database querier, ledger uint, user string) (Member, error) {
+	var member Member
+	err := database.QueryRow("SELECT u.id, u.name, m.role FROM ledger_members m JOIN users u ON u.id = m.user_id WHERE m.ledger_id = $1 AND m.user_id = $2", ledger, user).Scan(&member.User, &member.Name, &member.Role)
+	if err == sql.ErrNoRows {
+		return member, ErrMemberNotExists
+	}
+	return member, err
+}
+
+// UpdateMember changes the role of a member. The last owner of a ledger
+// cannot be demoted.
+func UpdateMember(database *sql.DB, scope Scope, user string, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if role != RoleOwner {
+		err = checkLastOwner(tx, scope.Ledger, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	current, err := getMember(tx, scope.Ledger, user)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE ledger_members SET role = $1 WHERE ledger_id = $2 AND user_id = $3", role, scope.Ledger, user)
+	if err != nil {
+		return err
+	}
+
+	changed := current
+	changed.Role = role
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityMember, user, ActionUpdate, current, changed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveMember revokes the access of a user to the ledger. The last owner of
+// a ledger cannot be removed.
+func RemoveMember(database *sql.DB, scope Scope, user string) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkLastOwner(tx, scope.Ledger, user)
+	if err != nil {
+		return err
+	}
+
+	current, err := getMember(tx, scope.Ledger, user)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM ledger_members WHERE ledger_id = $1 AND user_id = $2", scope.Ledger, user)
+	if err != nil {
+		return err
+	}
+
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityMember, user, ActionDelete, current, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkLastOwner fails when user is the only owner of the ledger. It locks
+// the ledger row by updating it, so two owners cannot demote each other at
+// the same time.
+func checkLastOwner(database querier, ledger uint, user string) error {
+	_, err := database.Exec("UPDATE ledgers SET id = id WHERE id = $1", ledger)
+	if err != nil {
+		return err
+	}
+
+	var allowed bool
+	err = database.QueryRow("SELECT NOT EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = $1 AND user_id = $2 AND role = $3) OR EXISTS (SELECT 1 FROM ledger_members WHERE ledger_id = $1 AND user_id <> $2 AND role = $3)", ledger, user, RoleOwner).Scan(&allowed)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrLastOwner
+	}
+	return nil
+}
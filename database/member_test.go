@@ -0,0 +1,131 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func insertMemberUser(t *testing.T, name string) string {
+	var id string
+	err := db.QueryRow("INSERT INTO users (name, password) VALUES ($1, 'secret') RETURNING id", name).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestAddMember(t *testing.T) {
+	cleanTables()
+	user := insertMemberUser(t, "Tax Advisor")
+
+	member, err := AddMember(db, testScope, "Tax Advisor", RoleAuditor)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, user, member.User)
+
+	// the ledger is now visible to the new member with their role
+	ledger, err := GetLedger(db, testScope.Ledger, user)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, RoleAuditor, ledger.Role)
+
+	members, err := GetMembers(db, testScope)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, members, 2)
+}
+
+func TestAddMemberTwice(t *testing.T) {
+	cleanTables()
+	insertMemberUser(t, "Bookkeeper")
+
+	_, err := AddMember(db, testScope, "Bookkeeper", RoleAccountant)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = AddMember(db, testScope, "Bookkeeper", RoleViewer)
+	assert.ErrorIs(t, err, ErrMemberExists)
+}
+
+func TestAddMemberUserNotExists(t *testing.T) {
+	cleanTables()
+	_, err := AddMember(db, testScope, "Nobody", RoleViewer)
+
+	assert.ErrorIs(t, err, ErrUserNotExists)
+}
+
+func TestAddMemberInvalidRole(t *testing.T) {
+	_, err := AddMember(db, testScope, "Bookkeeper", "admin")
+
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestUpdateMember(t *testing.T) {
+	cleanTables()
+	user := insertMemberUser(t, "Bookkeeper")
+
+	_, err := AddMember(db, testScope, "Bookkeeper", RoleViewer)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = UpdateMember(db, testScope, user, RoleAccountant)
+	if err != nil {
+		t.Error(err)
+	}
+
+	ledger, err := GetLedger(db, testScope.Ledger, user)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, RoleAccountant, ledger.Role)
+}
+
+func TestUpdateMemberLastOwner(t *testing.T) {
+	cleanTables()
+	err := UpdateMember(db, testScope, testScope.User, RoleViewer)
+
+	assert.ErrorIs(t, err, ErrLastOwner)
+}
+
+func TestRemoveMember(t *testing.T) {
+	cleanTables()
+	user := insertMemberUser(t, "Bookkeeper")
+
+	_, err := AddMember(db, testScope, "Bookkeeper", RoleOwner)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// with a second owner the first one may leave, the last one may not
+	err = RemoveMember(db, testScope, testScope.User)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = RemoveMember(db, testScope, user)
+	assert.ErrorIs(t, err, ErrLastOwner)
+
+	_, err = GetLedger(db, testScope.Ledger, testScope.User)
+	assert.ErrorIs(t, err, ErrLedgerNotExists)
+
+	// restore the fixture owner for the following tests
+	_, err = db.Exec("INSERT INTO ledger_members (ledger_id, user_id, role) VALUES ($1, $2, $3)", testScope.Ledger, testScope.User, RoleOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoveMemberNotExists(t *testing.T) {
+	cleanTables()
+	user := insertMemberUser(t, "Bookkeeper")
+
+	err := RemoveMember(db, testScope, user)
+
+	assert.ErrorIs(t, err, ErrMemberNotExists)
+}
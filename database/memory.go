@@ -0,0 +1,408 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a Store that keeps everything in memory. It checks accounts
+// and transactions like SQLStore does, but it knows no fiscal years, so no
+// period is ever closed and reversals are dated like the original, it knows
+// no members besides the owner of a ledger, and it keeps no audit log.
+type MemoryStore struct {
+	mu              sync.Mutex
+	ledgers         map[uint]Ledger
+	accounts        map[uint]map[uint]Account
+	transactions    map[uint]map[uint]Transaction
+	users           map[string]User
+	lastTransaction uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ledgers:      make(map[uint]Ledger),
+		accounts:     make(map[uint]map[uint]Account),
+		transactions: make(map[uint]map[uint]Transaction),
+		users:        make(map[string]User),
+	}
+}
+
+func (s *MemoryStore) GetLedger(id uint, user string) (Ledger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ledger, ok := s.ledgers[id]
+	if !ok || ledger.Owner != user {
+		return Ledger{}, ErrLedgerNotExists
+	}
+	return ledger, nil
+}
+
+func (s *MemoryStore) GetLedgers(user string) ([]Ledger, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ledgers []Ledger
+	for _, ledger := range s.ledgers {
+		if ledger.Owner == user {
+			ledgers = append(ledgers, ledger)
+		}
+	}
+
+	sort.Slice(ledgers, func(i, j int) bool {
+		return ledgers[i].ID < ledgers[j].ID
+	})
+	return ledgers, nil
+}
+
+func (s *MemoryStore) DefaultLedger(user string) (Ledger, error) {
+	ledgers, err := s.GetLedgers(user)
+	if err != nil {
+		return Ledger{}, err
+	}
+	if len(ledgers) == 0 {
+		return Ledger{}, ErrLedgerNotExists
+	}
+	return ledgers[0], nil
+}
+
+func (s *MemoryStore) NewLedger(ledger Ledger) (uint, error) {
+	if ledger.Name == "" {
+		return 0, errors.New("name is required")
+	}
+
+	if ledger.Owner == "" {
+		return 0, errors.New("owner is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ledger.ID = uint(len(s.ledgers) + 1)
+	ledger.Role = RoleOwner
+	s.ledgers[ledger.ID] = ledger
+	return ledger.ID, nil
+}
+
+func (s *MemoryStore) GetAccount(scope Scope, id int) (Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[scope.Ledger][uint(id)]
+	if !ok {
+		return Account{}, ErrAccountNotExists
+	}
+	return account, nil
+}
+
+func (s *MemoryStore) NewAccount(scope Scope, account Account) error {
+	if !ValidKind(account.Kind) {
+		return ErrInvalidKind
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[scope.Ledger][account.ID]; ok {
+		return errors.New("account already exists")
+	}
+
+	err := validateParent(s, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+
+	if s.accounts[scope.Ledger] == nil {
+		s.accounts[scope.Ledger] = make(map[uint]Account)
+	}
+	s.accounts[scope.Ledger][account.ID] = account
+	return nil
+}
+
+func (s *MemoryStore) UpdateAccount(scope Scope, account Account) error {
+	if !ValidKind(account.Kind) {
+		return ErrInvalidKind
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[scope.Ledger][account.ID]; !ok {
+		return ErrAccountNotExists
+	}
+
+	err := validateParent(s, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+
+	err = validateGroupChange(s, scope.Ledger, account)
+	if err != nil {
+		return err
+	}
+
+	s.accounts[scope.Ledger][account.ID] = account
+	return nil
+}
+
+func (s *MemoryStore) DeleteAccount(scope Scope, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.accounts[scope.Ledger][uint(id)]; !ok {
+		return ErrAccountNotExists
+	}
+
+	err := validateGroupChange(s, scope.Ledger, Account{ID: uint(id)})
+	if err != nil {
+		return err
+	}
+
+	delete(s.accounts[scope.Ledger], uint(id))
+	return nil
+}
+
+// lookupAccount, hasBookings and hasChildren make MemoryStore the
+// accountSource of its own account checks. The caller holds the mutex.
+func (s *MemoryStore) lookupAccount(ledger uint, id uint) (Account, bool, error) {
+	account, ok := s.accounts[ledger][id]
+	return account, ok, nil
+}
+
+func (s *MemoryStore) hasBookings(ledger uint, id uint) (bool, error) {
+	for _, transaction := range s.transactions[ledger] {
+		if transaction.Account == id || transaction.OffsetAccount == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) hasChildren(ledger uint, id uint) (bool, error) {
+	for _, child := range s.accounts[ledger] {
+		if child.Parent == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryStore) GetTransaction(scope Scope, id int) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	transaction, ok := s.transactions[scope.Ledger][uint(id)]
+	if !ok {
+		return Transaction{}, ErrTransactionNotExists
+	}
+	return transaction, nil
+}
+
+func (s *MemoryStore) GetTransactions(scope Scope, account int, year int, month int, status string) ([]Transaction, error) {
+	if year == 0 {
+		return nil, errors.New("year is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var transactions []Transaction
+	for _, transaction := range s.transactions[scope.Ledger] {
+		if transaction.Account != uint(account) && transaction.OffsetAccount != uint(account) {
+			continue
+		}
+		if transaction.Date.Year() != year || (month != 0 && int(transaction.Date.Month()) != month) {
+			continue
+		}
+		if status != "" && transaction.Status != status {
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	sort.Slice(transactions, func(i, j int) bool {
+		if !transactions[i].Date.Equal(transactions[j].Date) {
+			return transactions[i].Date.Before(transactions[j].Date)
+		}
+		return transactions[i].ID < transactions[j].ID
+	})
+	return transactions, nil
+}
+
+func (s *MemoryStore) NewTransaction(scope Scope, transaction Transaction) (uint, error) {
+	if transaction.Status == "" {
+		transaction.Status = StatusDraft
+	}
+
+	if transaction.Status != StatusDraft && transaction.Status != StatusPosted {
+		return 0, ErrInvalidTransactionStatus
+	}
+
+	err := validateTransaction(transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = checkPostable(s, scope.Ledger, transaction.Account, transaction.OffsetAccount)
+	if err != nil {
+		return 0, err
+	}
+
+	transaction.Reverses = 0
+	transaction.Replaces = 0
+	transaction.PreparedBy = scope.User
+	return s.insertTransaction(scope.Ledger, transaction), nil
+}
+
+func (s *MemoryStore) UpdateTransaction(scope Scope, transaction Transaction) (uint, error) {
+	err := validateTransaction(transaction)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.correctable(scope.Ledger, int(transaction.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	err = checkPostable(s, scope.Ledger, transaction.Account, transaction.OffsetAccount)
+	if err != nil {
+		return 0, err
+	}
+
+	transaction.Status = StatusDraft
+	transaction.Reverses = 0
+	transaction.PreparedBy = scope.User
+	if current.Status != StatusPosted {
+		transaction.Replaces = current.Replaces
+		s.transactions[scope.Ledger][current.ID] = transaction
+		return current.ID, nil
+	}
+
+	s.insertTransaction(scope.Ledger, reversalOf(scope, current, current.Date))
+	transaction.Replaces = current.ID
+	return s.insertTransaction(scope.Ledger, transaction), nil
+}
+
+func (s *MemoryStore) DeleteTransaction(scope Scope, id int) (uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.correctable(scope.Ledger, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if current.Status != StatusPosted {
+		delete(s.transactions[scope.Ledger], current.ID)
+		return 0, nil
+	}
+	return s.insertTransaction(scope.Ledger, reversalOf(scope, current, current.Date)), nil
+}
+
+// correctable is lockTransaction of SQLStore; the mutex does the locking.
+func (s *MemoryStore) correctable(ledger uint, id int) (Transaction, error) {
+	transaction, ok := s.transactions[ledger][uint(id)]
+	if !ok {
+		return transaction, ErrTransactionNotExists
+	}
+
+	if transaction.Reverses != 0 {
+		return transaction, ErrTransactionReversal
+	}
+
+	for _, other := range s.transactions[ledger] {
+		if other.Reverses == transaction.ID {
+			return transaction, ErrTransactionReversed
+		}
+	}
+	return transaction, nil
+}
+
+// insertTransaction stores a transaction under the next id, which like a
+// serial column counts across all ledgers.
+func (s *MemoryStore) insertTransaction(ledger uint, transaction Transaction) uint {
+	s.lastTransaction++
+	transaction.ID = s.lastTransaction
+
+	if s.transactions[ledger] == nil {
+		s.transactions[ledger] = make(map[uint]Transaction)
+	}
+	s.transactions[ledger][transaction.ID] = transaction
+	return transaction.ID
+}
+
+// GetUser and GetUserByName answer unknown users with sql.ErrNoRows, like
+// SQLStore does.
+func (s *MemoryStore) GetUser(id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) GetUserByName(name string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+func (s *MemoryStore) NewUser(user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.insertUser(user)
+	return err
+}
+
+// NewUserWithLedger stores the user and their ledger under one lock, so
+// neither is seen without the other.
+func (s *MemoryStore) NewUserWithLedger(user User, ledger string) error {
+	if ledger == "" {
+		return errors.New("name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := s.insertUser(user)
+	if err != nil {
+		return err
+	}
+
+	created := Ledger{ID: uint(len(s.ledgers) + 1), Name: ledger, Owner: id, Role: RoleOwner}
+	s.ledgers[created.ID] = created
+	return nil
+}
+
+func (s *MemoryStore) insertUser(user User) (string, error) {
+	for _, other := range s.users {
+		if other.Name == user.Name {
+			return "", errors.New("user name already exists")
+		}
+	}
+
+	// ids look like the UUIDs Postgres hands out
+	user.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(s.users)+1)
+	s.users[user.ID] = user
+	return user.ID, nil
+}
@@ -0,0 +1,520 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationLock is the key of the advisory lock held while the schema is
+// migrated, so replicas that start together migrate one after the other.
+const migrationLock = 7301995028441162
+
+// baselineSchema is the scratch schema the baseline is created in to compare
+// a database from before there were migrations with it.
+const baselineSchema = "bookholder_baseline"
+
+var (
+	ErrSchemaNewer     = errors.New("database schema is newer than this build; update the application")
+	ErrSchemaDowngrade = errors.New("database schema is newer than the requested version; downgrades have to be run with MigrateDown")
+	ErrLegacySchema    = errors.New("database from before schema migrations differs from the baseline")
+)
+
+// Migration is one step of the schema. Up brings the schema from the version
+// before to Version, Down takes it back.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migrations returns the Postgres migrations shipped with the binary in
+// order. Their versions count up from 1 without gaps, and every one has an up
+// and a down script.
+func Migrations() ([]Migration, error) {
+	return readMigrations("migrations")
+}
+
+// migrationsFor returns the migrations for the kind of database. Those for
+// SQLite, in migrations/sqlite, have the versions and names of the Postgres
+// ones and make the same changes.
+func migrationsFor(database *sql.DB) ([]Migration, error) {
+	if isSQLite(database) {
+		return readMigrations("migrations/sqlite")
+	}
+	return Migrations()
+}
+
+func readMigrations(dir string) ([]Migration, error) {
+	files, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		match := migrationName.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.up.sql or .down.sql", file.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		script, err := migrationFiles.ReadFile(dir + "/" + file.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: up and down scripts have different names", version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(script)
+		} else {
+			migration.Down = string(script)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d: needs an up and a down script", migration.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// SchemaVersion returns the version of the migration last applied, or 0 for
+// a database without any.
+func SchemaVersion(database *sql.DB) (int, error) {
+	var version int
+	err := database.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Migrate brings the schema up to the latest migration.
+func Migrate(database *sql.DB) error {
+	migrations, err := migrationsFor(database)
+	if err != nil {
+		return err
+	}
+	return MigrateTo(database, len(migrations))
+}
+
+// MigrateTo brings the schema up to version. Every migration runs in a
+// database transaction of its own, together with its entry in
+// schema_migrations, so a failing one leaves the schema at the version
+// before it. A schema already past version is left alone and
+// ErrSchemaDowngrade returned, so that starting an older configuration never
+// drops anything.
+//
+// A database made from bookholder.sql before there were migrations has no
+// schema_migrations yet. It is adopted as version 1, the baseline, if its
+// schema is that of the baseline; see adoptBaseline.
+func MigrateTo(database *sql.DB, version int) error {
+	return migrate(database, version, false)
+}
+
+// MigrateDown takes the schema back to version by running the down scripts
+// of the migrations after it.
+func MigrateDown(database *sql.DB, version int) error {
+	return migrate(database, version, true)
+}
+
+func migrate(database *sql.DB, version int, down bool) error {
+	migrations, err := migrationsFor(database)
+	if err != nil {
+		return err
+	}
+	if version < 0 || version > len(migrations) {
+		return fmt.Errorf("unknown schema version %d; must be between 0 and %d", version, len(migrations))
+	}
+
+	ctx := context.Background()
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if isSQLite(database) {
+		return migrateSQLite(ctx, conn, migrations, version, down)
+	}
+
+	// the lock belongs to the session, so it is taken and released on conn
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLock)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	var tracked, legacy bool
+	err = conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL, to_regclass('users') IS NOT NULL").Scan(&tracked, &legacy)
+	if err != nil {
+		return err
+	}
+
+	if !tracked && legacy {
+		err = adoptBaseline(ctx, conn, migrations[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, createSchemaMigrations)
+	if err != nil {
+		return err
+	}
+
+	if !tracked && legacy {
+		_, err = conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", migrations[0].Version, migrations[0].Name, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	var current int
+	err = conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	err = checkTarget(migrations, current, version, down)
+	if err != nil {
+		return err
+	}
+
+	return applyMigrations(migrations, current, version, func(script string, record string, args ...any) error {
+		return runMigration(ctx, conn, script, record, args...)
+	})
+}
+
+// checkTarget makes sure the schema goes the way it was asked to from
+// current to version: up, or down only for MigrateDown.
+func checkTarget(migrations []Migration, current int, version int, down bool) error {
+	if current > len(migrations) {
+		return ErrSchemaNewer
+	}
+	if !down && version < current {
+		return fmt.Errorf("schema is at version %d: %w", current, ErrSchemaDowngrade)
+	}
+	if down && version > current {
+		return fmt.Errorf("schema is at version %d; MigrateDown cannot bring it up to %d", current, version)
+	}
+	return nil
+}
+
+// adoptBaseline checks that a database made before there were migrations has
+// the schema of the baseline, so that it can be recorded as version 1. The
+// baseline is created in a scratch schema and compared with the database
+// column by column and constraint by constraint; the scratch schema is rolled
+// back afterwards. A database that differs is not touched, and the error
+// lists every difference.
+func adoptBaseline(ctx context.Context, conn *sql.Conn, baseline Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var schema string
+	err = tx.QueryRowContext(ctx, "SELECT current_schema()").Scan(&schema)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "CREATE SCHEMA "+baselineSchema)
+	if err != nil {
+		return err
+	}
+
+	// the baseline creates its tables in the first schema of the path and
+	// finds the extensions of the database in the second
+	_, err = tx.ExecContext(ctx, "SELECT set_config('search_path', $1 || ', ' || quote_ident($2), true)", baselineSchema, schema)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, baseline.Up)
+	if err != nil {
+		return fmt.Errorf("creating the baseline to compare with: %w", err)
+	}
+
+	differences, err := schemaDifferences(ctx, tx, schema)
+	if err != nil {
+		return err
+	}
+
+	if len(differences) > 0 {
+		return fmt.Errorf("%w:\n%s", ErrLegacySchema, strings.Join(differences, "\n"))
+	}
+	return nil
+}
+
+// schemaDifferences compares the tables of schema with those of the baseline
+// in baselineSchema. Constraints are compared by their definition, as their
+// names may differ; one that is validated in the baseline has to be validated
+// in schema as well, while those the baseline adds NOT VALID may be either.
+func schemaDifferences(ctx context.Context, tx *sql.Tx, schema string) ([]string, error) {
+	var differences []string
+
+	rows, err := tx.QueryContext(ctx, `SELECT COALESCE(b.table_name, s.table_name), COALESCE(b.column_name, s.column_name), b.data_type, b.is_nullable, s.data_type, s.is_nullable
+FROM (SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = $1) b
+FULL JOIN (SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = $2 AND table_name <> 'schema_migrations') s
+ON s.table_name = b.table_name AND s.column_name = b.column_name
+WHERE b.column_name IS NULL OR s.column_name IS NULL OR s.data_type <> b.data_type OR s.is_nullable <> b.is_nullable
+ORDER BY 1, 2`, baselineSchema, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, column string
+		var want, wantNullable, have, haveNullable sql.NullString
+		err = rows.Scan(&table, &column, &want, &wantNullable, &have, &haveNullable)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !have.Valid:
+			differences = append(differences, fmt.Sprintf("%s.%s: missing", table, column))
+		case !want.Valid:
+			differences = append(differences, fmt.Sprintf("%s.%s: not in the baseline", table, column))
+		default:
+			differences = append(differences, fmt.Sprintf("%s.%s: is %s, must be %s", table, column, columnType(have.String, haveNullable.String), columnType(want.String, wantNullable.String)))
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.QueryContext(ctx, `WITH constraints AS (
+SELECT n.nspname AS schema_name, t.relname AS table_name, replace(replace(pg_get_constraintdef(c.oid), n.nspname || '.', ''), ' NOT VALID', '') AS definition, c.convalidated AS valid
+FROM pg_constraint c JOIN pg_class t ON t.oid = c.conrelid JOIN pg_namespace n ON n.oid = t.relnamespace
+WHERE n.nspname IN ($1, $2) AND t.relname <> 'schema_migrations'
+)
+SELECT COALESCE(b.table_name, s.table_name), COALESCE(b.definition, s.definition), b.definition IS NOT NULL, s.definition IS NOT NULL
+FROM (SELECT table_name, definition, valid FROM constraints WHERE schema_name = $1) b
+FULL JOIN (SELECT table_name, definition, valid FROM constraints WHERE schema_name = $2) s
+ON s.table_name = b.table_name AND s.definition = b.definition
+WHERE b.definition IS NULL OR s.definition IS NULL OR (b.valid AND NOT s.valid)
+ORDER BY 1, 2`, baselineSchema, schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, definition string
+		var inBaseline, inSchema bool
+		err = rows.Scan(&table, &definition, &inBaseline, &inSchema)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !inSchema:
+			differences = append(differences, fmt.Sprintf("%s: missing constraint %s", table, definition))
+		case !inBaseline:
+			differences = append(differences, fmt.Sprintf("%s: constraint %s not in the baseline", table, definition))
+		default:
+			differences = append(differences, fmt.Sprintf("%s: constraint %s is not validated", table, definition))
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return differences, nil
+}
+
+func columnType(dataType string, nullable string) string {
+	if nullable == "YES" {
+		return dataType
+	}
+	return dataType + " not null"
+}
+
+const createSchemaMigrations = "CREATE TABLE IF NOT EXISTS schema_migrations (version integer NOT NULL PRIMARY KEY, name character varying NOT NULL, applied_at timestamp without time zone NOT NULL)"
+
+// migrateSQLite is migrate on SQLite. A transaction begun IMMEDIATE holds
+// the write lock while the schema is migrated, and every migration runs under
+// a savepoint of its own, so a failing one is undone alone and those before
+// it are kept.
+//
+// SQLite changes most of a table only by making it anew, and dropping the old
+// one would cascade to the rows that refer to it. Foreign keys are therefore
+// off while the schema is migrated, which can only be switched outside a
+// transaction, and checked all at once before the commit.
+func migrateSQLite(ctx context.Context, conn *sql.Conn, migrations []Migration, version int, down bool) error {
+	_, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	if err != nil {
+		return err
+	}
+	// after the commit there is nothing left to roll back
+	defer conn.ExecContext(ctx, "ROLLBACK")
+
+	_, err = conn.ExecContext(ctx, createSchemaMigrations)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	err = conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current)
+	if err != nil {
+		return err
+	}
+	err = checkTarget(migrations, current, version, down)
+	if err != nil {
+		return err
+	}
+
+	migrateErr := applyMigrations(migrations, current, version, func(script string, record string, args ...any) error {
+		return runSavepoint(ctx, conn, script, record, args...)
+	})
+
+	err = checkForeignKeys(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	if migrateErr != nil {
+		return migrateErr
+	}
+	return err
+}
+
+// checkForeignKeys fails if a row of the SQLite database refers to one that
+// does not exist.
+func checkForeignKeys(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		var table, parent string
+		var row sql.NullInt64
+		var key int
+		err = rows.Scan(&table, &row, &parent, &key)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("row %d of %s refers to a missing row of %s", row.Int64, table, parent)
+	}
+	return rows.Err()
+}
+
+// applyMigrations takes the schema from version current to version, handing
+// each migration to run with the statement that records it.
+func applyMigrations(migrations []Migration, current int, version int, run func(script string, record string, args ...any) error) error {
+	for current < version {
+		migration := migrations[current]
+		err := run(migration.Up, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", migration.Version, migration.Name, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		current++
+	}
+
+	for current > version {
+		migration := migrations[current-1]
+		err := run(migration.Down, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		if err != nil {
+			return fmt.Errorf("migration %d %s down: %w", migration.Version, migration.Name, err)
+		}
+		current--
+	}
+	return nil
+}
+
+// runMigration runs a script and the statement that records it in one
+// database transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, script string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// without arguments the script goes out as it is, all statements at once
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// runSavepoint runs a script and the statement that records it under a
+// savepoint of the transaction migrateSQLite holds.
+func runSavepoint(ctx context.Context, conn *sql.Conn, script string, record string, args ...any) error {
+	_, err := conn.ExecContext(ctx, "SAVEPOINT migration")
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, script)
+	if err == nil {
+		_, err = conn.ExecContext(ctx, record, args...)
+	}
+	if err != nil {
+		conn.ExecContext(ctx, "ROLLBACK TO migration")
+		conn.ExecContext(ctx, "RELEASE migration")
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, "RELEASE migration")
+	return err
+}
@@ -0,0 +1,360 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestDatabase creates an empty database next to the one the tests share.
+func newTestDatabase(t *testing.T, name string) *sql.DB {
+	if testDriver == DriverSQLite {
+		conn, err := OpenSQLite(filepath.Join(t.TempDir(), name+".db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			conn.Close()
+		})
+		return conn
+	}
+
+	_, err := db.Exec("CREATE DATABASE " + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := sql.Open("pgx", fmt.Sprintf("postgres://user_name:secret@%s/%s?sslmode=disable", hostAndPort, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "baseline", migrations[0].Name)
+
+	// SQLite follows the Postgres schema version for version
+	sqlite, err := readMigrations("migrations/sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, sqlite, len(migrations))
+	for i := range sqlite {
+		assert.Equal(t, migrations[i].Name, sqlite[i].Name)
+	}
+
+	version, err := SchemaVersion(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(migrations), version)
+}
+
+func TestMigrateDown(t *testing.T) {
+	conn := newTestDatabase(t, "migrate_down")
+	err := Migrate(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// starting with an older version leaves the schema alone
+	err = MigrateTo(conn, 0)
+	assert.ErrorIs(t, err, ErrSchemaDowngrade)
+
+	err = MigrateDown(conn, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := SchemaVersion(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, version)
+
+	_, err = conn.Exec("SELECT 1 FROM accounts")
+	assert.Error(t, err)
+
+	err = Migrate(conn)
+	assert.NoError(t, err)
+
+	err = MigrateTo(conn, 1000)
+	assert.Error(t, err)
+}
+
+func TestMigrateJournalWorkflow(t *testing.T) {
+	conn := newTestDatabase(t, "migrate_journal")
+	err := MigrateTo(conn, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, statement := range []string{
+		"INSERT INTO users (id, name, password) VALUES ('00000000-0000-0000-0000-000000000001', 'owner', 'x')",
+		"INSERT INTO ledgers (id, name, owner) VALUES (1, 'Default', '00000000-0000-0000-0000-000000000001')",
+		"INSERT INTO journal_entries (id, ledger_id, date, description) VALUES (1, 1, '2023-03-01 00:00:00', 'Opening')",
+	} {
+		_, err = conn.Exec(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = Migrate(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// entries from before the workflow keep counting
+	var status string
+	err = conn.QueryRow("SELECT status FROM journal_entries WHERE id = 1").Scan(&status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusPosted, status)
+}
+
+func TestMigrateRefusesDifferentLegacySchema(t *testing.T) {
+	if testDriver == DriverSQLite {
+		t.Skip("SQLite databases were never made from bookholder.sql")
+	}
+
+	conn := newTestDatabase(t, "migrate_legacy")
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = conn.Exec(migrations[0].Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec("ALTER TABLE transactions ALTER COLUMN amount TYPE numeric; ALTER TABLE accounts ADD COLUMN note character varying")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Migrate(conn)
+	assert.ErrorIs(t, err, ErrLegacySchema)
+	assert.ErrorContains(t, err, "accounts.note: not in the baseline")
+	assert.ErrorContains(t, err, "transactions.amount: is numeric not null, must be double precision not null")
+
+	// the database is left as it was
+	var tracked bool
+	err = conn.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&tracked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, tracked)
+}
+
+func TestMigrateAdoptsBaseline(t *testing.T) {
+	if testDriver == DriverSQLite {
+		t.Skip("SQLite databases were never made from bookholder.sql")
+	}
+
+	conn := newTestDatabase(t, "migrate_adopt")
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a database made from bookholder.sql, before there were migrations
+	_, err = conn.Exec(migrations[0].Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.Exec(`INSERT INTO users (name, password) VALUES ('anna', 'x');
+INSERT INTO accounts (id, name, kind) VALUES (1200, 'Bank', 'asset'), (8400, 'Revenue', 'revenue');
+INSERT INTO transactions (amount, debit, offset_account, account, date, description) VALUES (12.5, true, 8400, 1200, '2023-03-01 00:00:00', 'Sale')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Migrate(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	err = conn.QueryRow("SELECT name FROM schema_migrations WHERE version = 1").Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "baseline", name)
+
+	// and it is brought up to date from there
+	version, err := SchemaVersion(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(migrations), version)
+
+	var amount int64
+	var status string
+	err = conn.QueryRow("SELECT amount, status FROM transactions WHERE account = 1200").Scan(&amount, &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(1250), amount)
+	assert.Equal(t, StatusPosted, status)
+}
+
+func TestMigrateMinorUnits(t *testing.T) {
+	conn := newTestDatabase(t, "migrate_minor_units")
+	err := MigrateTo(conn, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// amounts from before minor units, in euros as double precision
+	for _, statement := range []string{
+		"INSERT INTO accounts (id, name, kind) VALUES (1200, 'Bank', 'asset'), (8400, 'Revenue', 'revenue')",
+		"INSERT INTO transactions (id, amount, debit, offset_account, account, date) VALUES (1, 12.345, true, 8400, 1200, '2023-03-01 00:00:00'), (2, -0.005, true, 8400, 1200, '2023-03-01 00:00:00'), (3, 0.145, true, 8400, 1200, '2023-03-01 00:00:00')",
+		"INSERT INTO journal_entries (id, date) VALUES (1, '2023-03-01 00:00:00')",
+		"INSERT INTO journal_lines (entry_id, account, amount, debit) VALUES (1, 1200, 0.145, true), (1, 8400, 0.145, false)",
+	} {
+		_, err = conn.Exec(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = MigrateTo(conn, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rounded to the nearest cent, halves away from zero
+	for id, want := range map[int]int64{1: 1235, 2: -1, 3: 15} {
+		var amount int64
+		err = conn.QueryRow("SELECT amount FROM transactions WHERE id = $1", id).Scan(&amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, want, amount, "transaction %d", id)
+	}
+
+	var lines int64
+	err = conn.QueryRow("SELECT SUM(amount) FROM journal_lines").Scan(&lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(30), lines)
+}
+
+func TestMigrateRefusesInvalidKinds(t *testing.T) {
+	conn := newTestDatabase(t, "migrate_kinds")
+	err := MigrateTo(conn, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an account from before kinds were checked
+	_, err = conn.Exec("INSERT INTO accounts (id, name, kind) VALUES (1200, 'Bank', 'Aktiva')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Migrate(conn)
+	assert.Error(t, err)
+	if testDriver != DriverSQLite {
+		assert.ErrorContains(t, err, `account 1200: kind "Aktiva" is not one of`)
+	}
+
+	version, err := SchemaVersion(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, version)
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	conn := newTestDatabase(t, "migrate_concurrently")
+
+	// two replicas starting together
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = Migrate(conn)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+
+	var count int
+	err := conn.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(migrations), count)
+}
+
+func TestMigrateGivesLegacyBooksToTheirUsers(t *testing.T) {
+	conn := newTestDatabase(t, "migrate_ledgers")
+	err := MigrateTo(conn, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// books from before ledgers, kept by two users together
+	for _, statement := range []string{
+		"INSERT INTO users (id, name, password) VALUES ('00000000-0000-0000-0000-000000000001', 'bert', 'x'), ('00000000-0000-0000-0000-000000000002', 'anna', 'x')",
+		"INSERT INTO accounts (id, name, kind) VALUES (1200, 'Bank', 'asset'), (8400, 'Revenue', 'revenue')",
+		"INSERT INTO transactions (amount, debit, offset_account, account, date, description) VALUES (1250, true, 8400, 1200, '2023-03-01 00:00:00', 'Sale')",
+	} {
+		_, err = conn.Exec(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = Migrate(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ledger uint
+	var owner string
+	err = conn.QueryRow("SELECT id, owner FROM ledgers WHERE name = 'Default'").Scan(&ledger, &owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "00000000-0000-0000-0000-000000000002", owner)
+
+	var members int
+	err = conn.QueryRow("SELECT COUNT(*) FROM ledger_members WHERE ledger_id = $1 AND role = 'owner'", ledger).Scan(&members)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, members)
+
+	var accounts, transactions int
+	err = conn.QueryRow("SELECT (SELECT COUNT(*) FROM accounts WHERE ledger_id = $1), (SELECT COUNT(*) FROM transactions WHERE ledger_id = $1)", ledger).Scan(&accounts, &transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, accounts)
+	assert.Equal(t, 1, transactions)
+}
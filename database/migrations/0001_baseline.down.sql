@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS transactions;
+
+DROP TABLE IF EXISTS accounts;
+
+DROP TABLE IF EXISTS users;
+
+DROP EXTENSION IF EXISTS "uuid-ossp";
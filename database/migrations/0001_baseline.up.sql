@@ -29,4 +29,4 @@ ALTER TABLE ONLY transactions
     ADD CONSTRAINT "Account" FOREIGN KEY (account) REFERENCES accounts(id) NOT VALID;
 
 ALTER TABLE ONLY transactions
-    ADD CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id) NOT VALID;
\ No newline at end of file
+    ADD CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id) NOT VALID;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS journal_lines;
+
+DROP TABLE IF EXISTS journal_entries;
@@ -0,0 +1,13 @@
+CREATE TABLE journal_entries (
+    id serial NOT NULL PRIMARY KEY,
+    date timestamp without time zone NOT NULL,
+    description character varying
+);
+
+CREATE TABLE journal_lines (
+    id serial NOT NULL PRIMARY KEY,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account integer NOT NULL REFERENCES accounts(id),
+    amount double precision NOT NULL,
+    debit boolean NOT NULL
+);
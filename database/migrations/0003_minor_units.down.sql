@@ -0,0 +1,5 @@
+ALTER TABLE journal_lines
+    ALTER COLUMN amount TYPE double precision USING amount / 100.0;
+
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE double precision USING amount / 100.0;
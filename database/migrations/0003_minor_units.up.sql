@@ -0,0 +1,10 @@
+-- Amounts become integer minor units. Values are rounded to the nearest cent,
+-- with halves rounded away from zero (commercial rounding): 0.005 becomes
+-- 0.01 and -0.005 becomes -0.01. The float is cast to numeric first, which
+-- keeps its shortest decimal representation, so 0.145 is rounded as 0.145
+-- and not as 0.14499999.
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE bigint USING round(amount::numeric * 100)::bigint;
+
+ALTER TABLE journal_lines
+    ALTER COLUMN amount TYPE bigint USING round(amount::numeric * 100)::bigint;
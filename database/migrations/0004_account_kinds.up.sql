@@ -0,0 +1,19 @@
+-- accounts from before kinds were checked may have any kind; they are listed
+-- so they can be corrected by hand, and nothing is changed until they are
+DO $$
+DECLARE
+    invalid text;
+BEGIN
+    SELECT string_agg(format('account %s: kind "%s" is not one of asset, liability, equity, revenue or expense', id, kind), E'\n' ORDER BY id)
+    INTO invalid
+    FROM accounts
+    WHERE kind NOT IN ('asset', 'liability', 'equity', 'revenue', 'expense');
+
+    IF invalid IS NOT NULL THEN
+        RAISE EXCEPTION E'accounts have kinds that are not valid:\n%', invalid;
+    END IF;
+END
+$$;
+
+ALTER TABLE accounts
+    ADD CONSTRAINT accounts_kind_check CHECK (kind IN ('asset', 'liability', 'equity', 'revenue', 'expense'));
@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS "Parent",
+    DROP COLUMN IF EXISTS is_group,
+    DROP COLUMN IF EXISTS parent;
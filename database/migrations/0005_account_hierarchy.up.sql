@@ -0,0 +1,6 @@
+ALTER TABLE accounts
+    ADD COLUMN parent integer,
+    ADD COLUMN is_group boolean NOT NULL DEFAULT false;
+
+ALTER TABLE ONLY accounts
+    ADD CONSTRAINT "Parent" FOREIGN KEY (parent) REFERENCES accounts(id);
@@ -0,0 +1,38 @@
+-- books of more than one ledger cannot go back, as account ids may repeat
+ALTER TABLE journal_lines
+    DROP CONSTRAINT journal_lines_ledger_id_account_fkey;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT "Account",
+    DROP CONSTRAINT "Offset";
+
+ALTER TABLE accounts
+    DROP CONSTRAINT "Parent",
+    DROP CONSTRAINT accounts_pkey;
+
+ALTER TABLE journal_lines DROP COLUMN ledger_id;
+
+ALTER TABLE journal_entries DROP COLUMN ledger_id;
+
+ALTER TABLE transactions DROP COLUMN ledger_id;
+
+ALTER TABLE accounts DROP COLUMN ledger_id;
+
+ALTER TABLE ONLY accounts
+    ADD CONSTRAINT accounts_pkey PRIMARY KEY (id);
+
+ALTER TABLE ONLY accounts
+    ADD CONSTRAINT "Parent" FOREIGN KEY (parent) REFERENCES accounts(id);
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT "Account" FOREIGN KEY (account) REFERENCES accounts(id) NOT VALID;
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id) NOT VALID;
+
+ALTER TABLE journal_lines
+    ADD CONSTRAINT journal_lines_account_fkey FOREIGN KEY (account) REFERENCES accounts(id);
+
+DROP TABLE IF EXISTS ledger_members;
+
+DROP TABLE IF EXISTS ledgers;
@@ -0,0 +1,87 @@
+CREATE TABLE ledgers (
+    id serial NOT NULL PRIMARY KEY,
+    name character varying NOT NULL,
+    owner UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE ledger_members (
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role character varying NOT NULL CHECK (role IN ('owner', 'accountant', 'approver', 'viewer', 'auditor')),
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+ALTER TABLE accounts ADD COLUMN ledger_id integer;
+
+ALTER TABLE transactions ADD COLUMN ledger_id integer;
+
+ALTER TABLE journal_entries ADD COLUMN ledger_id integer;
+
+ALTER TABLE journal_lines ADD COLUMN ledger_id integer;
+
+-- Books from before ledgers were kept by all users together. They move into a
+-- ledger named "Default", owned by the first user by name, and every user
+-- keeps them as an owner of it.
+DO $$
+DECLARE
+    ledger integer;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM accounts) AND NOT EXISTS (SELECT 1 FROM transactions) AND NOT EXISTS (SELECT 1 FROM journal_entries) THEN
+        RETURN;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM users) THEN
+        RAISE EXCEPTION 'existing accounts and transactions need an owner; create a user before upgrading';
+    END IF;
+
+    INSERT INTO ledgers (name, owner)
+    SELECT 'Default', id FROM users ORDER BY name LIMIT 1
+    RETURNING id INTO ledger;
+
+    INSERT INTO ledger_members (ledger_id, user_id, role)
+    SELECT ledger, id, 'owner' FROM users;
+
+    UPDATE accounts SET ledger_id = ledger;
+    UPDATE transactions SET ledger_id = ledger;
+    UPDATE journal_entries SET ledger_id = ledger;
+    UPDATE journal_lines SET ledger_id = ledger;
+END
+$$;
+
+-- account ids are unique per ledger, so every key of accounts includes it
+ALTER TABLE transactions
+    DROP CONSTRAINT "Account",
+    DROP CONSTRAINT "Offset";
+
+ALTER TABLE journal_lines
+    DROP CONSTRAINT journal_lines_account_fkey;
+
+ALTER TABLE accounts
+    DROP CONSTRAINT "Parent",
+    DROP CONSTRAINT accounts_pkey;
+
+ALTER TABLE accounts
+    ALTER COLUMN ledger_id SET NOT NULL,
+    ADD CONSTRAINT accounts_ledger_id_fkey FOREIGN KEY (ledger_id) REFERENCES ledgers(id) ON DELETE CASCADE,
+    ADD CONSTRAINT accounts_pkey PRIMARY KEY (ledger_id, id);
+
+ALTER TABLE ONLY accounts
+    ADD CONSTRAINT "Parent" FOREIGN KEY (ledger_id, parent) REFERENCES accounts(ledger_id, id);
+
+ALTER TABLE transactions
+    ALTER COLUMN ledger_id SET NOT NULL,
+    ADD CONSTRAINT transactions_ledger_id_fkey FOREIGN KEY (ledger_id) REFERENCES ledgers(id) ON DELETE CASCADE;
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT "Account" FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id) NOT VALID;
+
+ALTER TABLE ONLY transactions
+    ADD CONSTRAINT "Offset" FOREIGN KEY (ledger_id, offset_account) REFERENCES accounts(ledger_id, id) NOT VALID;
+
+ALTER TABLE journal_entries
+    ALTER COLUMN ledger_id SET NOT NULL,
+    ADD CONSTRAINT journal_entries_ledger_id_fkey FOREIGN KEY (ledger_id) REFERENCES ledgers(id) ON DELETE CASCADE;
+
+ALTER TABLE journal_lines
+    ALTER COLUMN ledger_id SET NOT NULL,
+    ADD CONSTRAINT journal_lines_ledger_id_account_fkey FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id);
@@ -0,0 +1,30 @@
+ALTER TABLE journal_entries
+    ADD COLUMN type character varying NOT NULL DEFAULT 'standard' CHECK (type IN ('standard', 'closing'));
+
+CREATE TABLE fiscal_years (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    closed boolean NOT NULL DEFAULT false,
+    closing_entry_id integer REFERENCES journal_entries(id)
+);
+
+CREATE TABLE fiscal_periods (
+    id serial NOT NULL PRIMARY KEY,
+    fiscal_year_id integer NOT NULL REFERENCES fiscal_years(id) ON DELETE CASCADE,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    number integer NOT NULL,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    status character varying NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'soft_closed', 'locked'))
+);
+
+CREATE TABLE opening_balances (
+    fiscal_year_id integer NOT NULL REFERENCES fiscal_years(id) ON DELETE CASCADE,
+    ledger_id integer NOT NULL,
+    account integer NOT NULL,
+    balance bigint NOT NULL,
+    PRIMARY KEY (fiscal_year_id, account),
+    FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id)
+);
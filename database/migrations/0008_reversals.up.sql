@@ -0,0 +1,3 @@
+ALTER TABLE transactions
+    ADD COLUMN reverses integer UNIQUE REFERENCES transactions(id),
+    ADD COLUMN replaces integer REFERENCES transactions(id);
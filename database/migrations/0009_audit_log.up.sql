@@ -0,0 +1,13 @@
+CREATE TABLE audit_log (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer,
+    user_id UUID NOT NULL,
+    entity character varying NOT NULL,
+    entity_id character varying NOT NULL,
+    action character varying NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before text,
+    after text,
+    created_at timestamp without time zone NOT NULL,
+    prev_hash character varying NOT NULL,
+    hash character varying NOT NULL
+);
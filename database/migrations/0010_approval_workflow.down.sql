@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS journal_approvals;
+
+DROP TABLE IF EXISTS approvals;
+
+ALTER TABLE journal_entries
+    DROP CONSTRAINT IF EXISTS journal_entries_status_check,
+    DROP COLUMN IF EXISTS prepared_by,
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_status_check,
+    DROP COLUMN IF EXISTS prepared_by,
+    DROP COLUMN IF EXISTS status;
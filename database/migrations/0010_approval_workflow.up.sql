@@ -0,0 +1,30 @@
+-- bookings made before the workflow count already, so they are posted
+ALTER TABLE transactions
+    ADD COLUMN status character varying NOT NULL DEFAULT 'posted',
+    ADD COLUMN prepared_by UUID,
+    ADD CONSTRAINT transactions_status_check CHECK (status IN ('draft', 'submitted', 'approved', 'rejected', 'posted'));
+
+ALTER TABLE journal_entries
+    ADD COLUMN status character varying NOT NULL DEFAULT 'posted',
+    ADD COLUMN prepared_by UUID,
+    ADD CONSTRAINT journal_entries_status_check CHECK (status IN ('draft', 'submitted', 'approved', 'rejected', 'posted'));
+
+CREATE TABLE approvals (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    transaction_id integer NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    decision character varying NOT NULL CHECK (decision IN ('approved', 'rejected')),
+    comment character varying,
+    created_at timestamp without time zone NOT NULL
+);
+
+CREATE TABLE journal_approvals (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    decision character varying NOT NULL CHECK (decision IN ('approved', 'rejected')),
+    comment character varying,
+    created_at timestamp without time zone NOT NULL
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS bank_lines;
+
+DROP TABLE IF EXISTS import_profiles;
@@ -0,0 +1,26 @@
+CREATE TABLE import_profiles (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    name character varying NOT NULL,
+    definition text NOT NULL,
+    UNIQUE (ledger_id, name)
+);
+
+CREATE TABLE bank_lines (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    account integer NOT NULL,
+    booking_date date NOT NULL,
+    value_date date,
+    amount bigint NOT NULL,
+    counterparty character varying NOT NULL DEFAULT '',
+    counterparty_iban character varying NOT NULL DEFAULT '',
+    remittance character varying NOT NULL DEFAULT '',
+    reference character varying NOT NULL DEFAULT '',
+    source character varying NOT NULL,
+    hash character varying NOT NULL,
+    transaction_id integer REFERENCES transactions(id) ON DELETE SET NULL,
+    imported_at timestamp without time zone NOT NULL,
+    UNIQUE (ledger_id, account, hash),
+    FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS tax_code;
+
+ALTER TABLE bank_lines DROP COLUMN IF EXISTS rule_id;
+
+DROP TABLE IF EXISTS rules;
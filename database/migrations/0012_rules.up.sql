@@ -0,0 +1,19 @@
+CREATE TABLE rules (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    name character varying NOT NULL,
+    priority integer NOT NULL DEFAULT 0,
+    conditions text NOT NULL,
+    offset_account integer,
+    description character varying NOT NULL DEFAULT '',
+    tax_code character varying NOT NULL DEFAULT '',
+    assign boolean NOT NULL DEFAULT false,
+    matches integer NOT NULL DEFAULT 0,
+    last_matched timestamp without time zone
+);
+
+ALTER TABLE bank_lines
+    ADD COLUMN rule_id integer REFERENCES rules(id) ON DELETE SET NULL;
+
+ALTER TABLE transactions
+    ADD COLUMN tax_code character varying NOT NULL DEFAULT '';
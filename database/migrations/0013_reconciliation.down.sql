@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS bank_match_transactions;
+
+DROP TABLE IF EXISTS bank_match_lines;
+
+DROP TABLE IF EXISTS bank_matches;
@@ -0,0 +1,20 @@
+CREATE TABLE bank_matches (
+    id serial NOT NULL PRIMARY KEY,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    account integer NOT NULL,
+    user_id UUID NOT NULL,
+    created_at timestamp without time zone NOT NULL,
+    FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id)
+);
+
+CREATE TABLE bank_match_lines (
+    match_id integer NOT NULL REFERENCES bank_matches(id) ON DELETE CASCADE,
+    bank_line_id integer NOT NULL UNIQUE REFERENCES bank_lines(id) ON DELETE CASCADE,
+    PRIMARY KEY (match_id, bank_line_id)
+);
+
+CREATE TABLE bank_match_transactions (
+    match_id integer NOT NULL REFERENCES bank_matches(id) ON DELETE CASCADE,
+    transaction_id integer NOT NULL UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    PRIMARY KEY (match_id, transaction_id)
+);
@@ -0,0 +1,23 @@
+CREATE TABLE accounts (
+    id integer NOT NULL PRIMARY KEY,
+    name text NOT NULL,
+    kind text NOT NULL
+);
+
+CREATE TABLE transactions (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    amount double precision NOT NULL,
+    debit boolean NOT NULL,
+    offset_account integer NOT NULL,
+    account integer NOT NULL,
+    date timestamp NOT NULL,
+    description text,
+    CONSTRAINT "Account" FOREIGN KEY (account) REFERENCES accounts(id),
+    CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id)
+);
+
+CREATE TABLE users (
+    id text NOT NULL PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    name text UNIQUE NOT NULL,
+    password text NOT NULL
+);
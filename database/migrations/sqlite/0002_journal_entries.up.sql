@@ -0,0 +1,13 @@
+CREATE TABLE journal_entries (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    date timestamp NOT NULL,
+    description text
+);
+
+CREATE TABLE journal_lines (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account integer NOT NULL REFERENCES accounts(id),
+    amount double precision NOT NULL,
+    debit boolean NOT NULL
+);
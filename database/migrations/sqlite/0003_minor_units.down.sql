@@ -0,0 +1,33 @@
+CREATE TABLE journal_lines_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account integer NOT NULL REFERENCES accounts(id),
+    amount double precision NOT NULL,
+    debit boolean NOT NULL
+);
+
+INSERT INTO journal_lines_new (id, entry_id, account, amount, debit)
+SELECT id, entry_id, account, amount / 100.0, debit FROM journal_lines;
+
+DROP TABLE journal_lines;
+
+ALTER TABLE journal_lines_new RENAME TO journal_lines;
+
+CREATE TABLE transactions_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    amount double precision NOT NULL,
+    debit boolean NOT NULL,
+    offset_account integer NOT NULL,
+    account integer NOT NULL,
+    date timestamp NOT NULL,
+    description text,
+    CONSTRAINT "Account" FOREIGN KEY (account) REFERENCES accounts(id),
+    CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id)
+);
+
+INSERT INTO transactions_new (id, amount, debit, offset_account, account, date, description)
+SELECT id, amount / 100.0, debit, offset_account, account, date, description FROM transactions;
+
+DROP TABLE transactions;
+
+ALTER TABLE transactions_new RENAME TO transactions;
@@ -0,0 +1,37 @@
+-- Amounts become integer minor units, rounded to the nearest cent with halves
+-- away from zero, as on Postgres. They are rounded to six places first, which
+-- drops the error of the float, so 0.145 is rounded as 0.145 and not as
+-- 0.14499999.
+CREATE TABLE transactions_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    amount bigint NOT NULL,
+    debit boolean NOT NULL,
+    offset_account integer NOT NULL,
+    account integer NOT NULL,
+    date timestamp NOT NULL,
+    description text,
+    CONSTRAINT "Account" FOREIGN KEY (account) REFERENCES accounts(id),
+    CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id)
+);
+
+INSERT INTO transactions_new (id, amount, debit, offset_account, account, date, description)
+SELECT id, CAST(round(round(amount * 100, 6)) AS integer), debit, offset_account, account, date, description FROM transactions;
+
+DROP TABLE transactions;
+
+ALTER TABLE transactions_new RENAME TO transactions;
+
+CREATE TABLE journal_lines_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account integer NOT NULL REFERENCES accounts(id),
+    amount bigint NOT NULL,
+    debit boolean NOT NULL
+);
+
+INSERT INTO journal_lines_new (id, entry_id, account, amount, debit)
+SELECT id, entry_id, account, CAST(round(round(amount * 100, 6)) AS integer), debit FROM journal_lines;
+
+DROP TABLE journal_lines;
+
+ALTER TABLE journal_lines_new RENAME TO journal_lines;
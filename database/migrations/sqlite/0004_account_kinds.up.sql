@@ -0,0 +1,11 @@
+CREATE TABLE accounts_new (
+    id integer NOT NULL PRIMARY KEY,
+    name text NOT NULL,
+    kind text NOT NULL CHECK (kind IN ('asset', 'liability', 'equity', 'revenue', 'expense'))
+);
+
+INSERT INTO accounts_new (id, name, kind) SELECT id, name, kind FROM accounts;
+
+DROP TABLE accounts;
+
+ALTER TABLE accounts_new RENAME TO accounts;
@@ -0,0 +1,3 @@
+ALTER TABLE accounts ADD COLUMN parent integer CONSTRAINT "Parent" REFERENCES accounts(id);
+
+ALTER TABLE accounts ADD COLUMN is_group boolean NOT NULL DEFAULT false;
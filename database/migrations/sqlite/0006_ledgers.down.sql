@@ -0,0 +1,66 @@
+-- books of more than one ledger cannot go back, as account ids may repeat
+CREATE TABLE accounts_new (
+    id integer NOT NULL PRIMARY KEY,
+    name text NOT NULL,
+    kind text NOT NULL CHECK (kind IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+    parent integer CONSTRAINT "Parent" REFERENCES accounts(id),
+    is_group boolean NOT NULL DEFAULT false
+);
+
+INSERT INTO accounts_new (id, name, kind, parent, is_group)
+SELECT id, name, kind, parent, is_group FROM accounts;
+
+CREATE TABLE transactions_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    amount bigint NOT NULL,
+    debit boolean NOT NULL,
+    offset_account integer NOT NULL,
+    account integer NOT NULL,
+    date timestamp NOT NULL,
+    description text,
+    CONSTRAINT "Account" FOREIGN KEY (account) REFERENCES accounts(id),
+    CONSTRAINT "Offset" FOREIGN KEY (offset_account) REFERENCES accounts(id)
+);
+
+INSERT INTO transactions_new (id, amount, debit, offset_account, account, date, description)
+SELECT id, amount, debit, offset_account, account, date, description FROM transactions;
+
+CREATE TABLE journal_entries_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    date timestamp NOT NULL,
+    description text
+);
+
+INSERT INTO journal_entries_new (id, date, description)
+SELECT id, date, description FROM journal_entries;
+
+CREATE TABLE journal_lines_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account integer NOT NULL REFERENCES accounts(id),
+    amount bigint NOT NULL,
+    debit boolean NOT NULL
+);
+
+INSERT INTO journal_lines_new (id, entry_id, account, amount, debit)
+SELECT id, entry_id, account, amount, debit FROM journal_lines;
+
+DROP TABLE journal_lines;
+
+DROP TABLE journal_entries;
+
+DROP TABLE transactions;
+
+DROP TABLE accounts;
+
+ALTER TABLE accounts_new RENAME TO accounts;
+
+ALTER TABLE transactions_new RENAME TO transactions;
+
+ALTER TABLE journal_entries_new RENAME TO journal_entries;
+
+ALTER TABLE journal_lines_new RENAME TO journal_lines;
+
+DROP TABLE IF EXISTS ledger_members;
+
+DROP TABLE IF EXISTS ledgers;
@@ -0,0 +1,93 @@
+CREATE TABLE ledgers (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    name text NOT NULL,
+    owner text NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE ledger_members (
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role text NOT NULL CHECK (role IN ('owner', 'accountant', 'approver', 'viewer', 'auditor')),
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+-- Books from before ledgers were kept by all users together. They move into a
+-- ledger named "Default", owned by the first user by name, and every user
+-- keeps them as an owner of it. Without users the ledger has no owner, which
+-- stops the migration.
+INSERT INTO ledgers (name, owner)
+SELECT 'Default', (SELECT id FROM users ORDER BY name LIMIT 1)
+WHERE EXISTS (SELECT 1 FROM accounts) OR EXISTS (SELECT 1 FROM transactions) OR EXISTS (SELECT 1 FROM journal_entries);
+
+INSERT INTO ledger_members (ledger_id, user_id, role)
+SELECT ledgers.id, users.id, 'owner' FROM ledgers, users;
+
+-- account ids are unique per ledger, so every key of accounts includes it
+CREATE TABLE accounts_new (
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    id integer NOT NULL,
+    name text NOT NULL,
+    kind text NOT NULL CHECK (kind IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+    parent integer,
+    is_group boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (ledger_id, id),
+    CONSTRAINT "Parent" FOREIGN KEY (ledger_id, parent) REFERENCES accounts(ledger_id, id)
+);
+
+INSERT INTO accounts_new (ledger_id, id, name, kind, parent, is_group)
+SELECT (SELECT id FROM ledgers), id, name, kind, parent, is_group FROM accounts;
+
+CREATE TABLE transactions_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    amount bigint NOT NULL,
+    debit boolean NOT NULL,
+    offset_account integer NOT NULL,
+    account integer NOT NULL,
+    date timestamp NOT NULL,
+    description text,
+    CONSTRAINT "Account" FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id),
+    CONSTRAINT "Offset" FOREIGN KEY (ledger_id, offset_account) REFERENCES accounts(ledger_id, id)
+);
+
+INSERT INTO transactions_new (id, ledger_id, amount, debit, offset_account, account, date, description)
+SELECT id, (SELECT id FROM ledgers), amount, debit, offset_account, account, date, description FROM transactions;
+
+CREATE TABLE journal_entries_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    date timestamp NOT NULL,
+    description text
+);
+
+INSERT INTO journal_entries_new (id, ledger_id, date, description)
+SELECT id, (SELECT id FROM ledgers), date, description FROM journal_entries;
+
+CREATE TABLE journal_lines_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    ledger_id integer NOT NULL,
+    account integer NOT NULL,
+    amount bigint NOT NULL,
+    debit boolean NOT NULL,
+    FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id)
+);
+
+INSERT INTO journal_lines_new (id, entry_id, ledger_id, account, amount, debit)
+SELECT id, entry_id, (SELECT id FROM ledgers), account, amount, debit FROM journal_lines;
+
+DROP TABLE journal_lines;
+
+DROP TABLE journal_entries;
+
+DROP TABLE transactions;
+
+DROP TABLE accounts;
+
+ALTER TABLE accounts_new RENAME TO accounts;
+
+ALTER TABLE transactions_new RENAME TO transactions;
+
+ALTER TABLE journal_entries_new RENAME TO journal_entries;
+
+ALTER TABLE journal_lines_new RENAME TO journal_lines;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS opening_balances;
+
+DROP TABLE IF EXISTS fiscal_periods;
+
+DROP TABLE IF EXISTS fiscal_years;
+
+ALTER TABLE journal_entries DROP COLUMN type;
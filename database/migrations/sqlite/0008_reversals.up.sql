@@ -0,0 +1,22 @@
+-- a column added later cannot be unique, so the table is made anew
+CREATE TABLE transactions_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    amount bigint NOT NULL,
+    debit boolean NOT NULL,
+    offset_account integer NOT NULL,
+    account integer NOT NULL,
+    date timestamp NOT NULL,
+    description text,
+    reverses integer UNIQUE REFERENCES transactions(id),
+    replaces integer REFERENCES transactions(id),
+    CONSTRAINT "Account" FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id),
+    CONSTRAINT "Offset" FOREIGN KEY (ledger_id, offset_account) REFERENCES accounts(ledger_id, id)
+);
+
+INSERT INTO transactions_new (id, ledger_id, amount, debit, offset_account, account, date, description)
+SELECT id, ledger_id, amount, debit, offset_account, account, date, description FROM transactions;
+
+DROP TABLE transactions;
+
+ALTER TABLE transactions_new RENAME TO transactions;
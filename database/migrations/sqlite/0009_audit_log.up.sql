@@ -0,0 +1,13 @@
+CREATE TABLE audit_log (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer,
+    user_id text NOT NULL,
+    entity text NOT NULL,
+    entity_id text NOT NULL,
+    action text NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before text,
+    after text,
+    created_at timestamp NOT NULL,
+    prev_hash text NOT NULL,
+    hash text NOT NULL
+);
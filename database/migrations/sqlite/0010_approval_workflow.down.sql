@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS journal_approvals;
+
+DROP TABLE IF EXISTS approvals;
+
+ALTER TABLE journal_entries DROP COLUMN prepared_by;
+
+ALTER TABLE journal_entries DROP COLUMN status;
+
+ALTER TABLE transactions DROP COLUMN prepared_by;
+
+ALTER TABLE transactions DROP COLUMN status;
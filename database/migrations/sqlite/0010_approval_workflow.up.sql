@@ -0,0 +1,28 @@
+-- bookings made before the workflow count already, so they are posted
+ALTER TABLE transactions ADD COLUMN status text NOT NULL DEFAULT 'posted' CONSTRAINT transactions_status_check CHECK (status IN ('draft', 'submitted', 'approved', 'rejected', 'posted'));
+
+ALTER TABLE transactions ADD COLUMN prepared_by text;
+
+ALTER TABLE journal_entries ADD COLUMN status text NOT NULL DEFAULT 'posted' CHECK (status IN ('draft', 'submitted', 'approved', 'rejected', 'posted'));
+
+ALTER TABLE journal_entries ADD COLUMN prepared_by text;
+
+CREATE TABLE approvals (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    transaction_id integer NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id text NOT NULL,
+    decision text NOT NULL CHECK (decision IN ('approved', 'rejected')),
+    comment text,
+    created_at timestamp NOT NULL
+);
+
+CREATE TABLE journal_approvals (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    entry_id integer NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    user_id text NOT NULL,
+    decision text NOT NULL CHECK (decision IN ('approved', 'rejected')),
+    comment text,
+    created_at timestamp NOT NULL
+);
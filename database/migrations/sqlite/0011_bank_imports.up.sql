@@ -0,0 +1,26 @@
+CREATE TABLE import_profiles (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    definition text NOT NULL,
+    UNIQUE (ledger_id, name)
+);
+
+CREATE TABLE bank_lines (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    account integer NOT NULL,
+    booking_date date NOT NULL,
+    value_date date,
+    amount bigint NOT NULL,
+    counterparty text NOT NULL DEFAULT '',
+    counterparty_iban text NOT NULL DEFAULT '',
+    remittance text NOT NULL DEFAULT '',
+    reference text NOT NULL DEFAULT '',
+    source text NOT NULL,
+    hash text NOT NULL,
+    transaction_id integer REFERENCES transactions(id) ON DELETE SET NULL,
+    imported_at timestamp NOT NULL,
+    UNIQUE (ledger_id, account, hash),
+    FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id)
+);
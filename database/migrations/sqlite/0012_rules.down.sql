@@ -0,0 +1,30 @@
+ALTER TABLE transactions DROP COLUMN tax_code;
+
+-- a column with a foreign key cannot be dropped, so the table is made anew
+CREATE TABLE bank_lines_new (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    account integer NOT NULL,
+    booking_date date NOT NULL,
+    value_date date,
+    amount bigint NOT NULL,
+    counterparty text NOT NULL DEFAULT '',
+    counterparty_iban text NOT NULL DEFAULT '',
+    remittance text NOT NULL DEFAULT '',
+    reference text NOT NULL DEFAULT '',
+    source text NOT NULL,
+    hash text NOT NULL,
+    transaction_id integer REFERENCES transactions(id) ON DELETE SET NULL,
+    imported_at timestamp NOT NULL,
+    UNIQUE (ledger_id, account, hash),
+    FOREIGN KEY (ledger_id, account) REFERENCES accounts(ledger_id, id)
+);
+
+INSERT INTO bank_lines_new (id, ledger_id, account, booking_date, value_date, amount, counterparty, counterparty_iban, remittance, reference, source, hash, transaction_id, imported_at)
+SELECT id, ledger_id, account, booking_date, value_date, amount, counterparty, counterparty_iban, remittance, reference, source, hash, transaction_id, imported_at FROM bank_lines;
+
+DROP TABLE bank_lines;
+
+ALTER TABLE bank_lines_new RENAME TO bank_lines;
+
+DROP TABLE IF EXISTS rules;
@@ -0,0 +1,17 @@
+CREATE TABLE rules (
+    id integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+    ledger_id integer NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    name text NOT NULL,
+    priority integer NOT NULL DEFAULT 0,
+    conditions text NOT NULL,
+    offset_account integer,
+    description text NOT NULL DEFAULT '',
+    tax_code text NOT NULL DEFAULT '',
+    assign boolean NOT NULL DEFAULT false,
+    matches integer NOT NULL DEFAULT 0,
+    last_matched timestamp
+);
+
+ALTER TABLE bank_lines ADD COLUMN rule_id integer REFERENCES rules(id) ON DELETE SET NULL;
+
+ALTER TABLE transactions ADD COLUMN tax_code text NOT NULL DEFAULT '';
@@ -1,25 +1,93 @@
-package database
-
-import "time"
-
-type Account struct {
-	ID   uint
-	Name string
-	Kind string
-}
-
-type Transaction struct {
-	ID            uint
-	Amount        float32
-	Debit         bool
-	OffsetAccount uint
-	Account       uint
-	Date          time.Time
-	Description   string
-}
-
-type User struct {
-	ID       string
-	Name     string
-	Password string
-}
+package database
+
+import "time"
+
+// Account kinds. Assets and expenses normally carry a debit balance,
+// liabilities, equity and revenue a credit balance.
+const (
+	KindAsset     = "asset"
+	KindLiability = "liability"
+	KindEquity    = "equity"
+	KindRevenue   = "revenue"
+	KindExpense   = "expense"
+)
+
+var Kinds = []string{KindAsset, KindLiability, KindEquity, KindRevenue, KindExpense}
+
+func ValidKind(kind string) bool {
+	for _, valid := range Kinds {
+		if kind == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// DebitNormal reports whether accounts of the kind increase on the debit
+// side.
+func DebitNormal(kind string) bool {
+	return kind == KindAsset || kind == KindExpense
+}
+
+// Account is an account of the chart of accounts. Parent is the id of the
+// group account it belongs to, or 0. Group accounts cannot be posted to;
+// their balance rolls up from their children.
+type Account struct {
+	ID     uint
+	Name   string
+	Kind   string
+	Parent uint
+	Group  bool
+}
+
+// Transaction is a two-sided booking. TaxCode is the tax key of the booking,
+// like VSt19 for 19% input tax, or empty. Reverses is the id of the
+// transaction a reversal cancels, Replaces the id of the transaction a
+// correction took the place of; both are 0 for ordinary bookings. PreparedBy
+// is the user who created or last changed it, who cannot approve it. Rule is
+// the rule that filled in a new transaction; its match is counted when the
+// transaction is stored, and the rule is not kept with it.
+type Transaction struct {
+	ID            uint
+	Amount        Money
+	Debit         bool
+	OffsetAccount uint
+	Account       uint
+	Date          time.Time
+	Description   string
+	TaxCode       string
+	Status        string
+	Reverses      uint
+	Replaces      uint
+	PreparedBy    string
+	Rule          uint `json:"-"`
+}
+
+// JournalEntry is a booking made of any number of debit and credit lines.
+// Source tells whether the entry was stored as a journal entry or is a
+// two-sided Transaction presented as a two-line entry. Type is either
+// EntryStandard or EntryClosing. Status and PreparedBy follow the approval
+// workflow of transactions.
+type JournalEntry struct {
+	ID          uint
+	Source      string
+	Type        string
+	Date        time.Time
+	Description string
+	Status      string
+	PreparedBy  string
+	Lines       []JournalLine
+}
+
+type JournalLine struct {
+	ID      uint
+	Account uint
+	Amount  Money
+	Debit   bool
+}
+
+type User struct {
+	ID       string
+	Name     string
+	Password string
+}
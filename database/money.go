@@ -0,0 +1,103 @@
+package database
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units (cents). It is stored as an integer so
+// that sums are exact, and is read from and written to JSON as a decimal
+// number with at most two fractional digits, e.g. 1234.56.
+type Money int64
+
+var ErrInvalidMoney = errors.New("invalid amount; must be a decimal number with at most two decimal places")
+
+// ParseMoney reads a decimal string such as "1234.56", "-0.5" or "12".
+// Amounts with more than two decimal places are rejected instead of being
+// rounded, so no value is ever changed silently.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	units, cents, hasPoint := strings.Cut(s, ".")
+	if units == "" && cents == "" {
+		return 0, ErrInvalidMoney
+	}
+	if hasPoint && (cents == "" || len(cents) > 2) {
+		return 0, ErrInvalidMoney
+	}
+	if units == "" {
+		units = "0"
+	}
+	for len(cents) < 2 {
+		cents += "0"
+	}
+
+	for _, r := range units + cents {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidMoney
+		}
+	}
+
+	unitsInt, err := strconv.ParseInt(units, 10, 64)
+	if err != nil || unitsInt > (math.MaxInt64-99)/100 {
+		return 0, ErrInvalidMoney
+	}
+	centsInt, _ := strconv.ParseInt(cents, 10, 64)
+
+	amount := Money(unitsInt*100 + centsInt)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// String formats the amount with two decimal places and a point as decimal
+// separator.
+func (m Money) String() string {
+	sign := ""
+	// unsigned, as the smallest amount has no positive counterpart
+	value := uint64(m)
+	if m < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	cents := strconv.FormatUint(value%100, 10)
+	if len(cents) < 2 {
+		cents = "0" + cents
+	}
+	return sign + strconv.FormatUint(value/100, 10) + "." + cents
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and strings. Numbers are parsed
+// from their literal text, never through a float.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	amount, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = amount
+	return nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := map[string]Money{
+		"1234.56": 123456,
+		"12":      1200,
+		"0.5":     50,
+		".05":     5,
+		"-0.05":   -5,
+		"+3.10":   310,
+	}
+
+	for input, expected := range cases {
+		amount, err := ParseMoney(input)
+		if err != nil {
+			t.Error(input, err)
+		}
+		assert.Equal(t, expected, amount, input)
+	}
+}
+
+func TestParseMoneyInvalid(t *testing.T) {
+	for _, input := range []string{"", "-", "1.234", "1,50", "abc", "1.", "1e3", "99999999999999999999"} {
+		_, err := ParseMoney(input)
+		assert.ErrorIs(t, err, ErrInvalidMoney, input)
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	assert.Equal(t, "1234.56", Money(123456).String())
+	assert.Equal(t, "0.05", Money(5).String())
+	assert.Equal(t, "-0.05", Money(-5).String())
+	assert.Equal(t, "-12.00", Money(-1200).String())
+	assert.Equal(t, "92233720368547758.07", Money(math.MaxInt64).String())
+	assert.Equal(t, "-92233720368547758.08", Money(math.MinInt64).String())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var transaction Transaction
+	err := json.Unmarshal([]byte(`{"Amount": 0.1}`), &transaction)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(10), transaction.Amount)
+
+	err = json.Unmarshal([]byte(`{"Amount": "1234.56"}`), &transaction)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(123456), transaction.Amount)
+
+	err = json.Unmarshal([]byte(`{"Amount": 0.001}`), &transaction)
+	assert.Error(t, err)
+
+	data, err := json.Marshal(Money(123456))
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, "1234.56", string(data))
+}
@@ -0,0 +1,533 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMatchNotExists   = errors.New("match does not exist")
+	ErrMatchEmpty       = errors.New("a match needs at least one bank line and one transaction")
+	ErrMatchAmount      = errors.New("the bank lines and the transactions of a match have to add up to the same amount")
+	ErrMatchTransaction = errors.New("only posted transactions on the bank account can be matched")
+	ErrAlreadyMatched   = errors.New("bank line or transaction is already matched")
+)
+
+// maxSplitCandidates limits how many lines or transactions are tried when
+// looking for a sum that makes up a single one.
+const maxSplitCandidates = 20
+
+// Match reconciles bank lines with the transactions that record them on the
+// bank account: one line with one transaction, one line with several
+// transactions, or several lines with one transaction. Amount is the total
+// of the lines.
+type Match struct {
+	ID           uint
+	Account      uint
+	Lines        []uint
+	Transactions []uint
+	Amount       Money
+	User         string
+	Time         time.Time
+}
+
+// MatchSuggestion is a match the bank lines and transactions of an account
+// suggest. Days is the largest distance between the dates of the lines and
+// the transactions.
+type MatchSuggestion struct {
+	Lines        []uint
+	Transactions []uint
+	Amount       Money
+	Days         int
+	Reason       string
+}
+
+// ReconciliationReport compares the balance of a bank statement with the
+// balance of the bank account on the statement date. Lines and transactions
+// up to the date that are not matched, or only matched with something after
+// the date, explain the difference; Unexplained is what is left of it.
+type ReconciliationReport struct {
+	Account               uint
+	Date                  time.Time
+	StatementBalance      Money
+	LedgerBalance         Money
+	Difference            Money
+	UnmatchedLines        []BankLine
+	UnmatchedTransactions []Transaction
+	Unexplained           Money
+}
+
+// bankAmount is the amount a transaction adds to the balance of the bank
+// account, signed like the amount of a bank line.
+func bankAmount(transaction Transaction, account uint) Money {
+	amount := transaction.Amount
+	if transaction.Debit != (transaction.Account == account) {
+		amount = -amount
+	}
+	return amount
+}
+
+// daysBetween is the number of whole days between two dates.
+func daysBetween(a time.Time, b time.Time) int {
+	days := int(a.Sub(b) / (24 * time.Hour))
+	if days < 0 {
+		return -days
+	}
+	return days
+}
+
+func queryBankLines(database querier, query string, args ...any) ([]BankLine, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []BankLine
+	for rows.Next() {
+		line, err := scanBankLine(rows)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func queryTransactions(database querier, query string, args ...any) ([]Transaction, error) {
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
+
+// unmatched returns the bank lines and the posted transactions of the bank
+// account that are not matched yet.
+func unmatched(database querier, ledger uint, account int) ([]BankLine, []Transaction, error) {
+	lines, err := queryBankLines(database, "SELECT "+bankLineColumns+" FROM bank_lines WHERE ledger_id = $1 AND account = $2 AND NOT EXISTS (SELECT 1 FROM bank_match_lines ml WHERE ml.bank_line_id = bank_lines.id) ORDER BY booking_date, id", ledger, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transactions, err := queryTransactions(database, "SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND status = 'posted' AND (account = $2 OR offset_account = $2) AND NOT EXISTS (SELECT 1 FROM bank_match_transactions mt WHERE mt.transaction_id = transactions.id) ORDER BY date, id", ledger, account)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lines, transactions, nil
+}
+
+// outstanding returns the bank lines and the posted transactions of the bank
+// account up to the date that are not matched with anything up to the date.
+func outstanding(database querier, ledger uint, account int, date time.Time) ([]BankLine, []Transaction, error) {
+	lines, err := queryBankLines(database, "SELECT "+bankLineColumns+" FROM bank_lines WHERE ledger_id = $1 AND account = $2 AND booking_date <= $3 AND NOT EXISTS (SELECT 1 FROM bank_match_lines ml WHERE ml.bank_line_id = bank_lines.id AND NOT EXISTS (SELECT 1 FROM bank_match_transactions mt JOIN transactions t ON t.id = mt.transaction_id WHERE mt.match_id = ml.match_id AND t.date >= $4)) ORDER BY booking_date, id", ledger, account, day(date), endOfDay(date))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transactions, err := queryTransactions(database, "SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND status = 'posted' AND (account = $2 OR offset_account = $2) AND date < $3 AND NOT EXISTS (SELECT 1 FROM bank_match_transactions mt WHERE mt.transaction_id = transactions.id AND NOT EXISTS (SELECT 1 FROM bank_match_lines ml JOIN bank_lines l ON l.id = ml.bank_line_id WHERE ml.match_id = mt.match_id AND l.booking_date > $4)) ORDER BY date, id", ledger, account, endOfDay(date), day(date))
+	if err != nil {
+		return nil, nil, err
+	}
+	return lines, transactions, nil
+}
+
+// referenceMatches reports whether the description of a transaction names
+// the reference of a bank line, or the transaction was booked from it.
+func referenceMatches(line BankLine, transaction Transaction) bool {
+	if line.Transaction == transaction.ID {
+		return true
+	}
+	return line.Reference != "" && strings.Contains(strings.ToLower(transaction.Description), strings.ToLower(line.Reference))
+}
+
+// GetMatchSuggestions suggests matches for the open bank lines and
+// transactions of a bank account whose dates are at most window days apart.
+// Amounts have to agree. Pairs of one line and one transaction come first,
+// preferring transactions booked from the line or naming its reference, then
+// the closest date; then lines made up of two or three transactions, and
+// transactions made up of two or three lines. Every line and transaction is
+// suggested once at most.
+func GetMatchSuggestions(database *sql.DB, scope Scope, account int, window int) ([]MatchSuggestion, error) {
+	exists, err := existAccount(database, scope.Ledger, account)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrAccountNotExists
+	}
+
+	lines, transactions, err := unmatched(database, scope.Ledger, account)
+	if err != nil {
+		return nil, err
+	}
+
+	usedLines := make(map[uint]bool)
+	usedTransactions := make(map[uint]bool)
+	var suggestions []MatchSuggestion
+
+	for _, line := range lines {
+		best := -1
+		bestReference := false
+		for i, transaction := range transactions {
+			if usedTransactions[transaction.ID] || bankAmount(transaction, uint(account)) != line.Amount {
+				continue
+			}
+
+			reference := referenceMatches(line, transaction)
+			days := daysBetween(line.BookingDate, transaction.Date)
+			if days > window && line.Transaction != transaction.ID {
+				continue
+			}
+
+			if best < 0 || (reference && !bestReference) || (reference == bestReference && days < daysBetween(line.BookingDate, transactions[best].Date)) {
+				best = i
+				bestReference = reference
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		transaction := transactions[best]
+		reason := "same amount and date"
+		switch {
+		case line.Transaction == transaction.ID:
+			reason = "booked from the bank line"
+		case bestReference:
+			reason = "same amount and reference"
+		}
+
+		usedLines[line.ID] = true
+		usedTransactions[transaction.ID] = true
+		suggestions = append(suggestions, MatchSuggestion{
+			Lines:        []uint{line.ID},
+			Transactions: []uint{transaction.ID},
+			Amount:       line.Amount,
+			Days:         daysBetween(line.BookingDate, transaction.Date),
+			Reason:       reason,
+		})
+	}
+
+	// one line paid in several transactions
+	for _, line := range lines {
+		if usedLines[line.ID] {
+			continue
+		}
+
+		var candidates []Transaction
+		var amounts []Money
+		for _, transaction := range transactions {
+			amount := bankAmount(transaction, uint(account))
+			if usedTransactions[transaction.ID] || daysBetween(line.BookingDate, transaction.Date) > window || !sameSide(amount, line.Amount) {
+				continue
+			}
+			candidates = append(candidates, transaction)
+			amounts = append(amounts, amount)
+			if len(candidates) == maxSplitCandidates {
+				break
+			}
+		}
+
+		subset := findSubset(amounts, line.Amount)
+		if subset == nil {
+			continue
+		}
+
+		suggestion := MatchSuggestion{Lines: []uint{line.ID}, Amount: line.Amount, Reason: "sum of transactions"}
+		for _, i := range subset {
+			usedTransactions[candidates[i].ID] = true
+			suggestion.Transactions = append(suggestion.Transactions, candidates[i].ID)
+			suggestion.Days = max(suggestion.Days, daysBetween(line.BookingDate, candidates[i].Date))
+		}
+		usedLines[line.ID] = true
+		suggestions = append(suggestions, suggestion)
+	}
+
+	// several lines booked as one transaction
+	for _, transaction := range transactions {
+		if usedTransactions[transaction.ID] {
+			continue
+		}
+
+		target := bankAmount(transaction, uint(account))
+		var candidates []BankLine
+		var amounts []Money
+		for _, line := range lines {
+			if usedLines[line.ID] || daysBetween(line.BookingDate, transaction.Date) > window || !sameSide(line.Amount, target) {
+				continue
+			}
+			candidates = append(candidates, line)
+			amounts = append(amounts, line.Amount)
+			if len(candidates) == maxSplitCandidates {
+				break
+			}
+		}
+
+		subset := findSubset(amounts, target)
+		if subset == nil {
+			continue
+		}
+
+		suggestion := MatchSuggestion{Transactions: []uint{transaction.ID}, Amount: target, Reason: "sum of bank lines"}
+		for _, i := range subset {
+			usedLines[candidates[i].ID] = true
+			suggestion.Lines = append(suggestion.Lines, candidates[i].ID)
+			suggestion.Days = max(suggestion.Days, daysBetween(candidates[i].BookingDate, transaction.Date))
+		}
+		usedTransactions[transaction.ID] = true
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return suggestions, nil
+}
+
+func sameSide(amount Money, target Money) bool {
+	return (amount > 0) == (target > 0) && amount != 0
+}
+
+// findSubset returns the indexes of two or three amounts that add up to the
+// target, or nil.
+func findSubset(amounts []Money, target Money) []int {
+	for i := range amounts {
+		for j := i + 1; j < len(amounts); j++ {
+			if amounts[i]+amounts[j] == target {
+				return []int{i, j}
+			}
+		}
+	}
+
+	for i := range amounts {
+		for j := i + 1; j < len(amounts); j++ {
+			for k := j + 1; k < len(amounts); k++ {
+				if amounts[i]+amounts[j]+amounts[k] == target {
+					return []int{i, j, k}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// uniqueIDs drops ids that are listed twice and sorts the rest.
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool)
+	var unique []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i] < unique[j] })
+	return unique
+}
+
+// ConfirmMatch matches bank lines of the bank account with posted
+// transactions on it. Both sides have to add up to the same amount, and
+// none of them may be matched already.
+func ConfirmMatch(database *sql.DB, scope Scope, account uint, lines []uint, transactions []uint) (uint, error) {
+	lines = uniqueIDs(lines)
+	transactions = uniqueIDs(transactions)
+	if len(lines) == 0 || len(transactions) == 0 {
+		return 0, ErrMatchEmpty
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	err = checkPostable(sqlAccounts{tx}, scope.Ledger, account)
+	if err != nil {
+		return 0, err
+	}
+
+	dialect := dialectOf(database)
+	var total Money
+	for _, id := range lines {
+		var amount Money
+		var matched bool
+		err = tx.QueryRow("SELECT amount, EXISTS (SELECT 1 FROM bank_match_lines WHERE bank_line_id = bank_lines.id) FROM bank_lines WHERE ledger_id = $1 AND account = $2 AND id = $3"+dialect.forUpdate, scope.Ledger, account, id).Scan(&amount, &matched)
+		if err == sql.ErrNoRows {
+			return 0, ErrBankLineNotExists
+		}
+		if err != nil {
+			return 0, err
+		}
+		if matched {
+			return 0, ErrAlreadyMatched
+		}
+		total += amount
+	}
+
+	for _, id := range transactions {
+		transaction, err := scanTransaction(tx.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND id = $2"+dialect.forUpdate, scope.Ledger, id))
+		if err == sql.ErrNoRows {
+			return 0, ErrTransactionNotExists
+		}
+		if err != nil {
+			return 0, err
+		}
+		if transaction.Status != StatusPosted || (transaction.Account != account && transaction.OffsetAccount != account) {
+			return 0, ErrMatchTransaction
+		}
+
+		var matched bool
+		err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM bank_match_transactions WHERE transaction_id = $1)", id).Scan(&matched)
+		if err != nil {
+			return 0, err
+		}
+		if matched {
+			return 0, ErrAlreadyMatched
+		}
+		total -= bankAmount(transaction, account)
+	}
+
+	if total != 0 {
+		return 0, ErrMatchAmount
+	}
+
+	var matchID uint
+	err = tx.QueryRow("INSERT INTO bank_matches (ledger_id, account, user_id, created_at) VALUES ($1, $2, $3, $4) RETURNING id", scope.Ledger, account, scope.User, time.Now().UTC()).Scan(&matchID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range lines {
+		_, err = tx.Exec("INSERT INTO bank_match_lines (match_id, bank_line_id) VALUES ($1, $2)", matchID, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, id := range transactions {
+		_, err = tx.Exec("INSERT INTO bank_match_transactions (match_id, transaction_id) VALUES ($1, $2)", matchID, id)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return matchID, nil
+}
+
+// DeleteMatch undoes a match of the bank account; its lines and
+// transactions are open again.
+func DeleteMatch(database *sql.DB, scope Scope, account int, id int) error {
+	result, err := database.Exec("DELETE FROM bank_matches WHERE ledger_id = $1 AND account = $2 AND id = $3", scope.Ledger, account, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrMatchNotExists
+	}
+	return nil
+}
+
+// GetMatches returns the matches of a bank account, the latest first.
+func GetMatches(database *sql.DB, scope Scope, account int) ([]Match, error) {
+	rows, err := database.Query("SELECT id, account, user_id, created_at, CAST((SELECT COALESCE(SUM(l.amount), 0) FROM bank_match_lines ml JOIN bank_lines l ON l.id = ml.bank_line_id WHERE ml.match_id = bank_matches.id) AS bigint) FROM bank_matches WHERE ledger_id = $1 AND account = $2 ORDER BY id DESC", scope.Ledger, account)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	index := make(map[uint]int)
+	for rows.Next() {
+		var match Match
+		err := rows.Scan(&match.ID, &match.Account, &match.User, &match.Time, &match.Amount)
+		if err != nil {
+			return nil, err
+		}
+		index[match.ID] = len(matches)
+		matches = append(matches, match)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := database.Query("SELECT ml.match_id, ml.bank_line_id, 'line' FROM bank_match_lines ml JOIN bank_matches m ON m.id = ml.match_id WHERE m.ledger_id = $1 AND m.account = $2 UNION ALL SELECT mt.match_id, mt.transaction_id, 'transaction' FROM bank_match_transactions mt JOIN bank_matches m ON m.id = mt.match_id WHERE m.ledger_id = $1 AND m.account = $2 ORDER BY 1, 2", scope.Ledger, account)
+	if err != nil {
+		return nil, err
+	}
+	defer members.Close()
+
+	for members.Next() {
+		var match, id uint
+		var kind string
+		err := members.Scan(&match, &id, &kind)
+		if err != nil {
+			return nil, err
+		}
+
+		i, ok := index[match]
+		if !ok {
+			continue
+		}
+		if kind == "line" {
+			matches[i].Lines = append(matches[i].Lines, id)
+		} else {
+			matches[i].Transactions = append(matches[i].Transactions, id)
+		}
+	}
+
+	err = members.Err()
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// GetReconciliationReport reconciles the balance of a bank statement on the
+// statement date with the balance of the bank account. Journal entries on
+// the bank account cannot be matched, so they show up as unexplained.
+func GetReconciliationReport(database *sql.DB, scope Scope, account int, date time.Time, statementBalance Money) (ReconciliationReport, error) {
+	report := ReconciliationReport{Account: uint(account), Date: date, StatementBalance: statementBalance}
+
+	balance, err := GetBalance(database, scope, account, date)
+	if err != nil {
+		return report, err
+	}
+	report.LedgerBalance = balance.Balance
+	report.Difference = report.StatementBalance - report.LedgerBalance
+
+	report.UnmatchedLines, report.UnmatchedTransactions, err = outstanding(database, scope.Ledger, account, date)
+	if err != nil {
+		return report, err
+	}
+
+	report.Unexplained = report.Difference
+	for _, line := range report.UnmatchedLines {
+		report.Unexplained -= line.Amount
+	}
+	for _, transaction := range report.UnmatchedTransactions {
+		report.Unexplained += bankAmount(transaction, uint(account))
+	}
+	return report, nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The books of the reconciliation tests on the bank account 1200: an invoice
+// paid once but booked twice, one payment booked in two parts and two
+// payments booked as one a day before the bank takes them.
+var (
+	reconciliationTransactions = []Transaction{
+		{Amount: 119000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Description: "Invoice RE-2024-017", Status: StatusPosted},
+		{Amount: 119000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Description: "Invoice 2024-018", Status: StatusPosted},
+		{Amount: 3000, Debit: false, Account: 1200, OffsetAccount: 4900, Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Description: "Paper", Status: StatusPosted},
+		{Amount: 2000, Debit: false, Account: 1200, OffsetAccount: 4900, Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Description: "Toner", Status: StatusPosted},
+		{Amount: 1000, Debit: true, Account: 4900, OffsetAccount: 1200, Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Description: "Postage", Status: StatusPosted},
+	}
+
+	reconciliationLines = []BankLine{
+		{BookingDate: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Amount: 119000, Counterparty: "ACME GmbH", Reference: "RE-2024-017"},
+		{BookingDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Amount: -5000, Counterparty: "Office supplies"},
+		{BookingDate: time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC), Amount: -700, Counterparty: "Post"},
+		{BookingDate: time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC), Amount: -300, Counterparty: "Post"},
+	}
+)
+
+func TestGetMatchSuggestions(t *testing.T) {
+	transactions := insertBooks(t, bankAccounts, reconciliationTransactions, nil)
+	staged, err := StageBankLines(db, testScope, 1200, "camt053", reconciliationLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := staged.Imported
+
+	suggestions, err := GetMatchSuggestions(db, testScope, 1200, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Len(t, suggestions, 3) {
+		return
+	}
+
+	// the reference wins over the closer date
+	assert.Equal(t, []uint{lines[0].ID}, suggestions[0].Lines)
+	assert.Equal(t, []uint{transactions[0]}, suggestions[0].Transactions)
+	assert.Equal(t, "same amount and reference", suggestions[0].Reason)
+	assert.Equal(t, 3, suggestions[0].Days)
+
+	assert.Equal(t, []uint{lines[1].ID}, suggestions[1].Lines)
+	assert.Equal(t, []uint{transactions[2], transactions[3]}, suggestions[1].Transactions)
+
+	assert.Equal(t, []uint{lines[2].ID, lines[3].ID}, suggestions[2].Lines)
+	assert.Equal(t, []uint{transactions[4]}, suggestions[2].Transactions)
+	assert.Equal(t, Money(-1000), suggestions[2].Amount)
+
+	// a narrow window leaves the invoice three days apart out
+	suggestions, err = GetMatchSuggestions(db, testScope, 1200, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []uint{transactions[1]}, suggestions[0].Transactions)
+}
+
+func TestConfirmMatch(t *testing.T) {
+	transactions := insertBooks(t, bankAccounts, reconciliationTransactions, nil)
+	staged, err := StageBankLines(db, testScope, 1200, "camt053", reconciliationLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := staged.Imported
+
+	_, err = ConfirmMatch(db, testScope, 1200, []uint{lines[1].ID}, []uint{transactions[2]})
+	assert.ErrorIs(t, err, ErrMatchAmount)
+
+	_, err = ConfirmMatch(db, testScope, 1200, []uint{lines[1].ID}, nil)
+	assert.ErrorIs(t, err, ErrMatchEmpty)
+
+	id, err := ConfirmMatch(db, testScope, 1200, []uint{lines[1].ID}, []uint{transactions[2], transactions[3]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ConfirmMatch(db, testScope, 1200, []uint{lines[2].ID, lines[3].ID}, []uint{transactions[4], transactions[2]})
+	assert.ErrorIs(t, err, ErrAlreadyMatched)
+
+	matches, err := GetMatches(db, testScope, 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, id, matches[0].ID)
+		assert.Equal(t, Money(-5000), matches[0].Amount)
+		assert.Equal(t, []uint{lines[1].ID}, matches[0].Lines)
+		assert.Equal(t, []uint{transactions[2], transactions[3]}, matches[0].Transactions)
+		assert.Equal(t, testScope.User, matches[0].User)
+	}
+
+	err = DeleteMatch(db, testScope, 1200, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DeleteMatch(db, testScope, 1200, int(id))
+	assert.ErrorIs(t, err, ErrMatchNotExists)
+
+	// undone matches free their lines and transactions
+	_, err = ConfirmMatch(db, testScope, 1200, []uint{lines[1].ID}, []uint{transactions[2], transactions[3]})
+	assert.NoError(t, err)
+}
+
+func TestConfirmMatchDraft(t *testing.T) {
+	insertBooks(t, bankAccounts, reconciliationTransactions, nil)
+	staged, err := StageBankLines(db, testScope, 1200, "camt053", reconciliationLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	draft, err := NewTransaction(db, testScope, Transaction{Amount: 119000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ConfirmMatch(db, testScope, 1200, []uint{staged.Imported[0].ID}, []uint{draft})
+	assert.ErrorIs(t, err, ErrMatchTransaction)
+}
+
+func TestReconciliationReport(t *testing.T) {
+	transactions := insertBooks(t, bankAccounts, reconciliationTransactions, nil)
+	staged, err := StageBankLines(db, testScope, 1200, "camt053", reconciliationLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := staged.Imported
+
+	for _, match := range [][2][]uint{
+		{{lines[0].ID}, {transactions[0]}},
+		{{lines[1].ID}, {transactions[2], transactions[3]}},
+		{{lines[2].ID, lines[3].ID}, {transactions[4]}},
+	} {
+		_, err := ConfirmMatch(db, testScope, 1200, match[0], match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := GetReconciliationReport(db, testScope, 1200, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), 113000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Money(232000), report.LedgerBalance)
+	assert.Equal(t, Money(-119000), report.Difference)
+	assert.Empty(t, report.UnmatchedLines)
+	if assert.Len(t, report.UnmatchedTransactions, 1) {
+		assert.Equal(t, transactions[1], report.UnmatchedTransactions[0].ID)
+	}
+	assert.Equal(t, Money(0), report.Unexplained)
+
+	// on the 5th the postage is booked but not yet on the statement
+	report, err = GetReconciliationReport(db, testScope, 1200, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), 114000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Money(-118000), report.Difference)
+	assert.Empty(t, report.UnmatchedLines)
+	if assert.Len(t, report.UnmatchedTransactions, 2) {
+		assert.Equal(t, transactions[4], report.UnmatchedTransactions[1].ID)
+	}
+	assert.Equal(t, Money(0), report.Unexplained)
+}
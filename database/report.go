@@ -0,0 +1,251 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+type TrialBalanceLine struct {
+	Account uint
+	Name    string
+	Kind    string
+	Parent  uint
+	Group   bool
+	Opening Money
+	Debit   Money
+	Credit  Money
+	Closing Money
+}
+
+// TrialBalance lists every account with its balance brought forward, the
+// debits and credits of the period and its closing balance. Balances are
+// debit minus credit. Balanced reports whether the period debits equal the
+// period credits and the opening and closing balances both net to zero.
+type TrialBalance struct {
+	From         time.Time
+	To           time.Time
+	Lines        []TrialBalanceLine
+	TotalOpening Money
+	TotalDebit   Money
+	TotalCredit  Money
+	TotalClosing Money
+	Balanced     bool
+}
+
+// GetTrialBalance builds the trial balance for the period from to to, both
+// inclusive. A zero from starts at the first booking, a zero to includes
+// every booking after from.
+func GetTrialBalance(database *sql.DB, scope Scope, from time.Time, to time.Time) (TrialBalance, error) {
+	return buildTrialBalance(database, scope, from, to, false)
+}
+
+// buildTrialBalance builds the trial balance, leaving out closing entries when
+// excludeClosing is set.
+func buildTrialBalance(database querier, scope Scope, from time.Time, to time.Time, excludeClosing bool) (TrialBalance, error) {
+	trialBalance := TrialBalance{From: from, To: to}
+
+	query := "SELECT a.id, a.name, a.kind, a.parent, a.is_group, CAST(COALESCE(SUM(CASE WHEN p.date < $1 THEN p.debit_amount - p.credit_amount ELSE 0 END), 0) AS bigint), CAST(COALESCE(SUM(CASE WHEN p.date >= $1 THEN p.debit_amount ELSE 0 END), 0) AS bigint), CAST(COALESCE(SUM(CASE WHEN p.date >= $1 THEN p.credit_amount ELSE 0 END), 0) AS bigint) FROM accounts a LEFT JOIN (" + postings + ") p ON p.ledger_id = a.ledger_id AND p.account = a.id"
+	args := []any{from, scope.Ledger}
+	if !to.IsZero() {
+		args = append(args, endOfDay(to))
+		query += " AND p.date < $" + strconv.Itoa(len(args))
+	}
+	if excludeClosing {
+		args = append(args, EntryClosing)
+		query += " AND p.entry_type <> $" + strconv.Itoa(len(args))
+	}
+	query += " WHERE a.ledger_id = $2 GROUP BY a.id, a.name, a.kind, a.parent, a.is_group ORDER BY a.id"
+
+	rows, err := database.Query(query, args...)
+	if err != nil {
+		return trialBalance, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line TrialBalanceLine
+		var parent sql.NullInt64
+		err := rows.Scan(&line.Account, &line.Name, &line.Kind, &parent, &line.Group, &line.Opening, &line.Debit, &line.Credit)
+		if err != nil {
+			return trialBalance, err
+		}
+		line.Parent = uint(parent.Int64)
+		line.Closing = line.Opening + line.Debit - line.Credit
+
+		trialBalance.TotalOpening += line.Opening
+		trialBalance.TotalDebit += line.Debit
+		trialBalance.TotalCredit += line.Credit
+		trialBalance.TotalClosing += line.Closing
+		trialBalance.Lines = append(trialBalance.Lines, line)
+	}
+	err = rows.Err()
+	if err != nil {
+		return trialBalance, err
+	}
+
+	trialBalance.Balanced = trialBalance.TotalDebit == trialBalance.TotalCredit && trialBalance.TotalOpening == 0 && trialBalance.TotalClosing == 0
+	return trialBalance, nil
+}
+
+// StatementLine is an account on a financial statement. Amount is signed by
+// the account's normal side, so a positive amount is a regular balance. Group
+// accounts carry the sum of their children.
+type StatementLine struct {
+	Account  uint
+	Name     string
+	Amount   Money
+	Children []StatementLine
+}
+
+type StatementSection struct {
+	Kind  string
+	Lines []StatementLine
+	Total Money
+}
+
+// BalanceSheet shows assets, liabilities and equity as of a date. The net
+// income not yet carried into an equity account is shown as its own equity
+// line, so assets equal liabilities plus equity.
+type BalanceSheet struct {
+	AsOf                      time.Time
+	Assets                    StatementSection
+	Liabilities               StatementSection
+	Equity                    StatementSection
+	NetIncome                 Money
+	TotalAssets               Money
+	TotalLiabilitiesAndEquity Money
+	Balanced                  bool
+}
+
+type ProfitAndLoss struct {
+	From      time.Time
+	To        time.Time
+	Revenue   StatementSection
+	Expenses  StatementSection
+	NetIncome Money
+}
+
+// normalAmount signs a debit minus credit balance by the normal side of the
+// account kind.
+func normalAmount(kind string, balance Money) Money {
+	if DebitNormal(kind) {
+		return balance
+	}
+	return -balance
+}
+
+// statementSections groups accounts with a non-zero amount by kind and nests
+// them under their group accounts. A group account shows up in every section
+// one of its descendants belongs to, carrying only the amounts of that kind.
+func statementSections(lines []TrialBalanceLine, amount func(TrialBalanceLine) Money) map[string]*StatementSection {
+	byID := make(map[uint]TrialBalanceLine)
+	for _, line := range lines {
+		byID[line.Account] = line
+	}
+
+	sections := make(map[string]*StatementSection)
+	for _, kind := range Kinds {
+		section := &StatementSection{Kind: kind}
+		sections[kind] = section
+
+		// pick the accounts of this kind and every group above them
+		included := make(map[uint]bool)
+		values := make(map[uint]Money)
+		for _, line := range lines {
+			if line.Group || line.Kind != kind {
+				continue
+			}
+
+			value := normalAmount(kind, amount(line))
+			if value == 0 {
+				continue
+			}
+
+			values[line.Account] = value
+			for id := line.Account; id != 0 && !included[id]; id = byID[id].Parent {
+				if _, ok := byID[id]; !ok {
+					break
+				}
+				included[id] = true
+			}
+		}
+
+		children := make(map[uint][]uint)
+		var roots []uint
+		for _, line := range lines {
+			if !included[line.Account] {
+				continue
+			}
+			if line.Parent == 0 || !included[line.Parent] {
+				roots = append(roots, line.Account)
+				continue
+			}
+			children[line.Parent] = append(children[line.Parent], line.Account)
+		}
+
+		var build func(id uint) StatementLine
+		build = func(id uint) StatementLine {
+			statementLine := StatementLine{Account: id, Name: byID[id].Name, Amount: values[id]}
+			for _, child := range children[id] {
+				childLine := build(child)
+				statementLine.Amount += childLine.Amount
+				statementLine.Children = append(statementLine.Children, childLine)
+			}
+			return statementLine
+		}
+
+		for _, root := range roots {
+			statementLine := build(root)
+			section.Lines = append(section.Lines, statementLine)
+			section.Total += statementLine.Amount
+		}
+	}
+
+	return sections
+}
+
+func GetBalanceSheet(database *sql.DB, scope Scope, asOf time.Time) (BalanceSheet, error) {
+	balanceSheet := BalanceSheet{AsOf: asOf}
+
+	trialBalance, err := GetTrialBalance(database, scope, time.Time{}, asOf)
+	if err != nil {
+		return balanceSheet, err
+	}
+
+	sections := statementSections(trialBalance.Lines, func(line TrialBalanceLine) Money { return line.Closing })
+
+	balanceSheet.Assets = *sections[KindAsset]
+	balanceSheet.Liabilities = *sections[KindLiability]
+	balanceSheet.Equity = *sections[KindEquity]
+	balanceSheet.NetIncome = sections[KindRevenue].Total - sections[KindExpense].Total
+
+	if balanceSheet.NetIncome != 0 {
+		balanceSheet.Equity.Lines = append(balanceSheet.Equity.Lines, StatementLine{Name: "Net income", Amount: balanceSheet.NetIncome})
+		balanceSheet.Equity.Total += balanceSheet.NetIncome
+	}
+
+	balanceSheet.TotalAssets = balanceSheet.Assets.Total
+	balanceSheet.TotalLiabilitiesAndEquity = balanceSheet.Liabilities.Total + balanceSheet.Equity.Total
+	balanceSheet.Balanced = balanceSheet.TotalAssets == balanceSheet.TotalLiabilitiesAndEquity
+	return balanceSheet, nil
+}
+
+// GetProfitAndLoss shows revenue and expenses booked between from and to,
+// both inclusive. Closing entries are left out, so a closed year still shows
+// its result.
+func GetProfitAndLoss(database *sql.DB, scope Scope, from time.Time, to time.Time) (ProfitAndLoss, error) {
+	profitAndLoss := ProfitAndLoss{From: from, To: to}
+
+	trialBalance, err := buildTrialBalance(database, scope, from, to, true)
+	if err != nil {
+		return profitAndLoss, err
+	}
+
+	sections := statementSections(trialBalance.Lines, func(line TrialBalanceLine) Money { return line.Debit - line.Credit })
+
+	profitAndLoss.Revenue = *sections[KindRevenue]
+	profitAndLoss.Expenses = *sections[KindExpense]
+	profitAndLoss.NetIncome = profitAndLoss.Revenue.Total - profitAndLoss.Expenses.Total
+	return profitAndLoss, nil
+}
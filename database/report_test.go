@@ -0,0 +1,83 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTrialBalance(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	from, _ := time.Parse("2006-01-02", "2024-04-01")
+	to, _ := time.Parse("2006-01-02", "2024-04-30")
+	trialBalance, err := GetTrialBalance(db, testScope, from, to)
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.True(t, trialBalance.Balanced)
+	assert.Len(t, trialBalance.Lines, 4)
+	assert.Equal(t, Money(11900), trialBalance.TotalDebit)
+	assert.Equal(t, Money(11900), trialBalance.TotalCredit)
+
+	bank := trialBalance.Lines[0]
+	assert.Equal(t, uint(1200), bank.Account)
+	assert.Equal(t, Money(50000), bank.Opening)
+	assert.Equal(t, Money(0), bank.Debit)
+	assert.Equal(t, Money(11900), bank.Credit)
+	assert.Equal(t, Money(38100), bank.Closing)
+}
+
+func TestGetTrialBalanceWithoutBookings(t *testing.T) {
+	cleanTables()
+	_, err := db.Exec("INSERT INTO accounts (ledger_id, id, name, kind) VALUES ($1, $2, $3, $4)", testScope.Ledger, 1000, "Cash", "asset")
+	if err != nil {
+		t.Error(err)
+	}
+
+	trialBalance, err := GetTrialBalance(db, testScope, time.Time{}, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.True(t, trialBalance.Balanced)
+	assert.Len(t, trialBalance.Lines, 1)
+	assert.Equal(t, Money(0), trialBalance.Lines[0].Closing)
+}
+
+func TestGetBalanceSheet(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	balanceSheet, err := GetBalanceSheet(db, testScope, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.True(t, balanceSheet.Balanced)
+	assert.Equal(t, Money(40000), balanceSheet.TotalAssets)
+	assert.Equal(t, Money(40000), balanceSheet.NetIncome)
+	assert.Equal(t, Money(40000), balanceSheet.Equity.Total)
+}
+
+func TestGetProfitAndLoss(t *testing.T) {
+	insertBooks(t, balanceAccounts, balanceTransactions, balanceEntries)
+
+	from, _ := time.Parse("2006-01-02", "2024-04-01")
+	to, _ := time.Parse("2006-01-02", "2024-04-30")
+	profitAndLoss, err := GetProfitAndLoss(db, testScope, from, to)
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.Equal(t, Money(0), profitAndLoss.Revenue.Total)
+	assert.Equal(t, Money(10000), profitAndLoss.Expenses.Total)
+	assert.Equal(t, Money(-10000), profitAndLoss.NetIncome)
+}
+
+func TestNewAccountInvalidKind(t *testing.T) {
+	err := NewAccount(db, testScope, Account{ID: 50, Name: "Test Account 50", Kind: "1000.00"})
+
+	assert.ErrorIs(t, err, ErrInvalidKind)
+}
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTransactionReversed = errors.New("transaction has already been reversed; correct its replacement instead")
+	ErrTransactionReversal = errors.New("reversals cannot be changed")
+)
+
+const transactionColumns = "id, amount, debit, offset_account, account, date, description, tax_code, status, reverses, replaces, prepared_by"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTransaction reads a row selected with transactionColumns.
+func scanTransaction(row rowScanner) (Transaction, error) {
+	var transaction Transaction
+	var reverses, replaces sql.NullInt64
+	var preparedBy sql.NullString
+	err := row.Scan(&transaction.ID, &transaction.Amount, &transaction.Debit, &transaction.OffsetAccount, &transaction.Account, &transaction.Date, &transaction.Description, &transaction.TaxCode, &transaction.Status, &reverses, &replaces, &preparedBy)
+	if err != nil {
+		return transaction, err
+	}
+	transaction.Reverses = uint(reverses.Int64)
+	transaction.Replaces = uint(replaces.Int64)
+	transaction.PreparedBy = preparedBy.String
+	return transaction, nil
+}
+
+func validateTransaction(transaction Transaction) error {
+	if transaction.OffsetAccount == transaction.Account {
+		return errors.New("offset account and account cannot be the same")
+	}
+
+	if transaction.Amount == 0 {
+		return errors.New("amount cannot be 0")
+	}
+
+	if transaction.Date.IsZero() {
+		return errors.New("date is required")
+	}
+	return nil
+}
+
+// lockTransaction loads a transaction for a correction and locks its row
+// until the end of the database transaction. Reversals and transactions that
+// have already been reversed cannot be corrected.
+func lockTransaction(database querier, dialect dialect, scope Scope, id int) (Transaction, error) {
+	transaction, err := scanTransaction(database.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND id = $2"+dialect.forUpdate, scope.Ledger, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return transaction, ErrTransactionNotExists
+		}
+		return transaction, err
+	}
+
+	if transaction.Reverses != 0 {
+		return transaction, ErrTransactionReversal
+	}
+
+	var reversed bool
+	err = database.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE ledger_id = $1 AND reverses = $2)", scope.Ledger, id).Scan(&reversed)
+	if err != nil {
+		return transaction, err
+	}
+	if reversed {
+		return transaction, ErrTransactionReversed
+	}
+	return transaction, nil
+}
+
+// reverseTransaction posts the mirror image of a transaction, so that both
+// cancel out in every balance. Reversals only undo what was approved before,
+// so they are posted without another approval.
+func reverseTransaction(database querier, scope Scope, transaction Transaction) (uint, error) {
+	date, err := reversalDate(database, scope, transaction.Date)
+	if err != nil {
+		return 0, err
+	}
+
+	reversal := reversalOf(scope, transaction, date)
+	err = database.QueryRow("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date, description, tax_code, status, reverses, prepared_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id", scope.Ledger, reversal.Amount, reversal.Debit, reversal.OffsetAccount, reversal.Account, reversal.Date, reversal.Description, reversal.TaxCode, reversal.Status, reversal.Reverses, reversal.PreparedBy).Scan(&reversal.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = recordAudit(database, scope.Ledger, scope.User, EntityTransaction, reversal.ID, ActionCreate, nil, reversal)
+	if err != nil {
+		return 0, err
+	}
+	return reversal.ID, nil
+}
+
+// reversalDate returns the day a reversal of a booking made on date is
+// posted on. That is the day of the booking while its period is open, so both
+// show in the same period; once the period is closed, the reversal goes into
+// the period of today instead, which has to be open.
+func reversalDate(database querier, scope Scope, date time.Time) (time.Time, error) {
+	err := checkPeriods(database, scope, date)
+	if err == nil || !(errors.Is(err, ErrPeriodLocked) || errors.Is(err, ErrPeriodClosed)) {
+		return date, err
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	err = checkPeriods(database, scope, today)
+	if err != nil {
+		return date, err
+	}
+	return today, nil
+}
+
+// reversalOf returns the reversal of transaction on date, without an id.
+func reversalOf(scope Scope, transaction Transaction, date time.Time) Transaction {
+	description := fmt.Sprintf("Reversal of transaction %d", transaction.ID)
+	if transaction.Description != "" {
+		description += ": " + transaction.Description
+	}
+
+	return Transaction{Amount: transaction.Amount, Debit: !transaction.Debit, OffsetAccount: transaction.OffsetAccount, Account: transaction.Account, Date: date, Description: description, TaxCode: transaction.TaxCode, Status: StatusPosted, Reverses: transaction.ID, PreparedBy: scope.User}
+}
+
+// GetTransactionHistory returns every version of a booking: the original
+// transaction, its reversals and their replacements, oldest first. Any id
+// from the chain can be given.
+func GetTransactionHistory(database *sql.DB, scope Scope, id int) ([]Transaction, error) {
+	rows, err := database.Query(`WITH RECURSIVE ancestors (id, previous) AS (
+SELECT id, COALESCE(replaces, reverses) FROM transactions WHERE ledger_id = $1 AND id = $2
+UNION SELECT t.id, COALESCE(t.replaces, t.reverses) FROM transactions t JOIN ancestors a ON t.id = a.previous WHERE t.ledger_id = $1
+), chain (id) AS (
+SELECT id FROM ancestors WHERE previous IS NULL
+UNION SELECT t.id FROM transactions t JOIN chain c ON t.replaces = c.id OR t.reverses = c.id WHERE t.ledger_id = $1
+)
+SELECT `+transactionColumns+` FROM transactions WHERE ledger_id = $1 AND id IN (SELECT id FROM chain) ORDER BY id`, scope.Ledger, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []Transaction
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, transaction)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history) == 0 {
+		return nil, ErrTransactionNotExists
+	}
+	return history, nil
+}
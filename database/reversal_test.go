@@ -0,0 +1,106 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The books of the reversal tests: 100.00 posted from the bank to revenue.
+var (
+	reversalAccounts = []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}, {ID: 8400, Name: "Revenue", Kind: KindRevenue}}
+
+	reversalTransactions = []Transaction{
+		{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Description: "Invoice 1", Status: StatusPosted},
+	}
+)
+
+func TestUpdatePostedTransaction(t *testing.T) {
+	id := int(insertBooks(t, reversalAccounts, reversalTransactions, nil)[0])
+
+	replacement, err := UpdateTransaction(db, testScope, Transaction{ID: uint(id), Amount: 12000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Description: "Invoice 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, uint(id), replacement)
+
+	// the original is left untouched
+	original, err := GetTransaction(db, testScope, id)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(10000), original.Amount)
+
+	// the replacement waits for approval, so nothing is booked until then
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(0), balance.Balance)
+
+	history, err := GetTransactionHistory(db, testScope, int(replacement))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, history, 3) {
+		assert.Equal(t, uint(id), history[0].ID)
+		assert.Equal(t, uint(id), history[1].Reverses)
+		assert.False(t, history[1].Debit)
+		assert.Equal(t, uint(id), history[2].Replaces)
+		assert.Equal(t, StatusDraft, history[2].Status)
+	}
+
+	// only the latest version can be corrected
+	_, err = UpdateTransaction(db, testScope, Transaction{ID: uint(id), Amount: 13000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)})
+	assert.ErrorIs(t, err, ErrTransactionReversed)
+
+	_, err = DeleteTransaction(db, testScope, int(history[1].ID))
+	assert.ErrorIs(t, err, ErrTransactionReversal)
+}
+
+func TestDeletePostedTransaction(t *testing.T) {
+	id := int(insertBooks(t, reversalAccounts, reversalTransactions, nil)[0])
+
+	reversal, err := DeleteTransaction(db, testScope, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotZero(t, reversal)
+
+	_, err = GetTransaction(db, testScope, id)
+	assert.NoError(t, err)
+
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(0), balance.Balance)
+
+	transactions, err := GetTransactions(db, testScope, 1200, 2024, 3, "")
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Len(t, transactions, 2)
+}
+
+func TestDraftTransactionNotCounted(t *testing.T) {
+	insertBooks(t, reversalAccounts, reversalTransactions, nil)
+
+	_, err := NewTransaction(db, testScope, Transaction{Amount: 5000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Status: StatusDraft})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(10000), balance.Balance)
+}
+
+func TestGetTransactionHistoryNotExists(t *testing.T) {
+	_, err := GetTransactionHistory(db, testScope, 99999)
+
+	assert.ErrorIs(t, err, ErrTransactionNotExists)
+}
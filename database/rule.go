@@ -0,0 +1,433 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrRuleNotExists = errors.New("rule does not exist")
+
+// Fields a rule condition can look at.
+const (
+	FieldCounterparty = "counterparty"
+	FieldIBAN         = "iban"
+	FieldRemittance   = "remittance"
+	FieldReference    = "reference"
+	FieldAmount       = "amount"
+)
+
+// Condition operators. Text is compared without regard to case; between
+// includes both ends.
+const (
+	OperatorEquals     = "equals"
+	OperatorContains   = "contains"
+	OperatorStartsWith = "startsWith"
+	OperatorBetween    = "between"
+)
+
+// RuleCondition is one test a bank line has to pass. Text fields compare
+// with Value. The amount is signed like the amount of a bank line, negative
+// for money going out; equals compares it with Min, between with Min and
+// Max.
+type RuleCondition struct {
+	Field    string
+	Operator string
+	Value    string
+	Min      Money
+	Max      Money
+}
+
+// Rule categorizes bank lines. A rule fires when all of its conditions
+// hold; of several rules the one with the lowest Priority wins. It suggests
+// an offset account, a description and a tax code, which fill in what a
+// booking leaves open. Rules with Assign set book the lines they fire on as
+// drafts right when a statement is imported. Matches counts how often the
+// rule fired.
+type Rule struct {
+	ID            uint
+	Name          string
+	Priority      int
+	Conditions    []RuleCondition
+	OffsetAccount uint
+	Description   string
+	TaxCode       string
+	Assign        bool
+	Matches       int
+	LastMatched   time.Time
+}
+
+// RuleStatistic tells how often a rule fired and what share of all firings
+// of the ledger's rules that was.
+type RuleStatistic struct {
+	Rule        uint
+	Name        string
+	Matches     int
+	Share       float64
+	LastMatched time.Time
+}
+
+func ValidateRule(rule Rule) error {
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if len(rule.Conditions) == 0 {
+		return errors.New("at least one condition is required")
+	}
+
+	if rule.OffsetAccount == 0 && rule.Description == "" && rule.TaxCode == "" {
+		return errors.New("a rule has to set an offset account, a description or a tax code")
+	}
+
+	if rule.Assign && rule.OffsetAccount == 0 {
+		return errors.New("a rule that assigns needs an offset account")
+	}
+
+	for i, condition := range rule.Conditions {
+		err := validateCondition(condition)
+		if err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func validateCondition(condition RuleCondition) error {
+	switch condition.Field {
+	case FieldCounterparty, FieldIBAN, FieldRemittance, FieldReference:
+		if condition.Operator != OperatorEquals && condition.Operator != OperatorContains && condition.Operator != OperatorStartsWith {
+			return fmt.Errorf("invalid operator %q for %s; must be equals, contains or startsWith", condition.Operator, condition.Field)
+		}
+		if condition.Value == "" {
+			return errors.New("value is required")
+		}
+	case FieldAmount:
+		if condition.Operator != OperatorEquals && condition.Operator != OperatorBetween {
+			return fmt.Errorf("invalid operator %q for amount; must be equals or between", condition.Operator)
+		}
+		if condition.Operator == OperatorBetween && condition.Min > condition.Max {
+			return errors.New("min cannot be greater than max")
+		}
+	default:
+		return fmt.Errorf("invalid field %q; must be counterparty, iban, remittance, reference or amount", condition.Field)
+	}
+	return nil
+}
+
+// Fires reports whether all conditions of the rule hold for the line.
+func (rule Rule) Fires(line BankLine) bool {
+	for _, condition := range rule.Conditions {
+		if !condition.matches(line) {
+			return false
+		}
+	}
+	return len(rule.Conditions) > 0
+}
+
+func (condition RuleCondition) matches(line BankLine) bool {
+	var value string
+	switch condition.Field {
+	case FieldAmount:
+		if condition.Operator == OperatorBetween {
+			return line.Amount >= condition.Min && line.Amount <= condition.Max
+		}
+		return line.Amount == condition.Min
+	case FieldCounterparty:
+		value = line.Counterparty
+	case FieldIBAN:
+		// IBANs are printed in groups of four
+		value = strings.ReplaceAll(line.CounterpartyIBAN, " ", "")
+		condition.Value = strings.ReplaceAll(condition.Value, " ", "")
+	case FieldRemittance:
+		value = line.Remittance
+	case FieldReference:
+		value = line.Reference
+	}
+
+	value = strings.ToLower(value)
+	expected := strings.ToLower(condition.Value)
+	switch condition.Operator {
+	case OperatorEquals:
+		return value == expected
+	case OperatorContains:
+		return strings.Contains(value, expected)
+	case OperatorStartsWith:
+		return strings.HasPrefix(value, expected)
+	}
+	return false
+}
+
+// MatchRule returns the first of the rules, which are in priority order,
+// that fires for the line.
+func MatchRule(rules []Rule, line BankLine) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.Fires(line) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Apply fills in the offset account, the description and the tax code of
+// a transaction where they are still empty.
+func (rule Rule) Apply(transaction Transaction) Transaction {
+	if transaction.OffsetAccount == 0 {
+		transaction.OffsetAccount = rule.OffsetAccount
+	}
+	if transaction.Description == "" {
+		transaction.Description = rule.Description
+	}
+	if transaction.TaxCode == "" {
+		transaction.TaxCode = rule.TaxCode
+	}
+	return transaction
+}
+
+// TransactionLine presents a transaction to the rules as a bank line. A
+// transaction only has a description, so it stands in for both the
+// counterparty and the remittance text. Debits on the account are money
+// coming in.
+func TransactionLine(transaction Transaction) BankLine {
+	line := BankLine{
+		Account:      transaction.Account,
+		BookingDate:  transaction.Date,
+		Amount:       transaction.Amount,
+		Counterparty: transaction.Description,
+		Remittance:   transaction.Description,
+	}
+	if !transaction.Debit {
+		line.Amount = -line.Amount
+	}
+	return line
+}
+
+const ruleColumns = "id, name, priority, conditions, offset_account, description, tax_code, assign, matches, last_matched"
+
+func scanRule(row rowScanner) (Rule, error) {
+	var rule Rule
+	var conditions string
+	var offsetAccount sql.NullInt64
+	var lastMatched sql.NullTime
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Priority, &conditions, &offsetAccount, &rule.Description, &rule.TaxCode, &rule.Assign, &rule.Matches, &lastMatched)
+	if err != nil {
+		return rule, err
+	}
+	rule.OffsetAccount = uint(offsetAccount.Int64)
+	rule.LastMatched = lastMatched.Time
+
+	err = json.Unmarshal([]byte(conditions), &rule.Conditions)
+	if err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func nullAccount(account uint) any {
+	if account == 0 {
+		return nil
+	}
+	return account
+}
+
+func NewRule(database *sql.DB, scope Scope, rule Rule) (uint, error) {
+	err := ValidateRule(rule)
+	if err != nil {
+		return 0, err
+	}
+
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if rule.OffsetAccount != 0 {
+		err = checkPostable(sqlAccounts{tx}, scope.Ledger, rule.OffsetAccount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var id uint
+	err = tx.QueryRow("INSERT INTO rules (ledger_id, name, priority, conditions, offset_account, description, tax_code, assign) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id", scope.Ledger, rule.Name, rule.Priority, string(conditions), nullAccount(rule.OffsetAccount), rule.Description, rule.TaxCode, rule.Assign).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateRule changes the definition of a rule; its statistics are kept.
+func UpdateRule(database *sql.DB, scope Scope, rule Rule) error {
+	err := ValidateRule(rule)
+	if err != nil {
+		return err
+	}
+
+	conditions, err := json.Marshal(rule.Conditions)
+	if err != nil {
+		return err
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if rule.OffsetAccount != 0 {
+		err = checkPostable(sqlAccounts{tx}, scope.Ledger, rule.OffsetAccount)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec("UPDATE rules SET name = $1, priority = $2, conditions = $3, offset_account = $4, description = $5, tax_code = $6, assign = $7 WHERE ledger_id = $8 AND id = $9", rule.Name, rule.Priority, string(conditions), nullAccount(rule.OffsetAccount), rule.Description, rule.TaxCode, rule.Assign, scope.Ledger, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrRuleNotExists
+	}
+	return tx.Commit()
+}
+
+func DeleteRule(database *sql.DB, scope Scope, id int) error {
+	result, err := database.Exec("DELETE FROM rules WHERE ledger_id = $1 AND id = $2", scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrRuleNotExists
+	}
+	return nil
+}
+
+func GetRule(database *sql.DB, scope Scope, id int) (Rule, error) {
+	return getRule(database, scope.Ledger, uint(id))
+}
+
+func getRule(database querier, ledger uint, id uint) (Rule, error) {
+	rule, err := scanRule(database.QueryRow("SELECT "+ruleColumns+" FROM rules WHERE ledger_id = $1 AND id = $2", ledger, id))
+	if err == sql.ErrNoRows {
+		return rule, ErrRuleNotExists
+	}
+	return rule, err
+}
+
+// GetRules returns the rules of the ledger in the order they are tried.
+func GetRules(database *sql.DB, scope Scope) ([]Rule, error) {
+	return getRules(database, scope.Ledger)
+}
+
+func getRules(database querier, ledger uint) ([]Rule, error) {
+	rows, err := database.Query("SELECT "+ruleColumns+" FROM rules WHERE ledger_id = $1 ORDER BY priority, id", ledger)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// countRuleMatch adds a firing to the statistics of a rule.
+func countRuleMatch(database querier, ledger uint, id uint) error {
+	_, err := database.Exec("UPDATE rules SET matches = matches + 1, last_matched = $1 WHERE ledger_id = $2 AND id = $3", time.Now().UTC(), ledger, id)
+	return err
+}
+
+// FindRule returns the rule that would fire for the line without counting it
+// in the statistics.
+func FindRule(database *sql.DB, scope Scope, line BankLine) (Rule, bool, error) {
+	rules, err := getRules(database, scope.Ledger)
+	if err != nil {
+		return Rule{}, false, err
+	}
+
+	rule, ok := MatchRule(rules, line)
+	return rule, ok, nil
+}
+
+// CategorizeTransaction fills in what a new transaction leaves open from the
+// first rule that fires for it. It returns the id of that rule, or 0. The
+// match is counted by NewTransaction once the transaction is stored.
+func CategorizeTransaction(database *sql.DB, scope Scope, transaction Transaction) (Transaction, uint, error) {
+	rules, err := getRules(database, scope.Ledger)
+	if err != nil {
+		return transaction, 0, err
+	}
+
+	rule, ok := MatchRule(rules, TransactionLine(transaction))
+	if !ok {
+		return transaction, 0, nil
+	}
+
+	transaction = rule.Apply(transaction)
+	transaction.Rule = rule.ID
+	return transaction, rule.ID, nil
+}
+
+// GetRuleStatistics returns how often each rule of the ledger fired, the
+// most used first.
+func GetRuleStatistics(database *sql.DB, scope Scope) ([]RuleStatistic, error) {
+	rules, err := getRules(database, scope.Ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, rule := range rules {
+		total += rule.Matches
+	}
+
+	statistics := make([]RuleStatistic, 0, len(rules))
+	for _, rule := range rules {
+		statistic := RuleStatistic{Rule: rule.ID, Name: rule.Name, Matches: rule.Matches, LastMatched: rule.LastMatched}
+		if total > 0 {
+			statistic.Share = float64(rule.Matches) / float64(total)
+		}
+		statistics = append(statistics, statistic)
+	}
+
+	sort.SliceStable(statistics, func(i, j int) bool {
+		return statistics[i].Matches > statistics[j].Matches
+	})
+	return statistics, nil
+}
@@ -0,0 +1,234 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var telekomRule = Rule{
+	Name:          "Telekom",
+	Priority:      10,
+	Conditions:    []RuleCondition{{Field: FieldCounterparty, Operator: OperatorContains, Value: "telekom"}},
+	OffsetAccount: 4900,
+	Description:   "Telephone",
+	TaxCode:       "VSt19",
+}
+
+func TestRuleFires(t *testing.T) {
+	rule := Rule{Name: "Rent", Conditions: []RuleCondition{
+		{Field: FieldIBAN, Operator: OperatorEquals, Value: "DE02 1203 0000 0000 2020 51"},
+		{Field: FieldAmount, Operator: OperatorBetween, Min: -100000, Max: -80000},
+	}}
+
+	assert.True(t, rule.Fires(BankLine{CounterpartyIBAN: "DE02120300000000202051", Amount: -90000}))
+	assert.False(t, rule.Fires(BankLine{CounterpartyIBAN: "DE02120300000000202051", Amount: -120000}))
+	assert.False(t, rule.Fires(BankLine{CounterpartyIBAN: "DE89370400440532013000", Amount: -90000}))
+	assert.True(t, telekomRule.Fires(BankLine{Counterparty: "Telekom Deutschland GmbH"}))
+	assert.False(t, Rule{Name: "Empty"}.Fires(BankLine{}))
+}
+
+func TestValidateRule(t *testing.T) {
+	assert.NoError(t, ValidateRule(telekomRule))
+
+	broken := telekomRule
+	broken.Conditions = []RuleCondition{{Field: FieldAmount, Operator: OperatorContains, Value: "12"}}
+	assert.ErrorContains(t, ValidateRule(broken), "condition 1")
+
+	broken = telekomRule
+	broken.OffsetAccount = 0
+	broken.Assign = true
+	assert.Error(t, ValidateRule(broken))
+}
+
+func TestRulesPriority(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	_, err := NewRule(db, testScope, telekomRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := telekomRule
+	first.Name = "Telekom mobile"
+	first.Priority = 1
+	first.Conditions = append(first.Conditions, RuleCondition{Field: FieldRemittance, Operator: OperatorContains, Value: "mobil"})
+	first.TaxCode = ""
+	_, err = NewRule(db, testScope, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule, ok, err := FindRule(db, testScope, BankLine{Counterparty: "Telekom", Remittance: "Mobilfunk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+	assert.Equal(t, "Telekom mobile", rule.Name)
+
+	rule, ok, err = FindRule(db, testScope, BankLine{Counterparty: "Telekom", Remittance: "Festnetz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, ok)
+	assert.Equal(t, "Telekom", rule.Name)
+}
+
+func TestStageBankLinesRules(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	bakery := Rule{Name: "Bakery", Conditions: []RuleCondition{{Field: FieldCounterparty, Operator: OperatorEquals, Value: "bakery"}}, OffsetAccount: 4900, Description: "Office snacks", Assign: true}
+	_, err := NewRule(db, testScope, bakery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revenue := Rule{Name: "ACME", Conditions: []RuleCondition{{Field: FieldAmount, Operator: OperatorBetween, Min: 100000, Max: 200000}}, OffsetAccount: 8400, TaxCode: "USt19"}
+	revenueID, err := NewRule(db, testScope, revenue)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a preview suggests, but neither books nor counts
+	result, err := PreviewBankLines(db, testScope, 1200, "csv", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, revenueID, result.Imported[0].Rule)
+	assert.Equal(t, uint(8400), result.Bookings[0].OffsetAccount)
+	assert.Equal(t, "Office snacks", result.Bookings[1].Description)
+
+	result, err = StageBankLines(db, testScope, 1200, "csv", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the bakery lines are booked, the revenue waits for review
+	assert.Zero(t, result.Imported[0].Transaction)
+	assert.NotZero(t, result.Imported[1].Transaction)
+	assert.NotZero(t, result.Imported[2].Transaction)
+
+	transaction, err := GetTransaction(db, testScope, int(result.Imported[1].Transaction))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint(4900), transaction.OffsetAccount)
+	assert.Equal(t, "Office snacks", transaction.Description)
+	assert.Equal(t, StatusDraft, transaction.Status)
+
+	// booking without an offset account takes the suggestion
+	id, err := BookBankLine(db, testScope, int(result.Imported[0].ID), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transaction, err = GetTransaction(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, uint(8400), transaction.OffsetAccount)
+	assert.Equal(t, "USt19", transaction.TaxCode)
+	assert.Equal(t, "ACME GmbH Invoice 2024-017", transaction.Description)
+
+	statistics, err := GetRuleStatistics(db, testScope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, statistics, 2) {
+		assert.Equal(t, "Bakery", statistics[0].Name)
+		assert.Equal(t, 2, statistics[0].Matches)
+		assert.InDelta(t, 2.0/3.0, statistics[0].Share, 0.001)
+		assert.Equal(t, 1, statistics[1].Matches)
+		assert.False(t, statistics[1].LastMatched.IsZero())
+	}
+}
+
+func TestBookBankLineWithoutRule(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	result, err := StageBankLines(db, testScope, 1200, "csv", bankLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = BookBankLine(db, testScope, int(result.Imported[0].ID), 0, "")
+	assert.ErrorIs(t, err, ErrOffsetAccountRequired)
+}
+
+func TestCategorizeTransaction(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	id, err := NewRule(db, testScope, telekomRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, rule, err := CategorizeTransaction(db, testScope, Transaction{Amount: 5995, Account: 1200, Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Description: "Telekom invoice March"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, id, rule)
+	assert.Equal(t, uint(4900), transaction.OffsetAccount)
+	assert.Equal(t, "VSt19", transaction.TaxCode)
+	// the description the user wrote is kept
+	assert.Equal(t, "Telekom invoice March", transaction.Description)
+
+	// the match counts once the transaction is stored, and only then
+	stored, err := GetRule(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, stored.Matches)
+
+	failed := transaction
+	failed.OffsetAccount = 1800
+	_, err = NewTransaction(db, testScope, failed)
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+
+	_, err = NewTransaction(db, testScope, transaction)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err = GetRule(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, stored.Matches)
+}
+
+func TestUpdateAndDeleteRule(t *testing.T) {
+	insertBooks(t, bankAccounts, nil, nil)
+
+	id, err := NewRule(db, testScope, telekomRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule := telekomRule
+	rule.ID = id
+	rule.OffsetAccount = 1800
+	err = UpdateRule(db, testScope, rule)
+	assert.ErrorIs(t, err, ErrAccountNotExists)
+
+	rule.OffsetAccount = 4900
+	rule.Priority = 5
+	err = UpdateRule(db, testScope, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := GetRule(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, stored.Priority)
+
+	err = DeleteRule(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = DeleteRule(db, testScope, int(id))
+	assert.ErrorIs(t, err, ErrRuleNotExists)
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqliteDriverName is the name the SQLite driver of this package is
+// registered under.
+const sqliteDriverName = "bookholder-sqlite"
+
+func init() {
+	sql.Register(sqliteDriverName, sqliteDriver{&sqlite.Driver{}})
+}
+
+// OpenSQLite opens the SQLite database in the file at path, which is created
+// if it does not exist. Foreign keys are enforced, and transactions take the
+// write lock when they begin, which stands in for the row locks of Postgres.
+func OpenSQLite(path string) (*sql.DB, error) {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Set("_txlock", "immediate")
+	return sql.Open(sqliteDriverName, "file:"+path+"?"+query.Encode())
+}
+
+// isSQLite reports whether database was opened with OpenSQLite.
+func isSQLite(database *sql.DB) bool {
+	_, ok := database.Driver().(sqliteDriver)
+	return ok
+}
+
+// dialect holds the SQL that Postgres and SQLite spell differently.
+type dialect struct {
+	// forUpdate ends a query that locks the rows it selects. SQLite has no
+	// row locks, the transaction holds the lock on the whole database.
+	forUpdate string
+}
+
+var (
+	postgresDialect = dialect{forUpdate: " FOR UPDATE"}
+	sqliteDialect   = dialect{}
+)
+
+// dialectOf returns the dialect of the backend database was opened with.
+func dialectOf(database *sql.DB) dialect {
+	if isSQLite(database) {
+		return sqliteDialect
+	}
+	return postgresDialect
+}
+
+// sqliteDriver keeps times as text that sorts like the times do.
+type sqliteDriver struct {
+	*sqlite.Driver
+}
+
+func (d sqliteDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{conn.(sqliteBackend)}, nil
+}
+
+// sqliteBackend is what the connections of modernc.org/sqlite implement.
+type sqliteBackend interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// sqliteTimeLayout is how times are stored: in UTC and without a zone, like
+// timestamp without time zone, and with the seconds always written, so
+// comparing the text compares the times.
+const sqliteTimeLayout = "2006-01-02 15:04:05.999999999"
+
+type sqliteConn struct {
+	sqliteBackend
+}
+
+// CheckNamedValue converts arguments like database/sql does by default and
+// then writes times in sqliteTimeLayout.
+func (c *sqliteConn) CheckNamedValue(value *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := v.(time.Time); ok {
+		v = t.UTC().Format(sqliteTimeLayout)
+	}
+	value.Value = v
+	return nil
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectOf(t *testing.T) {
+	if testDriver == DriverSQLite {
+		assert.Equal(t, sqliteDialect, dialectOf(db))
+	} else {
+		assert.Equal(t, postgresDialect, dialectOf(db))
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	cleanTables()
+
+	// both backends refuse bookings on accounts that do not exist
+	_, err := db.Exec("INSERT INTO transactions (ledger_id, amount, debit, offset_account, account, date) VALUES ($1, 100, true, 1, 4711, $2)", testScope.Ledger, time.Now())
+	assert.Error(t, err)
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Zero(t, count)
+}
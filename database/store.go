@@ -0,0 +1,111 @@
+package database
+
+import "database/sql"
+
+// Store keeps the ledgers, accounts, transactions and users of the server.
+// SQLStore keeps them in a Postgres or SQLite database; MemoryStore keeps
+// them in memory, for tests that should not need a database.
+//
+// The Store covers only these records. Workflow, history, periods, reports,
+// audit, imports, reconciliation, exports and backups are functions of this
+// package that take the *sql.DB themselves, so the routes for them need a
+// database whatever the Store is.
+type Store interface {
+	GetLedger(id uint, user string) (Ledger, error)
+	GetLedgers(user string) ([]Ledger, error)
+	DefaultLedger(user string) (Ledger, error)
+	NewLedger(ledger Ledger) (uint, error)
+
+	GetAccount(scope Scope, id int) (Account, error)
+	NewAccount(scope Scope, account Account) error
+	UpdateAccount(scope Scope, account Account) error
+	DeleteAccount(scope Scope, id int) error
+
+	GetTransaction(scope Scope, id int) (Transaction, error)
+	GetTransactions(scope Scope, account int, year int, month int, status string) ([]Transaction, error)
+	NewTransaction(scope Scope, transaction Transaction) (uint, error)
+	UpdateTransaction(scope Scope, transaction Transaction) (uint, error)
+	DeleteTransaction(scope Scope, id int) (uint, error)
+
+	GetUser(id string) (User, error)
+	GetUserByName(name string) (User, error)
+	NewUser(user User) error
+	NewUserWithLedger(user User, ledger string) error
+}
+
+// SQLStore is the Store on a database opened with New or OpenSQLite.
+type SQLStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(database *sql.DB) *SQLStore {
+	return &SQLStore{db: database}
+}
+
+func (s *SQLStore) GetLedger(id uint, user string) (Ledger, error) {
+	return GetLedger(s.db, id, user)
+}
+
+func (s *SQLStore) GetLedgers(user string) ([]Ledger, error) {
+	return GetLedgers(s.db, user)
+}
+
+func (s *SQLStore) DefaultLedger(user string) (Ledger, error) {
+	return DefaultLedger(s.db, user)
+}
+
+func (s *SQLStore) NewLedger(ledger Ledger) (uint, error) {
+	return NewLedger(s.db, ledger)
+}
+
+func (s *SQLStore) GetAccount(scope Scope, id int) (Account, error) {
+	return GetAccount(s.db, scope, id)
+}
+
+func (s *SQLStore) NewAccount(scope Scope, account Account) error {
+	return NewAccount(s.db, scope, account)
+}
+
+func (s *SQLStore) UpdateAccount(scope Scope, account Account) error {
+	return UpdateAccount(s.db, scope, account)
+}
+
+func (s *SQLStore) DeleteAccount(scope Scope, id int) error {
+	return DeleteAccount(s.db, scope, id)
+}
+
+func (s *SQLStore) GetTransaction(scope Scope, id int) (Transaction, error) {
+	return GetTransaction(s.db, scope, id)
+}
+
+func (s *SQLStore) GetTransactions(scope Scope, account int, year int, month int, status string) ([]Transaction, error) {
+	return GetTransactions(s.db, scope, account, year, month, status)
+}
+
+func (s *SQLStore) NewTransaction(scope Scope, transaction Transaction) (uint, error) {
+	return NewTransaction(s.db, scope, transaction)
+}
+
+func (s *SQLStore) UpdateTransaction(scope Scope, transaction Transaction) (uint, error) {
+	return UpdateTransaction(s.db, scope, transaction)
+}
+
+func (s *SQLStore) DeleteTransaction(scope Scope, id int) (uint, error) {
+	return DeleteTransaction(s.db, scope, id)
+}
+
+func (s *SQLStore) GetUser(id string) (User, error) {
+	return GetUser(s.db, id)
+}
+
+func (s *SQLStore) GetUserByName(name string) (User, error) {
+	return GetUserByName(s.db, name)
+}
+
+func (s *SQLStore) NewUser(user User) error {
+	return NewUser(s.db, user)
+}
+
+func (s *SQLStore) NewUserWithLedger(user User, ledger string) error {
+	return NewUserWithLedger(s.db, user, ledger)
+}
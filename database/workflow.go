@@ -0,0 +1,269 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Transaction statuses. A transaction is prepared as a draft, submitted for
+// approval, approved or rejected by another user and finally posted. Only
+// posted transactions count in balances and reports, and they are never
+// changed again; corrections reverse them. Everything before can be changed
+// or deleted, which sends the transaction back to draft.
+const (
+	StatusDraft     = "draft"
+	StatusSubmitted = "submitted"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusPosted    = "posted"
+)
+
+var Statuses = []string{StatusDraft, StatusSubmitted, StatusApproved, StatusRejected, StatusPosted}
+
+func ValidStatus(status string) bool {
+	for _, valid := range Statuses {
+		if status == valid {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrInvalidTransactionStatus = errors.New("invalid status; must be draft or posted")
+	ErrStatusTransition         = errors.New("transaction is not in the required status")
+	ErrOwnApproval              = errors.New("transactions cannot be approved or rejected by the user who prepared them")
+)
+
+// Approval is the decision of an approver on a submitted transaction.
+type Approval struct {
+	ID       uint
+	User     string
+	Decision string
+	Comment  string
+	Time     time.Time
+}
+
+// SubmitTransaction asks for the approval of a draft.
+func SubmitTransaction(database *sql.DB, scope Scope, id int) error {
+	return changeStatus(database, scope, id, StatusDraft, StatusSubmitted, "")
+}
+
+// ApproveTransaction approves a submitted transaction. The approver has to
+// be someone else than the user who prepared it.
+func ApproveTransaction(database *sql.DB, scope Scope, id int, comment string) error {
+	return changeStatus(database, scope, id, StatusSubmitted, StatusApproved, comment)
+}
+
+// RejectTransaction rejects a submitted transaction. It can be changed and
+// submitted again.
+func RejectTransaction(database *sql.DB, scope Scope, id int, comment string) error {
+	return changeStatus(database, scope, id, StatusSubmitted, StatusRejected, comment)
+}
+
+// PostTransaction posts an approved transaction, after which it counts in
+// balances and can no longer be changed.
+func PostTransaction(database *sql.DB, scope Scope, id int) error {
+	return changeStatus(database, scope, id, StatusApproved, StatusPosted, "")
+}
+
+// changeStatus moves a transaction from one status to the next. Approvals
+// and rejections are recorded with their comment.
+func changeStatus(database *sql.DB, scope Scope, id int, from string, to string, comment string) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := scanTransaction(tx.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE ledger_id = $1 AND id = $2"+dialectOf(database).forUpdate, scope.Ledger, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrTransactionNotExists
+		}
+		return err
+	}
+
+	err = checkTransition(scope, current.Status, current.PreparedBy, from, to)
+	if err != nil {
+		return err
+	}
+
+	if to == StatusApproved || to == StatusRejected {
+		_, err = tx.Exec("INSERT INTO approvals (ledger_id, transaction_id, user_id, decision, comment, created_at) VALUES ($1, $2, $3, $4, $5, $6)", scope.Ledger, id, scope.User, to, comment, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	if to == StatusPosted {
+		err = checkPeriods(tx, scope, current.Date)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE transactions SET status = $1 WHERE ledger_id = $2 AND id = $3", to, scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	changed := current
+	changed.Status = to
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityTransaction, id, ActionUpdate, current, changed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkTransition makes sure a booking in status, prepared by preparedBy,
+// may move from one status to the next: it has to be in from, and approvals
+// and rejections have to come from someone else than the user who prepared
+// it.
+func checkTransition(scope Scope, status string, preparedBy string, from string, to string) error {
+	if status != from {
+		return fmt.Errorf("%w: it is %s, but has to be %s", ErrStatusTransition, status, from)
+	}
+
+	if (to == StatusApproved || to == StatusRejected) && preparedBy == scope.User {
+		return ErrOwnApproval
+	}
+	return nil
+}
+
+// SubmitJournalEntry asks for the approval of a draft journal entry. Journal
+// entries go through the same steps as transactions, but they cannot be
+// changed; a rejected entry stays rejected and is replaced by a new one.
+func SubmitJournalEntry(database *sql.DB, scope Scope, id int) error {
+	return changeEntryStatus(database, scope, id, StatusDraft, StatusSubmitted, "")
+}
+
+// ApproveJournalEntry approves a submitted journal entry. The approver has to
+// be someone else than the user who prepared it.
+func ApproveJournalEntry(database *sql.DB, scope Scope, id int, comment string) error {
+	return changeEntryStatus(database, scope, id, StatusSubmitted, StatusApproved, comment)
+}
+
+// RejectJournalEntry rejects a submitted journal entry.
+func RejectJournalEntry(database *sql.DB, scope Scope, id int, comment string) error {
+	return changeEntryStatus(database, scope, id, StatusSubmitted, StatusRejected, comment)
+}
+
+// PostJournalEntry posts an approved journal entry, after which it counts in
+// balances.
+func PostJournalEntry(database *sql.DB, scope Scope, id int) error {
+	return changeEntryStatus(database, scope, id, StatusApproved, StatusPosted, "")
+}
+
+// changeEntryStatus is changeStatus for journal entries.
+func changeEntryStatus(database *sql.DB, scope Scope, id int, from string, to string, comment string) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT "+journalEntryColumns+" FROM journal_entries e JOIN journal_lines l ON l.entry_id = e.id WHERE e.ledger_id = $1 AND e.id = $2 ORDER BY l.id"+dialectOf(database).forUpdate, scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+	entries, err := scanJournalEntries(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return ErrJournalEntryNotExists
+	}
+	current := entries[0]
+
+	err = checkTransition(scope, current.Status, current.PreparedBy, from, to)
+	if err != nil {
+		return err
+	}
+
+	if to == StatusApproved || to == StatusRejected {
+		_, err = tx.Exec("INSERT INTO journal_approvals (ledger_id, entry_id, user_id, decision, comment, created_at) VALUES ($1, $2, $3, $4, $5, $6)", scope.Ledger, id, scope.User, to, comment, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	if to == StatusPosted {
+		err = checkPeriods(tx, scope, current.Date)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE journal_entries SET status = $1 WHERE ledger_id = $2 AND id = $3", to, scope.Ledger, id)
+	if err != nil {
+		return err
+	}
+
+	changed := current
+	changed.Status = to
+	err = recordAudit(tx, scope.Ledger, scope.User, EntityJournalEntry, id, ActionUpdate, current, changed)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetApprovals returns the approvals and rejections of a transaction, oldest
+// first.
+func GetApprovals(database *sql.DB, scope Scope, id int) ([]Approval, error) {
+	_, err := GetTransaction(database, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.Query("SELECT id, user_id, decision, COALESCE(comment, ''), created_at FROM approvals WHERE ledger_id = $1 AND transaction_id = $2 ORDER BY id", scope.Ledger, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanApprovals(rows)
+}
+
+// scanApprovals reads the approvals of a booking.
+func scanApprovals(rows *sql.Rows) ([]Approval, error) {
+	var approvals []Approval
+	for rows.Next() {
+		var approval Approval
+		err := rows.Scan(&approval.ID, &approval.User, &approval.Decision, &approval.Comment, &approval.Time)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, approval)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+// GetJournalEntryApprovals returns the approvals and rejections of a journal
+// entry, oldest first.
+func GetJournalEntryApprovals(database *sql.DB, scope Scope, id int) ([]Approval, error) {
+	_, err := GetJournalEntry(database, scope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.Query("SELECT id, user_id, decision, COALESCE(comment, ''), created_at FROM journal_approvals WHERE ledger_id = $1 AND entry_id = $2 ORDER BY id", scope.Ledger, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanApprovals(rows)
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The books of the workflow tests: a draft of 100.00 from the bank to
+// revenue.
+var (
+	workflowAccounts = []Account{{ID: 1200, Name: "Bank", Kind: KindAsset}, {ID: 8400, Name: "Revenue", Kind: KindRevenue}}
+
+	workflowTransactions = []Transaction{
+		{Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+)
+
+func TestApprovalWorkflow(t *testing.T) {
+	id := int(insertBooks(t, workflowAccounts, workflowTransactions, nil)[0])
+	insertMemberUser(t, "Approver")
+	member, err := AddMember(db, testScope, "Approver", RoleApprover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := Scope{Ledger: testScope.Ledger, User: member.User, Role: RoleApprover}
+
+	err = SubmitTransaction(db, testScope, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// four eyes: whoever prepared a transaction cannot approve it
+	err = ApproveTransaction(db, testScope, id, "")
+	assert.ErrorIs(t, err, ErrOwnApproval)
+
+	err = ApproveTransaction(db, approver, id, "checked against invoice 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(0), balance.Balance)
+
+	err = PostTransaction(db, testScope, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err = GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(10000), balance.Balance)
+
+	approvals, err := GetApprovals(db, testScope, id)
+	if err != nil {
+		t.Error(err)
+	}
+	if assert.Len(t, approvals, 1) {
+		assert.Equal(t, approver.User, approvals[0].User)
+		assert.Equal(t, StatusApproved, approvals[0].Decision)
+		assert.Equal(t, "checked against invoice 1", approvals[0].Comment)
+	}
+}
+
+func TestRejectTransaction(t *testing.T) {
+	id := int(insertBooks(t, workflowAccounts, workflowTransactions, nil)[0])
+	insertMemberUser(t, "Approver")
+	member, err := AddMember(db, testScope, "Approver", RoleApprover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := Scope{Ledger: testScope.Ledger, User: member.User, Role: RoleApprover}
+
+	err = SubmitTransaction(db, testScope, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = RejectTransaction(db, approver, id, "wrong account")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = PostTransaction(db, testScope, id)
+	assert.ErrorIs(t, err, ErrStatusTransition)
+
+	// a rejected transaction goes back to draft once it is corrected
+	_, err = UpdateTransaction(db, testScope, Transaction{ID: uint(id), Amount: 10000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := GetTransaction(db, testScope, id)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, StatusDraft, transaction.Status)
+}
+
+func TestApproveDraft(t *testing.T) {
+	id := int(insertBooks(t, workflowAccounts, workflowTransactions, nil)[0])
+	insertMemberUser(t, "Approver")
+	member, err := AddMember(db, testScope, "Approver", RoleApprover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := Scope{Ledger: testScope.Ledger, User: member.User, Role: RoleApprover}
+
+	err = ApproveTransaction(db, approver, id, "")
+
+	assert.ErrorIs(t, err, ErrStatusTransition)
+}
+
+func TestPostTransactionLockedPeriod(t *testing.T) {
+	id := int(insertBooks(t, workflowAccounts, workflowTransactions, nil)[0])
+	insertMemberUser(t, "Approver")
+	member, err := AddMember(db, testScope, "Approver", RoleApprover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := Scope{Ledger: testScope.Ledger, User: member.User, Role: RoleApprover}
+
+	year, err := NewFiscalYear(db, testScope, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SubmitTransaction(db, testScope, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ApproveTransaction(db, approver, id, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetPeriodStatus(db, testScope, int(year.Periods[2].ID), PeriodLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = PostTransaction(db, testScope, id)
+	assert.ErrorIs(t, err, ErrPeriodLocked)
+}
+
+func TestJournalEntryWorkflow(t *testing.T) {
+	insertBooks(t, workflowAccounts, workflowTransactions, nil)
+	insertMemberUser(t, "Approver")
+	member, err := AddMember(db, testScope, "Approver", RoleApprover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := Scope{Ledger: testScope.Ledger, User: member.User, Role: RoleApprover}
+
+	id, err := NewJournalEntry(db, testScope, JournalEntry{Date: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Lines: []JournalLine{{Account: 1200, Amount: 5000, Debit: true}, {Account: 8400, Amount: 5000}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := GetJournalEntry(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusDraft, entry.Status)
+	assert.Equal(t, testScope.User, entry.PreparedBy)
+
+	err = SubmitJournalEntry(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ApproveJournalEntry(db, testScope, int(id), "")
+	assert.ErrorIs(t, err, ErrOwnApproval)
+
+	err = ApproveJournalEntry(db, approver, int(id), "checked")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drafts and approved entries do not count yet
+	balance, err := GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(0), balance.Balance)
+
+	err = PostJournalEntry(db, testScope, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err = GetBalance(db, testScope, 1200, time.Time{})
+	if err != nil {
+		t.Error(err)
+	}
+	assert.Equal(t, Money(5000), balance.Balance)
+
+	approvals, err := GetJournalEntryApprovals(db, testScope, int(id))
+	if err != nil {
+		t.Error(err)
+	}
+	if assert.Len(t, approvals, 1) {
+		assert.Equal(t, approver.User, approvals[0].User)
+	}
+
+	err = PostJournalEntry(db, testScope, int(id))
+	assert.ErrorIs(t, err, ErrStatusTransition)
+}
+
+func TestJournalEntryWorkflowNotExists(t *testing.T) {
+	cleanTables()
+
+	err := SubmitJournalEntry(db, testScope, 99999)
+
+	assert.ErrorIs(t, err, ErrJournalEntryNotExists)
+}
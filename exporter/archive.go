@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// ledgerManifest is the manifest.json of a ledger archive.
+type ledgerManifest struct {
+	Version       int
+	SchemaVersion int
+	Ledger        string
+	Created       time.Time
+}
+
+// WriteLedgerArchive writes a ledger backup as a zip archive. manifest.json
+// comes first, so a reader learns the version of the archive and of the
+// schema it was taken from before it meets any record.
+// Then follows one JSON Lines file per kind of record, with one record per
+// line: accounts.jsonl, transactions.jsonl, journal_entries.jsonl,
+// fiscal_years.jsonl, opening_balances.jsonl, members.jsonl, rules.jsonl and
+// import_profiles.jsonl.
+func WriteLedgerArchive(w io.Writer, backup database.Backup) error {
+	archive := zip.NewWriter(w)
+
+	manifest, err := archive.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(manifest).Encode(ledgerManifest{
+		Version:       backup.Version,
+		SchemaVersion: backup.SchemaVersion,
+		Ledger:        backup.Ledger,
+		Created:       backup.Created,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeRecords(archive, "accounts.jsonl", backup.Accounts)
+	if err == nil {
+		err = writeRecords(archive, "transactions.jsonl", backup.Transactions)
+	}
+	if err == nil {
+		err = writeRecords(archive, "journal_entries.jsonl", backup.JournalEntries)
+	}
+	if err == nil {
+		err = writeRecords(archive, "fiscal_years.jsonl", backup.FiscalYears)
+	}
+	if err == nil {
+		err = writeRecords(archive, "opening_balances.jsonl", backup.OpeningBalances)
+	}
+	if err == nil {
+		err = writeRecords(archive, "members.jsonl", backup.Members)
+	}
+	if err == nil {
+		err = writeRecords(archive, "rules.jsonl", backup.Rules)
+	}
+	if err == nil {
+		err = writeRecords(archive, "import_profiles.jsonl", backup.ImportProfiles)
+	}
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func writeRecords[T any](archive *zip.Writer, name string, records []T) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, record := range records {
+		err = encoder.Encode(record)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
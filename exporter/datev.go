@@ -0,0 +1,279 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// DATEVHeader describes a DATEV Buchungsstapel, the batch of bookings DATEV
+// imports from an EXTF file. Advisor and Client are the Beraternummer and
+// Mandantennummer the tax advisor's DATEV knows the books by. AccountLength
+// is the number of digits of the ledger accounts (Sachkontenlänge); personal
+// accounts have one digit more. The bookings have to lie between From and To,
+// which have to lie in the fiscal year starting on FiscalYearStart. DATEV
+// only knows bookings with one account and one offset account, so every line
+// of a journal entry is booked against the Clearing account, on which the
+// lines of an entry cancel out.
+type DATEVHeader struct {
+	Advisor         int
+	Client          int
+	FiscalYearStart time.Time
+	AccountLength   int
+	Clearing        uint
+	From            time.Time
+	To              time.Time
+	Name            string
+	Initials        string
+	Created         time.Time
+}
+
+// DATEVProblem is a reason DATEV would reject an export. Line is the line of
+// the file it concerns: 1 is the header record, the bookings start on line 3.
+// Transaction or JournalEntry is the id of the booking on the line, or 0.
+type DATEVProblem struct {
+	Line         int
+	Transaction  uint
+	JournalEntry uint
+	Message      string
+}
+
+// datevBooking is a line of a Buchungsstapel: a transaction, or a line of a
+// journal entry as a transaction against the clearing account. Document is
+// the Belegfeld 1, the id of the transaction or J and the id of the entry.
+type datevBooking struct {
+	database.Transaction
+	Entry    uint
+	Document string
+}
+
+// datevBookings lists the transactions and then the lines of the journal
+// entries in the order of the file.
+func datevBookings(header DATEVHeader, transactions []database.Transaction, entries []database.JournalEntry) []datevBooking {
+	var bookings []datevBooking
+	for _, transaction := range transactions {
+		bookings = append(bookings, datevBooking{Transaction: transaction, Document: strconv.FormatUint(uint64(transaction.ID), 10)})
+	}
+
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			bookings = append(bookings, datevBooking{
+				Transaction: database.Transaction{
+					Amount:        line.Amount,
+					Debit:         line.Debit,
+					Account:       line.Account,
+					OffsetAccount: header.Clearing,
+					Date:          entry.Date,
+					Description:   entry.Description,
+					Status:        entry.Status,
+				},
+				Entry:    entry.ID,
+				Document: "J" + strconv.FormatUint(uint64(entry.ID), 10),
+			})
+		}
+	}
+	return bookings
+}
+
+// datevTaxKeys maps the tax codes of transactions to the BU keys
+// (BU-Schlüssel) of the DATEV standard charts SKR03 and SKR04. Tax codes that
+// already are BU keys pass unchanged.
+var datevTaxKeys = map[string]string{
+	"UStfrei": "1",
+	"USt7":    "2",
+	"USt19":   "3",
+	"VSt7":    "8",
+	"VSt19":   "9",
+}
+
+// DATEVTaxKey returns the BU key of a tax code; false means DATEV has no key
+// for it. Bookings without a tax code have no key.
+func DATEVTaxKey(code string) (string, bool) {
+	if code == "" {
+		return "", true
+	}
+	if key, ok := datevTaxKeys[code]; ok {
+		return key, true
+	}
+	if len(code) <= 4 && strings.Trim(code, "0123456789") == "" {
+		return code, true
+	}
+	return "", false
+}
+
+// datevColumns are the columns of a Buchungsstapel up to the booking text.
+// DATEV leaves the columns after them empty.
+var datevColumns = []string{
+	"Umsatz (ohne Soll/Haben-Kz)",
+	"Soll/Haben-Kennzeichen",
+	"WKZ Umsatz",
+	"Kurs",
+	"Basis-Umsatz",
+	"WKZ Basis-Umsatz",
+	"Konto",
+	"Gegenkonto (ohne BU-Schlüssel)",
+	"BU-Schlüssel",
+	"Belegdatum",
+	"Belegfeld 1",
+	"Belegfeld 2",
+	"Skonto",
+	"Buchungstext",
+}
+
+// datevMaxAmount is the largest amount DATEV takes: ten digits before the
+// decimal comma.
+const datevMaxAmount database.Money = 999999999999
+
+func datevText(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+func datevDate(date time.Time) string {
+	return date.Format("20060102")
+}
+
+// datevAmount writes an amount without sign, with a decimal comma and
+// without thousands separators.
+func datevAmount(amount database.Money) string {
+	if amount < 0 {
+		amount = -amount
+	}
+	return strings.Replace(amount.String(), ".", ",", 1)
+}
+
+// datevRecord is the header record in the first line of an EXTF file: format
+// version 700, category 21 Buchungsstapel in its format version 13, bookings
+// of the financial accounts in euro that are not locked against changes.
+func datevRecord(header DATEVHeader) []string {
+	created := header.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	return []string{
+		datevText("EXTF"), "700", "21", datevText("Buchungsstapel"), "13",
+		created.Format("20060102150405") + fmt.Sprintf("%03d", created.Nanosecond()/int(time.Millisecond)),
+		"", datevText("RE"), datevText(""), datevText(""),
+		strconv.Itoa(header.Advisor), strconv.Itoa(header.Client),
+		datevDate(header.FiscalYearStart), strconv.Itoa(header.AccountLength),
+		datevDate(header.From), datevDate(header.To),
+		datevText(truncate(header.Name, 30)), datevText(truncate(header.Initials, 2)),
+		"1", "0", "0", datevText("EUR"),
+		"", datevText(""), "", "", datevText(""), "", "", datevText(""), datevText(""),
+	}
+}
+
+// datevRow is the line of a booking. Soll/Haben tells whether Konto is
+// debited, like Debit tells it of Account.
+func datevRow(booking datevBooking) []string {
+	transaction := booking.Transaction
+	side := "H"
+	if transaction.Debit {
+		side = "S"
+	}
+	key, _ := DATEVTaxKey(transaction.TaxCode)
+
+	return []string{
+		datevAmount(transaction.Amount),
+		datevText(side),
+		datevText("EUR"),
+		"", "", "",
+		strconv.FormatUint(uint64(transaction.Account), 10),
+		strconv.FormatUint(uint64(transaction.OffsetAccount), 10),
+		datevText(key),
+		transaction.Date.Format("0201"),
+		datevText(booking.Document),
+		datevText(""),
+		"",
+		datevText(truncate(transaction.Description, 60)),
+	}
+}
+
+// WriteDATEV writes the transactions and journal entries as a DATEV
+// Buchungsstapel in the EXTF format: Windows-1252, fields separated by
+// semicolons, lines ended by CRLF. It writes what it is given; ValidateDATEV
+// tells what DATEV would reject.
+func WriteDATEV(w io.Writer, header DATEVHeader, transactions []database.Transaction, entries []database.JournalEntry) error {
+	buf := bufio.NewWriter(w)
+
+	lines := [][]string{datevRecord(header), datevColumns}
+	for _, booking := range datevBookings(header, transactions, entries) {
+		lines = append(lines, datevRow(booking))
+	}
+
+	for _, fields := range lines {
+		_, err := buf.Write(encodeWindows1252(strings.Join(fields, ";") + "\r\n"))
+		if err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// ValidateDATEV returns the problems that would make DATEV reject the header
+// or single bookings of an export, in the order of the file.
+func ValidateDATEV(header DATEVHeader, transactions []database.Transaction, entries []database.JournalEntry) []DATEVProblem {
+	var problems []DATEVProblem
+	headerProblem := func(format string, args ...any) {
+		problems = append(problems, DATEVProblem{Line: 1, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if header.Advisor < 1001 || header.Advisor > 9999999 {
+		headerProblem("advisor number %d must be between 1001 and 9999999", header.Advisor)
+	}
+	if header.Client < 1 || header.Client > 99999 {
+		headerProblem("client number %d must be between 1 and 99999", header.Client)
+	}
+	if header.AccountLength < 4 || header.AccountLength > 8 {
+		headerProblem("account length %d must be between 4 and 8", header.AccountLength)
+	}
+	if header.From.IsZero() || header.To.IsZero() || header.To.Before(header.From) {
+		headerProblem("the period must have a start and an end, and must not end before it starts")
+	} else if header.From.Before(header.FiscalYearStart) || !header.To.Before(header.FiscalYearStart.AddDate(1, 0, 0)) {
+		headerProblem("the period %s to %s is not within the fiscal year starting %s", header.From.Format(time.DateOnly), header.To.Format(time.DateOnly), header.FiscalYearStart.Format(time.DateOnly))
+	}
+
+	if len(entries) > 0 && header.Clearing == 0 {
+		headerProblem("journal entries need a clearing account")
+	}
+
+	maxDigits := header.AccountLength + 1
+	for i, booking := range datevBookings(header, transactions, entries) {
+		line := i + 3
+		transaction := booking.Transaction
+		problem := func(format string, args ...any) {
+			problems = append(problems, DATEVProblem{Line: line, Transaction: transaction.ID, JournalEntry: booking.Entry, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if transaction.Status != database.StatusPosted {
+			problem("only posted bookings can be exported")
+		}
+		if transaction.Amount <= 0 || transaction.Amount > datevMaxAmount {
+			problem("amount %s must be greater than 0 and have at most 10 digits before the decimal point", transaction.Amount)
+		}
+		if transaction.Account == 0 || transaction.OffsetAccount == 0 {
+			problem("account and offset account are required")
+		} else if transaction.Account == transaction.OffsetAccount {
+			problem("account and offset account must differ")
+		}
+		for _, account := range []uint{transaction.Account, transaction.OffsetAccount} {
+			if len(strconv.FormatUint(uint64(account), 10)) > maxDigits {
+				problem("account %d has more than %d digits", account, maxDigits)
+			}
+		}
+		if _, ok := DATEVTaxKey(transaction.TaxCode); !ok {
+			problem("tax code %q has no DATEV tax key", transaction.TaxCode)
+		}
+
+		date := time.Date(transaction.Date.Year(), transaction.Date.Month(), transaction.Date.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Before(header.From) || date.After(header.To) {
+			problem("date %s is outside the period %s to %s", date.Format(time.DateOnly), header.From.Format(time.DateOnly), header.To.Format(time.DateOnly))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var march = DATEVHeader{
+	Advisor:         29098,
+	Client:          55003,
+	FiscalYearStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	AccountLength:   4,
+	From:            time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+	To:              time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+	Name:            "Bookholder March 2024",
+	Created:         time.Date(2024, 4, 2, 9, 30, 15, 250000000, time.UTC),
+}
+
+var marchTransactions = []database.Transaction{
+	{ID: 17, Amount: 119000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Description: `Invoice "2024-017" Müller`, TaxCode: "USt19", Status: database.StatusPosted},
+	{ID: 18, Amount: 5995, Debit: false, Account: 1200, OffsetAccount: 4900, Date: time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), Description: "Telephone €", TaxCode: "VSt19", Status: database.StatusPosted},
+}
+
+func TestWriteDATEV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteDATEV(&buf, march, marchTransactions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if !assert.Len(t, lines, 4) {
+		return
+	}
+
+	header := strings.Split(lines[0], ";")
+	assert.Len(t, header, 31)
+	assert.Equal(t, `"EXTF";700;21;"Buchungsstapel";13;20240402093015250;;"RE";"";"";29098;55003;20240101;4;20240301;20240331;"Bookholder March 2024"`, strings.Join(header[:17], ";"))
+	assert.True(t, strings.HasPrefix(lines[1], "Umsatz (ohne Soll/Haben-Kz);Soll/Haben-Kennzeichen;"))
+
+	assert.Equal(t, "1190,00;\"S\";\"EUR\";;;;1200;8400;\"3\";0403;\"17\";\"\";;\"Invoice \"\"2024-017\"\" M\xfcller\"", lines[2])
+	assert.Equal(t, "59,95;\"H\";\"EUR\";;;;1200;4900;\"9\";1503;\"18\";\"\";;\"Telephone \x80\"", lines[3])
+}
+
+func TestValidateDATEV(t *testing.T) {
+	assert.Empty(t, ValidateDATEV(march, marchTransactions, nil))
+
+	header := march
+	header.Advisor = 12
+	header.To = time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	problems := ValidateDATEV(header, nil, nil)
+	if assert.Len(t, problems, 2) {
+		assert.Equal(t, 1, problems[0].Line)
+		assert.Contains(t, problems[1].Message, "fiscal year")
+	}
+
+	transactions := []database.Transaction{
+		marchTransactions[0],
+		{ID: 19, Amount: 1000, Debit: true, Account: 1200, OffsetAccount: 1200, Date: time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC), Status: database.StatusPosted},
+		{ID: 20, Amount: 1000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), TaxCode: "reduced", Status: database.StatusPosted},
+		{ID: 21, Amount: 1000, Debit: true, Account: 1200, OffsetAccount: 123456, Date: time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC), Status: database.StatusPosted},
+	}
+	problems = ValidateDATEV(march, transactions, nil)
+	if assert.Len(t, problems, 4) {
+		assert.Equal(t, DATEVProblem{Line: 4, Transaction: 19, Message: "account and offset account must differ"}, problems[0])
+		assert.Equal(t, 5, problems[1].Line)
+		assert.Contains(t, problems[1].Message, `"reduced"`)
+		assert.Contains(t, problems[2].Message, "outside the period")
+		assert.Equal(t, uint(21), problems[3].Transaction)
+	}
+}
+
+var marchEntry = database.JournalEntry{ID: 17, Date: time.Date(2024, 3, 28, 0, 0, 0, 0, time.UTC), Description: "Payroll", Status: database.StatusPosted, Lines: []database.JournalLine{
+	{Account: 4120, Amount: 300000, Debit: true},
+	{Account: 1200, Amount: 210000},
+	{Account: 1741, Amount: 90000},
+}}
+
+func TestWriteDATEVJournalEntries(t *testing.T) {
+	header := march
+	header.Clearing = 1590
+
+	var buf bytes.Buffer
+	err := WriteDATEV(&buf, header, marchTransactions[:1], []database.JournalEntry{marchEntry})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if !assert.Len(t, lines, 6) {
+		return
+	}
+
+	// every line of the entry is booked against the clearing account
+	assert.Equal(t, "3000,00;\"S\";\"EUR\";;;;4120;1590;\"\";2803;\"J17\";\"\";;\"Payroll\"", lines[3])
+	assert.Equal(t, "2100,00;\"H\";\"EUR\";;;;1200;1590;\"\";2803;\"J17\";\"\";;\"Payroll\"", lines[4])
+	assert.Equal(t, "900,00;\"H\";\"EUR\";;;;1741;1590;\"\";2803;\"J17\";\"\";;\"Payroll\"", lines[5])
+
+	assert.Empty(t, ValidateDATEV(header, marchTransactions[:1], []database.JournalEntry{marchEntry}))
+}
+
+func TestValidateDATEVJournalEntries(t *testing.T) {
+	problems := ValidateDATEV(march, nil, []database.JournalEntry{marchEntry})
+	if assert.NotEmpty(t, problems) {
+		assert.Equal(t, DATEVProblem{Line: 1, Message: "journal entries need a clearing account"}, problems[0])
+	}
+
+	header := march
+	header.Clearing = 1200
+	problems = ValidateDATEV(header, nil, []database.JournalEntry{marchEntry})
+	if assert.Len(t, problems, 1) {
+		assert.Equal(t, DATEVProblem{Line: 4, JournalEntry: 17, Message: "account and offset account must differ"}, problems[0])
+	}
+}
+
+func TestDATEVTaxKey(t *testing.T) {
+	key, ok := DATEVTaxKey("VSt7")
+	assert.True(t, ok)
+	assert.Equal(t, "8", key)
+
+	key, ok = DATEVTaxKey("94")
+	assert.True(t, ok)
+	assert.Equal(t, "94", key)
+
+	_, ok = DATEVTaxKey("VAT")
+	assert.False(t, ok)
+}
@@ -0,0 +1,42 @@
+// Package exporter writes bookings and reports in the formats other software
+// reads: DATEV for the tax advisor, tables in CSV, JSON Lines and XLSX,
+// reports printed as PDF and whole ledgers as a backup archive. Like the
+// importer it never touches the database; the caller loads the rows and hands
+// them over.
+package exporter
+
+import "strings"
+
+// windows1252 holds the characters Windows-1252 puts where Latin-1 has its
+// control characters 0x80 to 0x9F.
+var windows1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encodeWindows1252 encodes text for software that only reads the Windows
+// code page. Characters the code page does not have become a question mark.
+func encodeWindows1252(text string) []byte {
+	data := make([]byte, 0, len(text))
+	for _, r := range text {
+		if b, ok := windows1252[r]; ok {
+			data = append(data, b)
+		} else if r < 0x80 || (r >= 0xA0 && r <= 0xFF) {
+			data = append(data, byte(r))
+		} else {
+			data = append(data, '?')
+		}
+	}
+	return data
+}
+
+// truncate cuts text to at most length characters.
+func truncate(text string, length int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) > length {
+		runes = runes[:length]
+	}
+	return string(runes)
+}
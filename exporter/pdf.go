@@ -0,0 +1,299 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/go-pdf/fpdf"
+)
+
+// FormatPDF is the format of the printable reports. Unlike the table formats
+// it is only written for reports, from rows that are all loaded.
+const FormatPDF = "pdf"
+
+// Letterhead is printed at the top of every page of a PDF report: the name
+// of the books and any lines below it, like an address.
+type Letterhead struct {
+	Name  string
+	Lines []string
+}
+
+// pdfColumn is a column of a PDF report. Width is in millimetres; amount
+// columns are aligned right.
+type pdfColumn struct {
+	title  string
+	width  float64
+	amount bool
+}
+
+// pdfReport lays out a report as a table on A4 pages. The letterhead, title,
+// period and column titles repeat on every page, the page number is printed
+// at the bottom.
+type pdfReport struct {
+	pdf       *fpdf.Fpdf
+	translate func(string) string
+	columns   []pdfColumn
+}
+
+func newPDFReport(letterhead Letterhead, title string, period string, columns []pdfColumn) *pdfReport {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+	pdf.SetTitle(title, true)
+
+	report := &pdfReport{pdf: pdf, translate: pdf.UnicodeTranslatorFromDescriptor(""), columns: columns}
+
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 7, report.translate(letterhead.Name), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		for _, line := range letterhead.Lines {
+			pdf.CellFormat(0, 4.5, report.translate(line), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 6, report.translate(title), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 5, report.translate(period), "", 1, "L", false, 0, "")
+		pdf.Ln(3)
+
+		pdf.SetFont("Helvetica", "B", 9)
+		for _, column := range columns {
+			align := "L"
+			if column.amount {
+				align = "R"
+			}
+			pdf.CellFormat(column.width, 6, report.translate(column.title), "B", 0, align, false, 0, "")
+		}
+		pdf.Ln(7)
+	})
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	return report
+}
+
+// pdfAmount writes an amount with thousands separators.
+func pdfAmount(amount database.Money) string {
+	text := amount.String()
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+
+	units, cents, _ := strings.Cut(text, ".")
+	for i := len(units) - 3; i > 0; i -= 3 {
+		units = units[:i] + "," + units[i:]
+	}
+	return sign + units + "." + cents
+}
+
+func pdfText(value any) string {
+	switch value := value.(type) {
+	case database.Money:
+		return pdfAmount(value)
+	case uint:
+		if value == 0 {
+			return ""
+		}
+		return strconv.FormatUint(uint64(value), 10)
+	}
+	return text(value)
+}
+
+// row writes a line of the table. Totals are bold and have a rule above
+// their amounts.
+func (r *pdfReport) row(total bool, values ...any) {
+	style, border := "", ""
+	if total {
+		style = "B"
+	}
+	r.pdf.SetFont("Helvetica", style, 9)
+
+	for i, column := range r.columns {
+		value := ""
+		if i < len(values) {
+			value = pdfText(values[i])
+		}
+
+		align := "L"
+		if column.amount {
+			align = "R"
+			if total {
+				border = "T"
+			}
+		}
+
+		// cut text that does not fit instead of running into the next column
+		value = r.translate(value)
+		for len(value) > 0 && r.pdf.GetStringWidth(value) > column.width-1 {
+			value = value[:len(value)-1]
+		}
+		r.pdf.CellFormat(column.width, 5.5, value, border, 0, align, false, 0, "")
+		border = ""
+	}
+	r.pdf.Ln(-1)
+	if total {
+		r.pdf.Ln(1.5)
+	}
+}
+
+// heading starts a section of the report.
+func (r *pdfReport) heading(text string) {
+	r.pdf.Ln(2)
+	r.pdf.SetFont("Helvetica", "B", 10)
+	r.pdf.CellFormat(0, 6, r.translate(text), "", 1, "L", false, 0, "")
+}
+
+func (r *pdfReport) write(w io.Writer) error {
+	return r.pdf.Output(w)
+}
+
+// pdfPeriod describes the period of a report; zero bounds are open.
+func pdfPeriod(from time.Time, to time.Time) string {
+	switch {
+	case from.IsZero() && to.IsZero():
+		return "All bookings"
+	case from.IsZero():
+		return "Up to " + to.Format(time.DateOnly)
+	case to.IsZero():
+		return "From " + from.Format(time.DateOnly)
+	}
+	return from.Format(time.DateOnly) + " to " + to.Format(time.DateOnly)
+}
+
+// WriteTrialBalancePDF prints a trial balance. Accounts are listed by kind,
+// each kind with its subtotal, and the totals of all accounts at the end.
+// Group accounts are left out; their balances are those of their children.
+func WriteTrialBalancePDF(w io.Writer, letterhead Letterhead, trialBalance database.TrialBalance) error {
+	report := newPDFReport(letterhead, "Trial balance", pdfPeriod(trialBalance.From, trialBalance.To), []pdfColumn{
+		{title: "Account", width: 18},
+		{title: "Name", width: 52},
+		{title: "Opening", width: 27.5, amount: true},
+		{title: "Debit", width: 27.5, amount: true},
+		{title: "Credit", width: 27.5, amount: true},
+		{title: "Closing", width: 27.5, amount: true},
+	})
+
+	for _, kind := range database.Kinds {
+		var opening, debit, credit, closing database.Money
+		found := false
+		for _, line := range trialBalance.Lines {
+			if line.Group || line.Kind != kind {
+				continue
+			}
+			if !found {
+				report.heading(strings.ToUpper(kind[:1]) + kind[1:])
+				found = true
+			}
+			report.row(false, line.Account, line.Name, line.Opening, line.Debit, line.Credit, line.Closing)
+			opening += line.Opening
+			debit += line.Debit
+			credit += line.Credit
+			closing += line.Closing
+		}
+		if found {
+			report.row(true, "", "Subtotal", opening, debit, credit, closing)
+		}
+	}
+
+	report.pdf.Ln(2)
+	report.row(true, "", "Total", trialBalance.TotalOpening, trialBalance.TotalDebit, trialBalance.TotalCredit, trialBalance.TotalClosing)
+	return report.write(w)
+}
+
+var statementColumns = []pdfColumn{
+	{title: "Account", width: 20},
+	{title: "Name", width: 120},
+	{title: "Amount", width: 40, amount: true},
+}
+
+// statementLines prints the lines of a statement section, the children of a
+// group account indented below it.
+func statementLines(report *pdfReport, lines []database.StatementLine, level int) {
+	for _, line := range lines {
+		report.row(false, line.Account, strings.Repeat("    ", level)+line.Name, line.Amount)
+		statementLines(report, line.Children, level+1)
+	}
+}
+
+func statementSection(report *pdfReport, title string, section database.StatementSection) {
+	report.heading(title)
+	statementLines(report, section.Lines, 0)
+	report.row(true, "", "Total "+strings.ToLower(title), section.Total)
+}
+
+// WriteBalanceSheetPDF prints a balance sheet: assets, liabilities and
+// equity with their subtotals.
+func WriteBalanceSheetPDF(w io.Writer, letterhead Letterhead, balanceSheet database.BalanceSheet) error {
+	period := "All bookings"
+	if !balanceSheet.AsOf.IsZero() {
+		period = "As of " + balanceSheet.AsOf.Format(time.DateOnly)
+	}
+	report := newPDFReport(letterhead, "Balance sheet", period, statementColumns)
+
+	statementSection(report, "Assets", balanceSheet.Assets)
+	statementSection(report, "Liabilities", balanceSheet.Liabilities)
+	statementSection(report, "Equity", balanceSheet.Equity)
+
+	report.pdf.Ln(2)
+	report.row(true, "", "Total liabilities and equity", balanceSheet.TotalLiabilitiesAndEquity)
+	return report.write(w)
+}
+
+// WriteProfitAndLossPDF prints a profit and loss statement: revenue and
+// expenses with their subtotals, and the net income.
+func WriteProfitAndLossPDF(w io.Writer, letterhead Letterhead, profitAndLoss database.ProfitAndLoss) error {
+	report := newPDFReport(letterhead, "Profit and loss", pdfPeriod(profitAndLoss.From, profitAndLoss.To), statementColumns)
+
+	statementSection(report, "Revenue", profitAndLoss.Revenue)
+	statementSection(report, "Expenses", profitAndLoss.Expenses)
+
+	report.pdf.Ln(2)
+	report.row(true, "", "Net income", profitAndLoss.NetIncome)
+	return report.write(w)
+}
+
+// WriteAccountLedgerPDF prints the statement of an account: the balance
+// brought forward, every booking with the running balance, the debits and
+// credits of every month and the closing balance.
+func WriteAccountLedgerPDF(w io.Writer, letterhead Letterhead, account database.Account, ledger database.AccountLedger) error {
+	report := newPDFReport(letterhead, fmt.Sprintf("Account statement %d %s", account.ID, account.Name), pdfPeriod(ledger.From, ledger.To), []pdfColumn{
+		{title: "Date", width: 22},
+		{title: "Entry", width: 16},
+		{title: "Description", width: 62},
+		{title: "Debit", width: 26, amount: true},
+		{title: "Credit", width: 26, amount: true},
+		{title: "Balance", width: 28, amount: true},
+	})
+
+	report.row(false, ledger.From, "", "Balance brought forward", "", "", ledger.Opening)
+
+	var debit, credit database.Money
+	for i, line := range ledger.Lines {
+		report.row(false, line.Date, line.EntryID, line.Description, line.Debit, line.Credit, line.Balance)
+		debit += line.Debit
+		credit += line.Credit
+
+		last := i == len(ledger.Lines)-1
+		if last || ledger.Lines[i+1].Date.Format("2006-01") != line.Date.Format("2006-01") {
+			report.row(true, "", "", "Total "+line.Date.Format("January 2006"), debit, credit, line.Balance)
+			debit, credit = 0, 0
+		}
+	}
+
+	report.pdf.Ln(2)
+	report.row(true, "", "", "Closing balance", "", "", ledger.Closing)
+	return report.write(w)
+}
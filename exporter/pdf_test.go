@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPDFAmount(t *testing.T) {
+	assert.Equal(t, "0.05", pdfAmount(5))
+	assert.Equal(t, "999.00", pdfAmount(99900))
+	assert.Equal(t, "1,190.00", pdfAmount(119000))
+	assert.Equal(t, "-12,345,678.90", pdfAmount(-1234567890))
+}
+
+func TestWriteAccountLedgerPDF(t *testing.T) {
+	ledger := database.AccountLedger{
+		From:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		Opening: 10000,
+		Closing: 123005,
+	}
+	for day := 1; day <= 80; day++ {
+		ledger.Lines = append(ledger.Lines, database.AccountLedgerLine{
+			Date:        ledger.From.AddDate(0, 0, day/2),
+			EntryID:     uint(day),
+			Description: "Invoice Müller",
+			Debit:       1000,
+		})
+	}
+
+	var buf bytes.Buffer
+	err := WriteAccountLedgerPDF(&buf, Letterhead{Name: "Bookholder GmbH", Lines: []string{"Hauptstraße 1"}}, database.Account{ID: 1200, Name: "Bank"}, ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, buf.String(), "/Count 3")
+}
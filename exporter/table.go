@@ -0,0 +1,241 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/xuri/excelize/v2"
+)
+
+// Formats of a Table.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format; must be one of csv, jsonl or xlsx")
+
+var contentTypes = map[string]string{
+	FormatCSV:   "text/csv; charset=utf-8",
+	FormatJSONL: "application/x-ndjson",
+	FormatXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatPDF:   "application/pdf",
+}
+
+// ContentType returns the media type of a format, or "" for an unknown one.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// FormatOf returns the format of a media type, as found in an Accept header.
+func FormatOf(contentType string) (string, bool) {
+	switch contentType {
+	case "text/csv":
+		return FormatCSV, true
+	case "application/x-ndjson", "application/jsonl":
+		return FormatJSONL, true
+	case contentTypes[FormatXLSX]:
+		return FormatXLSX, true
+	case contentTypes[FormatPDF]:
+		return FormatPDF, true
+	}
+	return "", false
+}
+
+// Table writes rows under a fixed list of columns. Rows are written as they
+// come and not kept, so a table can take the rows of a cursor of any length.
+// Values are strings, numbers, bools, database.Money and time.Time, which is
+// written as a date.
+type Table interface {
+	Write(values ...any) error
+	Close() error
+}
+
+// NewTable starts a table in the format. The CSV and JSON Lines tables write
+// through to w; the XLSX table keeps its rows in a temporary file and writes
+// the workbook on Close.
+func NewTable(w io.Writer, format string, columns []string) (Table, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVTable(w, columns)
+	case FormatJSONL:
+		return &jsonlTable{w: bufio.NewWriter(w), columns: columns}, nil
+	case FormatXLSX:
+		return newXLSXTable(w, columns)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// text writes a value the way the text formats show it.
+func text(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.DateOnly)
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(value)
+}
+
+type csvTable struct {
+	w *csv.Writer
+}
+
+func newCSVTable(w io.Writer, columns []string) (*csvTable, error) {
+	table := &csvTable{w: csv.NewWriter(w)}
+	err := table.w.Write(columns)
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func (t *csvTable) Write(values ...any) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = text(value)
+	}
+	return t.w.Write(record)
+}
+
+func (t *csvTable) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// jsonlTable writes every row as a JSON object with the columns as keys, in
+// the order of the columns.
+type jsonlTable struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func (t *jsonlTable) Write(values ...any) error {
+	t.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			t.w.WriteByte(',')
+		}
+		t.w.WriteString(strconv.Quote(t.columns[i]))
+		t.w.WriteByte(':')
+
+		var data []byte
+		var err error
+		switch value := value.(type) {
+		case time.Time:
+			data, err = json.Marshal(text(value))
+		default:
+			data, err = json.Marshal(value)
+		}
+		if err != nil {
+			return err
+		}
+		t.w.Write(data)
+	}
+	t.w.WriteString("}\n")
+
+	// flush a full buffer now and then instead of growing it
+	if t.w.Buffered() > 32*1024 {
+		return t.w.Flush()
+	}
+	return nil
+}
+
+func (t *jsonlTable) Close() error {
+	return t.w.Flush()
+}
+
+// xlsxTable writes the rows into the first sheet of a workbook. Amounts are
+// numbers in a currency format and dates are dates, so they can be summed
+// and sorted.
+type xlsxTable struct {
+	w      io.Writer
+	file   *excelize.File
+	sheet  *excelize.StreamWriter
+	row    int
+	amount int
+	date   int
+}
+
+func newXLSXTable(w io.Writer, columns []string) (*xlsxTable, error) {
+	file := excelize.NewFile()
+	table := &xlsxTable{w: w, file: file, row: 1}
+
+	var err error
+	table.sheet, err = file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	format := "#,##0.00"
+	table.amount, err = file.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	table.date, err = file.NewStyle(&excelize.Style{NumFmt: 14})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	err = table.Write(header...)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+func (t *xlsxTable) Write(values ...any) error {
+	cells := make([]any, len(values))
+	for i, value := range values {
+		switch value := value.(type) {
+		case database.Money:
+			cells[i] = excelize.Cell{StyleID: t.amount, Value: float64(value) / 100}
+		case time.Time:
+			if value.IsZero() {
+				cells[i] = nil
+			} else {
+				cells[i] = excelize.Cell{StyleID: t.date, Value: value}
+			}
+		default:
+			cells[i] = value
+		}
+	}
+
+	cell, err := excelize.CoordinatesToCellName(1, t.row)
+	if err != nil {
+		return err
+	}
+	t.row++
+	return t.sheet.SetRow(cell, cells)
+}
+
+func (t *xlsxTable) Close() error {
+	defer t.file.Close()
+
+	err := t.sheet.Flush()
+	if err != nil {
+		return err
+	}
+	return t.file.Write(t.w)
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func writeTable(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	table, err := NewTable(&buf, format, []string{"Date", "Account", "Description", "Debit", "Amount"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range [][]any{
+		{time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), uint(1200), `Invoice "2024-017", ACME`, true, database.Money(119000)},
+		{time.Time{}, uint(4900), "Telephone", false, database.Money(-5995)},
+	} {
+		err = table.Write(row...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = table.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSVTable(t *testing.T) {
+	data := writeTable(t, FormatCSV)
+
+	assert.Equal(t, "Date,Account,Description,Debit,Amount\n2024-03-04,1200,\"Invoice \"\"2024-017\"\", ACME\",true,1190.00\n,4900,Telephone,false,-59.95\n", string(data))
+}
+
+func TestJSONLTable(t *testing.T) {
+	data := writeTable(t, FormatJSONL)
+
+	assert.Equal(t, `{"Date":"2024-03-04","Account":1200,"Description":"Invoice \"2024-017\", ACME","Debit":true,"Amount":1190.00}
+{"Date":"","Account":4900,"Description":"Telephone","Debit":false,"Amount":-59.95}
+`, string(data))
+}
+
+func TestXLSXTable(t *testing.T) {
+	data := writeTable(t, FormatXLSX)
+
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows("Sheet1", excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, []string{"Date", "Account", "Description", "Debit", "Amount"}, rows[0])
+		assert.Equal(t, "1200", rows[1][1])
+		assert.Equal(t, "1190", rows[1][4])
+		assert.Equal(t, "-59.95", rows[2][4])
+	}
+}
+
+func TestNewTableUnknownFormat(t *testing.T) {
+	_, err := NewTable(&bytes.Buffer{}, "pdf", []string{"Date"})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// MaxArchiveContent limits what the files of a ledger archive may unpack to,
+// so a small upload cannot unpack to more than an uploaded archive may hold.
+const MaxArchiveContent = 100 << 20
+
+var ErrArchiveTooLarge = errors.New("archive unpacks to more than 100 MB")
+
+// ParseLedgerArchive reads a ledger archive as exporter.WriteLedgerArchive
+// writes it. The version in manifest.json is checked before any record is
+// read, and a record with a field this version does not know is an error
+// rather than lost. What the records say about each other is left to
+// database.ValidateBackup. An archive whose files unpack to more than
+// MaxArchiveContent is rejected with ErrArchiveTooLarge.
+func ParseLedgerArchive(r io.ReaderAt, size int64) (database.Backup, error) {
+	var backup database.Backup
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return backup, errors.New("not a zip archive")
+	}
+
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	manifest, ok := files["manifest.json"]
+	if !ok {
+		return backup, errors.New("archive has no manifest.json")
+	}
+	// the manifest of any version is read, so a newer one fails on its version
+	f, err := manifest.Open()
+	if err != nil {
+		return backup, fmt.Errorf("manifest.json: %w", err)
+	}
+	var header struct {
+		Version       int
+		SchemaVersion int
+		Ledger        string
+		Created       time.Time
+	}
+	err = json.NewDecoder(io.LimitReader(f, MaxArchiveContent)).Decode(&header)
+	f.Close()
+	if err != nil {
+		return backup, fmt.Errorf("manifest.json: %w", err)
+	}
+	backup.Version, backup.SchemaVersion, backup.Ledger, backup.Created = header.Version, header.SchemaVersion, header.Ledger, header.Created
+
+	if backup.Version != database.BackupVersion {
+		return backup, fmt.Errorf("archive has version %d: %w", backup.Version, database.ErrBackupVersion)
+	}
+	if backup.SchemaVersion < 1 {
+		return backup, errors.New("manifest.json has no schema version")
+	}
+
+	records := map[string]func(*json.Decoder) error{
+		"manifest.json":          nil,
+		"accounts.jsonl":         decodeAll(&backup.Accounts),
+		"transactions.jsonl":     decodeAll(&backup.Transactions),
+		"journal_entries.jsonl":  decodeAll(&backup.JournalEntries),
+		"fiscal_years.jsonl":     decodeAll(&backup.FiscalYears),
+		"opening_balances.jsonl": decodeAll(&backup.OpeningBalances),
+		"members.jsonl":          decodeAll(&backup.Members),
+		"rules.jsonl":            decodeAll(&backup.Rules),
+		"import_profiles.jsonl":  decodeAll(&backup.ImportProfiles),
+	}
+	for name := range files {
+		if _, ok := records[name]; !ok {
+			return backup, fmt.Errorf("unknown file %s in archive", name)
+		}
+	}
+
+	remaining := int64(MaxArchiveContent)
+	for name, decode := range records {
+		file, ok := files[name]
+		if !ok {
+			return backup, fmt.Errorf("archive has no %s", name)
+		}
+		if decode == nil {
+			continue
+		}
+
+		err = readRecords(file, &remaining, decode)
+		if err != nil {
+			return backup, err
+		}
+	}
+	return backup, nil
+}
+
+// readRecords hands the content of a file of the archive to decode. At most
+// remaining bytes are read, whatever the file claims its size is, and what
+// was read is taken off remaining.
+func readRecords(file *zip.File, remaining *int64, decode func(*json.Decoder) error) error {
+	f, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%s: %w", file.Name, err)
+	}
+	defer f.Close()
+
+	limited := &io.LimitedReader{R: f, N: *remaining + 1}
+	decoder := json.NewDecoder(limited)
+	decoder.DisallowUnknownFields()
+	err = decode(decoder)
+	if limited.N == 0 {
+		return ErrArchiveTooLarge
+	}
+	*remaining -= *remaining + 1 - limited.N
+	if err != nil {
+		return fmt.Errorf("%s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// decodeAll decodes every record of a JSON Lines file into records.
+func decodeAll[T any](records *[]T) func(*json.Decoder) error {
+	return func(decoder *json.Decoder) error {
+		for line := 1; decoder.More(); line++ {
+			var record T
+			err := decoder.Decode(&record)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			*records = append(*records, record)
+		}
+		return nil
+	}
+}
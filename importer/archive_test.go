@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/LeRoid-hub/Bookholder-API/exporter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLedgerArchive(t *testing.T) {
+	backup := database.Backup{
+		Version:       database.BackupVersion,
+		SchemaVersion: 1,
+		Ledger:        "Müller GmbH",
+		Created:       time.Date(2024, 4, 2, 9, 30, 0, 0, time.UTC),
+		Accounts: []database.Account{
+			{ID: 1200, Name: "Bank", Kind: database.KindAsset},
+			{ID: 8400, Name: "Revenue", Kind: database.KindRevenue},
+		},
+		Transactions: []database.Transaction{
+			{ID: 17, Amount: 119000, Debit: true, Account: 1200, OffsetAccount: 8400, Date: date(2024, 3, 4), Description: "Invoice 2024-017", TaxCode: "USt19", Status: database.StatusPosted},
+		},
+		Members: []database.Member{{User: "5b1e0b4e-4f7c-4a7e-9d57-c0b3a6a5d1f2", Name: "Ledger Owner", Role: database.RoleOwner}},
+	}
+
+	var buf bytes.Buffer
+	err := exporter.WriteLedgerArchive(&buf, backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseLedgerArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, backup, parsed)
+}
+
+// archive zips files given as name and content.
+func archive(t *testing.T, files ...string) *bytes.Reader {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		f, err := w.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(files[i+1]))
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestParseLedgerArchiveRejected(t *testing.T) {
+	_, err := ParseLedgerArchive(bytes.NewReader([]byte("not a zip")), 9)
+	assert.Error(t, err)
+
+	// a newer archive fails on its version, whatever else it holds
+	r := archive(t, "manifest.json", `{"Version":3,"Ledger":"Books","Checksum":"abc"}`)
+	_, err = ParseLedgerArchive(r, r.Size())
+	assert.ErrorIs(t, err, database.ErrBackupVersion)
+
+	r = archive(t, "manifest.json", `{"Version":2,"Ledger":"Books"}`)
+	_, err = ParseLedgerArchive(r, r.Size())
+	assert.ErrorContains(t, err, "no schema version")
+
+	files := []string{"manifest.json", `{"Version":2,"SchemaVersion":2,"Ledger":"Books"}`}
+	for _, name := range []string{"accounts.jsonl", "transactions.jsonl", "journal_entries.jsonl", "fiscal_years.jsonl", "opening_balances.jsonl", "members.jsonl", "rules.jsonl", "import_profiles.jsonl"} {
+		files = append(files, name, "")
+	}
+
+	r = archive(t, files...)
+	_, err = ParseLedgerArchive(r, r.Size())
+	assert.NoError(t, err)
+
+	r = archive(t, append(files, "bank_lines.jsonl", "")...)
+	_, err = ParseLedgerArchive(r, r.Size())
+	assert.ErrorContains(t, err, "unknown file bank_lines.jsonl")
+
+	files[3] = `{"ID":1200,"Name":"Bank","Kind":"asset"}` + "\n" + `{"ID":8400,"Name":"Revenue","Kind":"revenue","Currency":"EUR"}`
+	r = archive(t, files...)
+	_, err = ParseLedgerArchive(r, r.Size())
+	assert.ErrorContains(t, err, "accounts.jsonl: line 2")
+}
+
+func TestParseLedgerArchiveTooLarge(t *testing.T) {
+	files := []string{"manifest.json", `{"Version":2,"SchemaVersion":2,"Ledger":"Books"}`}
+	for _, name := range []string{"accounts.jsonl", "transactions.jsonl", "journal_entries.jsonl", "fiscal_years.jsonl", "opening_balances.jsonl", "members.jsonl", "rules.jsonl", "import_profiles.jsonl"} {
+		files = append(files, name, "")
+	}
+
+	// blank lines are valid JSON Lines and pack to almost nothing
+	files[3] = strings.Repeat("\n", MaxArchiveContent/2)
+	r := archive(t, files...)
+	_, err := ParseLedgerArchive(r, r.Size())
+	assert.NoError(t, err)
+
+	// the limit holds for all files together
+	files[5] = files[3] + "\n"
+	r = archive(t, files...)
+	_, err = ParseLedgerArchive(r, r.Size())
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+}
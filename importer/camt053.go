@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 bank to customer
+// statement the importer reads. Tags are matched without their namespace,
+// so every version of the message is accepted.
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount        string        `xml:"Amt"`
+	CreditDebit   string        `xml:"CdtDbtInd"`
+	Reversal      bool          `xml:"RvslInd"`
+	Status        camtStatus    `xml:"Sts"`
+	BookingDate   camtDate      `xml:"BookgDt"`
+	ValueDate     camtDate      `xml:"ValDt"`
+	AdditionalInf string        `xml:"AddtlNtryInf"`
+	Details       []camtDetails `xml:"NtryDtls>TxDtls"`
+}
+
+// camtStatus is a plain code up to version 2 and a Cd element from
+// version 8 on.
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+func (status camtStatus) String() string {
+	return strings.TrimSpace(status.Text + status.Code)
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+type camtDetails struct {
+	EndToEndID    string    `xml:"Refs>EndToEndId"`
+	Amount        string    `xml:"AmtDtls>TxAmt>Amt"`
+	Debtor        camtParty `xml:"RltdPties>Dbtr"`
+	DebtorIBAN    string    `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	Creditor      camtParty `xml:"RltdPties>Cdtr"`
+	CreditorIBAN  string    `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+	Unstructured  []string  `xml:"RmtInf>Ustrd"`
+	Structured    []string  `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AdditionalInf string    `xml:"AddtlTxInf"`
+}
+
+// ParseCAMT053 reads the booked entries of a camt.053 statement. Batch
+// entries that list the amount of every transaction are split into one
+// line per transaction; pending entries are left out.
+func ParseCAMT053(r io.Reader) ([]database.BankLine, error) {
+	var document camtDocument
+	err := xml.NewDecoder(r).Decode(&document)
+	if err != nil {
+		return nil, fmt.Errorf("reading camt.053: %w", err)
+	}
+
+	var lines []database.BankLine
+	for _, statement := range document.Statements {
+		for i, entry := range statement.Entries {
+			status := entry.Status.String()
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			entryLines, err := parseCAMTEntry(entry)
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %w", i+1, err)
+			}
+			lines = append(lines, entryLines...)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return lines, nil
+}
+
+func parseCAMTEntry(entry camtEntry) ([]database.BankLine, error) {
+	var base database.BankLine
+	var err error
+
+	base.BookingDate, err = entry.BookingDate.parse()
+	if err != nil {
+		return nil, fmt.Errorf("booking date: %w", err)
+	}
+	base.ValueDate, err = entry.ValueDate.parse()
+	if err != nil {
+		return nil, fmt.Errorf("value date: %w", err)
+	}
+
+	// money goes out on debits, and on reversed credits
+	outgoing := entry.CreditDebit == "DBIT"
+	if entry.Reversal {
+		outgoing = !outgoing
+	}
+
+	details := entry.Details
+	split := len(details) > 1
+	for _, detail := range details {
+		if detail.Amount == "" {
+			split = false
+		}
+	}
+	if !split {
+		var detail camtDetails
+		if len(details) > 0 {
+			detail = details[0]
+		}
+		detail.Amount = entry.Amount
+		details = []camtDetails{detail}
+	}
+
+	var lines []database.BankLine
+	for _, detail := range details {
+		line := base
+		line.Amount, err = parseAmount(detail.Amount, false)
+		if err != nil {
+			return nil, err
+		}
+		if outgoing {
+			line.Amount = -line.Amount
+		}
+
+		// the counterparty is whoever is on the other side of the payment
+		party, iban := detail.Debtor, detail.DebtorIBAN
+		if entry.CreditDebit == "DBIT" {
+			party, iban = detail.Creditor, detail.CreditorIBAN
+		}
+		line.Counterparty = strings.TrimSpace(party.Name + party.PartyName)
+		line.CounterpartyIBAN = strings.ReplaceAll(iban, " ", "")
+
+		line.Remittance = strings.TrimSpace(strings.Join(append(detail.Unstructured, detail.Structured...), " "))
+		if line.Remittance == "" {
+			line.Remittance = strings.TrimSpace(detail.AdditionalInf)
+		}
+		if line.Remittance == "" {
+			line.Remittance = strings.TrimSpace(entry.AdditionalInf)
+		}
+
+		if detail.EndToEndID != "NOTPROVIDED" {
+			line.Reference = strings.TrimSpace(detail.EndToEndID)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func (date camtDate) parse() (time.Time, error) {
+	value := date.Date
+	if value == "" && len(date.DateTime) >= 10 {
+		value = date.DateTime[:10]
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseCAMT053(t *testing.T) {
+	file, err := os.Open("testdata/camt053.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines, err := ParseCAMT053(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the batch is split and the pending card payment is left out
+	if !assert.Len(t, lines, 5) {
+		return
+	}
+
+	assert.Equal(t, database.BankLine{
+		BookingDate:      date(2024, 3, 1),
+		ValueDate:        date(2024, 3, 1),
+		Amount:           -8900,
+		Counterparty:     "Stadtwerke Münster GmbH",
+		CounterpartyIBAN: "DE02120300000000202051",
+		Remittance:       "Abschlag Strom Maerz 2024",
+		Reference:        "SW-2024-03-000123",
+	}, lines[0])
+
+	assert.Equal(t, database.Money(119000), lines[1].Amount)
+	assert.Equal(t, "Muster KG", lines[1].Counterparty)
+	assert.Equal(t, "DE75512108001245126199", lines[1].CounterpartyIBAN)
+	assert.Equal(t, "Rechnung 2024-17 Beratung", lines[1].Remittance)
+
+	assert.Equal(t, database.Money(10000), lines[2].Amount)
+	assert.Equal(t, "", lines[2].Reference)
+	assert.Equal(t, database.Money(20000), lines[3].Amount)
+	assert.Equal(t, "RF18539007547034", lines[3].Remittance)
+	assert.Equal(t, "MB-2024-8", lines[3].Reference)
+
+	// a reversed credit takes the money back out
+	assert.Equal(t, database.Money(-8900), lines[4].Amount)
+	assert.Equal(t, date(2024, 3, 29), lines[4].BookingDate)
+	assert.Equal(t, "RUECKLASTSCHRIFT", lines[4].Remittance)
+}
+
+func TestParseCAMT053Invalid(t *testing.T) {
+	_, err := ParseCAMT053(strings.NewReader("Date;Amount\n"))
+	assert.Error(t, err)
+
+	_, err = ParseCAMT053(strings.NewReader("<Document><BkToCstmrStmt><Stmt></Stmt></BkToCstmrStmt></Document>"))
+	assert.ErrorIs(t, err, ErrNoLines)
+}
@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// ParseCSV reads a CSV export as described by the profile. SkipRows lines
+// before the header are skipped, as are empty rows and rows without a
+// booking date, which banks use for opening and closing balances.
+func ParseCSV(r io.Reader, profile database.ImportProfile) ([]database.BankLine, error) {
+	err := database.ValidateImportProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err = decode(r, profile.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lines before the header are free text, so they are skipped before
+	// the CSV reader sees them
+	buffered := bufio.NewReader(r)
+	for i := 0; i < profile.SkipRows; i++ {
+		_, err = buffered.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("skipping line %d: %w", i+1, err)
+		}
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = ','
+	if profile.Separator != "" {
+		reader.Comma = []rune(profile.Separator)[0]
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for _, name := range []string{profile.BookingDate, profile.ValueDate, profile.Amount, profile.Debit, profile.Credit, profile.Indicator, profile.Counterparty, profile.IBAN, profile.Remittance, profile.Reference} {
+		if _, ok := columns[name]; name != "" && !ok {
+			return nil, fmt.Errorf("column %q is missing from the header", name)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if name == "" || !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	layout := dateLayout(profile.DateFormat)
+	var lines []database.BankLine
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row, _ := reader.FieldPos(0)
+		row += profile.SkipRows
+
+		if field(record, profile.BookingDate) == "" {
+			continue
+		}
+
+		line, err := parseCSVRecord(record, profile, layout, field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", row, err)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return lines, nil
+}
+
+func parseCSVRecord(record []string, profile database.ImportProfile, layout string, field func([]string, string) string) (database.BankLine, error) {
+	line := database.BankLine{
+		Counterparty:     field(record, profile.Counterparty),
+		CounterpartyIBAN: strings.ReplaceAll(field(record, profile.IBAN), " ", ""),
+		Remittance:       field(record, profile.Remittance),
+		Reference:        field(record, profile.Reference),
+	}
+
+	var err error
+	line.BookingDate, err = time.Parse(layout, field(record, profile.BookingDate))
+	if err != nil {
+		return line, fmt.Errorf("booking date: %w", err)
+	}
+
+	if value := field(record, profile.ValueDate); value != "" {
+		line.ValueDate, err = time.Parse(layout, value)
+		if err != nil {
+			return line, fmt.Errorf("value date: %w", err)
+		}
+	}
+
+	if profile.Amount != "" {
+		line.Amount, err = parseAmount(field(record, profile.Amount), profile.DecimalComma)
+		if err != nil {
+			return line, err
+		}
+
+		if profile.Indicator != "" {
+			if line.Amount < 0 {
+				line.Amount = -line.Amount
+			}
+			if strings.EqualFold(field(record, profile.Indicator), profile.DebitIndicator) {
+				line.Amount = -line.Amount
+			}
+		}
+	} else {
+		// either column may be empty; withdrawals count as money going out
+		// whatever sign the bank prints
+		for _, column := range []string{profile.Credit, profile.Debit} {
+			value := field(record, column)
+			if value == "" {
+				continue
+			}
+
+			amount, err := parseAmount(value, profile.DecimalComma)
+			if err != nil {
+				return line, err
+			}
+			if amount < 0 {
+				amount = -amount
+			}
+			if column == profile.Debit {
+				amount = -amount
+			}
+			line.Amount += amount
+		}
+	}
+
+	if profile.InvertSign {
+		line.Amount = -line.Amount
+	}
+
+	if line.Amount == 0 {
+		return line, fmt.Errorf("amount cannot be 0")
+	}
+	return line, nil
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var sparkasseProfile = database.ImportProfile{
+	Name:           "Sparkasse",
+	Separator:      ";",
+	SkipRows:       3,
+	Encoding:       "latin1",
+	DateFormat:     "DD.MM.YYYY",
+	DecimalComma:   true,
+	BookingDate:    "Buchungstag",
+	ValueDate:      "Valuta",
+	Amount:         "Betrag",
+	Indicator:      "Soll/Haben",
+	DebitIndicator: "S",
+	Counterparty:   "Empfänger/Zahlungspflichtiger",
+	IBAN:           "IBAN",
+	Remittance:     "Verwendungszweck",
+}
+
+func TestParseCSVIndicator(t *testing.T) {
+	file, err := os.Open("testdata/sparkasse.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines, err := ParseCSV(file, sparkasseProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the closing balance has no booking date and is skipped
+	if assert.Len(t, lines, 4) {
+		assert.Equal(t, database.Money(-8900), lines[0].Amount)
+		assert.Equal(t, "Stadtwerke Münster", lines[0].Counterparty)
+		assert.Equal(t, "DE02120300000000202051", lines[0].CounterpartyIBAN)
+		assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), lines[0].BookingDate)
+		assert.Equal(t, database.Money(119000), lines[1].Amount)
+		assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), lines[1].ValueDate)
+		assert.Equal(t, lines[2], lines[3])
+	}
+}
+
+func TestParseCSVDebitCreditColumns(t *testing.T) {
+	file, err := os.Open("testdata/simple.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines, err := ParseCSV(file, database.ImportProfile{Name: "Simple", BookingDate: "Date", Debit: "Withdrawals", Credit: "Deposits", Remittance: "Description", Reference: "Reference"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, database.Money(-125000), lines[0].Amount)
+		assert.Equal(t, database.Money(340050), lines[1].Amount)
+		assert.Equal(t, "R-2", lines[1].Reference)
+	}
+}
+
+func TestParseCSVInvertSign(t *testing.T) {
+	lines, err := ParseCSV(strings.NewReader("Date,Amount\n2024-03-01,25.00\n"), database.ImportProfile{Name: "Card", BookingDate: "Date", Amount: "Amount", InvertSign: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, database.Money(-2500), lines[0].Amount)
+}
+
+func TestParseCSVMissingColumn(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("Date,Amount\n2024-03-01,25.00\n"), database.ImportProfile{Name: "Wrong", BookingDate: "Booked", Amount: "Amount"})
+
+	assert.ErrorContains(t, err, "Booked")
+}
+
+func TestParseCSVInvalidAmount(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("Date,Amount\n2024-03-01,abc\n"), database.ImportProfile{Name: "Simple", BookingDate: "Date", Amount: "Amount"})
+
+	assert.ErrorIs(t, err, database.ErrInvalidMoney)
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]database.Money{
+		"1.234,56":  123456,
+		"-1.234,56": -123456,
+		"12,50-":    -1250,
+		"0,05":      5,
+	}
+
+	for input, expected := range cases {
+		amount, err := parseAmount(input, true)
+		if err != nil {
+			t.Error(input, err)
+		}
+		assert.Equal(t, expected, amount, input)
+	}
+}
@@ -0,0 +1,98 @@
+// Package importer reads bank statements into database.BankLine values,
+// which are then staged with database.StageBankLines, and ledger archives
+// into a database.Backup to restore. The parsers only read; they never touch
+// the database.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+var ErrNoLines = errors.New("statement contains no lines")
+
+// parseAmount reads an amount as banks print it: with thousands separators,
+// a decimal comma if decimalComma is set, and the sign in front or behind.
+func parseAmount(value string, decimalComma bool) (database.Money, error) {
+	value = strings.TrimSpace(value)
+	value = strings.ReplaceAll(value, " ", "")
+	value = strings.ReplaceAll(value, "'", "")
+
+	if strings.HasSuffix(value, "-") || strings.HasSuffix(value, "+") {
+		value = value[len(value)-1:] + value[:len(value)-1]
+	}
+
+	if decimalComma {
+		value = strings.ReplaceAll(value, ".", "")
+		value = strings.ReplaceAll(value, ",", ".")
+	} else {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	amount, err := database.ParseMoney(value)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q: %w", value, err)
+	}
+	return amount, nil
+}
+
+// dateLayouts translates the date formats people write, like DD.MM.YYYY, into
+// Go layouts. Formats that are already Go layouts pass unchanged.
+var dateLayouts = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02")
+
+func dateLayout(format string) string {
+	if format == "" {
+		return "2006-01-02"
+	}
+	return dateLayouts.Replace(format)
+}
+
+// decode returns the statement as UTF-8 without a byte order mark. Latin-1
+// maps every byte to the rune of the same value.
+func decode(r io.Reader, encoding string) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if encoding == "latin1" {
+		var buf bytes.Buffer
+		for _, b := range data {
+			buf.WriteRune(rune(b))
+		}
+		return &buf, nil
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("statement is not valid UTF-8; try the latin1 encoding")
+	}
+	return bytes.NewReader(data), nil
+}
+
+// readText reads a statement in a format that does not declare its encoding.
+// Anything that is not valid UTF-8 is read as Latin-1, which is what older
+// banking and personal finance software writes.
+func readText(r io.Reader) (io.Reader, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utf8.Valid(data) {
+		return decode(bytes.NewReader(data), "latin1")
+	}
+	return bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), nil
+}
+
+// parseAnyAmount reads an amount whose decimal separator is not known up
+// front: the separator that comes last is taken as the decimal one.
+func parseAnyAmount(value string) (database.Money, error) {
+	return parseAmount(value, strings.LastIndex(value, ",") > strings.LastIndex(value, "."))
+}
@@ -0,0 +1,240 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+var (
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+
+	// mt940Line is the first line of field 61: value date, optional entry
+	// date, debit/credit mark, optional funds code, amount, transaction type
+	// and references.
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})(.*)$`)
+
+	// mt940Structured matches field 86 in the subfield layout of German
+	// banks: a business transaction code followed by ?NN subfields.
+	mt940Structured = regexp.MustCompile(`^\d{3}\?`)
+
+	// sepaKeywords start the parts of a SEPA remittance text.
+	sepaKeywords = regexp.MustCompile(`(EREF|KREF|MREF|CRED|DEBT|SVWZ|ABWA|ABWE|IBAN|BIC)\+`)
+)
+
+// mt940Field is one tag of a statement with its continuation lines.
+type mt940Field struct {
+	tag   string
+	lines []string
+}
+
+// ParseMT940 reads the statement lines of a SWIFT MT940 file. Field 61
+// gives the dates and the amount of a line, the following field 86 the
+// counterparty and the remittance text; the subfields and SEPA keywords
+// German banks use there are split up, other banks' text is kept as is.
+func ParseMT940(r io.Reader) ([]database.BankLine, error) {
+	fields, err := readMT940(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []database.BankLine
+	for i, field := range fields {
+		if field.tag != "61" {
+			continue
+		}
+
+		line, err := parseMT940Line(field.lines[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", len(lines)+1, err)
+		}
+
+		if i+1 < len(fields) && fields[i+1].tag == "86" {
+			parseMT940Information(&line, fields[i+1].lines)
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return lines, nil
+}
+
+// readMT940 splits a statement into its fields. The header blocks of the
+// SWIFT envelope and the dash that ends every message are skipped.
+func readMT940(r io.Reader) ([]mt940Field, error) {
+	text, err := readText(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []mt940Field
+	scanner := bufio.NewScanner(text)
+	for scanner.Scan() {
+		value := strings.TrimRight(scanner.Text(), " \r")
+		if match := mt940Tag.FindStringSubmatch(value); match != nil {
+			fields = append(fields, mt940Field{
+				tag:   match[1],
+				lines: []string{value[len(match[0]):]},
+			})
+			continue
+		}
+
+		if value == "" || value == "-" || strings.HasPrefix(value, "-}") || strings.HasPrefix(value, "{") {
+			continue
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		last := &fields[len(fields)-1]
+		last.lines = append(last.lines, value)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("not an MT940 statement")
+	}
+	return fields, nil
+}
+
+func parseMT940Line(value string) (database.BankLine, error) {
+	var line database.BankLine
+
+	match := mt940Line.FindStringSubmatch(value)
+	if match == nil {
+		return line, fmt.Errorf("invalid statement line %q", value)
+	}
+
+	var err error
+	line.ValueDate, err = time.Parse("060102", match[1])
+	if err != nil {
+		return line, fmt.Errorf("value date: %w", err)
+	}
+
+	line.BookingDate = line.ValueDate
+	if match[2] != "" {
+		line.BookingDate, err = entryDate(line.ValueDate, match[2])
+		if err != nil {
+			return line, fmt.Errorf("entry date: %w", err)
+		}
+	}
+
+	line.Amount, err = parseAmount(match[5], true)
+	if err != nil {
+		return line, err
+	}
+	// money goes out on debits, and on reversed credits
+	if match[3] == "D" || match[3] == "RC" {
+		line.Amount = -line.Amount
+	}
+
+	reference, _, _ := strings.Cut(match[7], "//")
+	if reference != "NONREF" {
+		line.Reference = reference
+	}
+	return line, nil
+}
+
+// entryDate completes the entry date, which comes without a year, with the
+// year of the value date. Lines around the turn of the year can be booked in
+// the year before or after their value date.
+func entryDate(valueDate time.Time, monthDay string) (time.Time, error) {
+	month, err := strconv.Atoi(monthDay[:2])
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := strconv.Atoi(monthDay[2:])
+	if err != nil {
+		return time.Time{}, err
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("invalid date %q", monthDay)
+	}
+
+	year := valueDate.Year()
+	switch {
+	case month-int(valueDate.Month()) > 6:
+		year--
+	case int(valueDate.Month())-month > 6:
+		year++
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseMT940Information fills in the counterparty and the remittance text
+// from field 86.
+func parseMT940Information(line *database.BankLine, lines []string) {
+	if !mt940Structured.MatchString(lines[0]) {
+		line.Remittance = strings.Join(lines, " ")
+		parseSEPARemittance(line)
+		return
+	}
+
+	// subfields may be wrapped at any place, so the lines are joined as they are
+	var remittance, name strings.Builder
+	for _, subfield := range strings.Split(strings.Join(lines, ""), "?")[1:] {
+		if len(subfield) < 2 {
+			continue
+		}
+
+		code, value := subfield[:2], subfield[2:]
+		switch {
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			remittance.WriteString(value)
+		case code == "31":
+			line.CounterpartyIBAN = strings.ReplaceAll(value, " ", "")
+		case code == "32", code == "33":
+			name.WriteString(value)
+		}
+	}
+
+	line.Counterparty = strings.TrimSpace(name.String())
+	line.Remittance = remittance.String()
+	parseSEPARemittance(line)
+}
+
+// parseSEPARemittance splits a remittance text with SEPA keywords, like
+// "EREF+4711 SVWZ+Invoice 17", into the end-to-end reference and the
+// remittance information. Texts without keywords are left alone.
+func parseSEPARemittance(line *database.BankLine) {
+	text := line.Remittance
+	matches := sepaKeywords.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		line.Remittance = strings.TrimSpace(text)
+		return
+	}
+
+	parts := make(map[string]string)
+	for i, match := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		parts[text[match[2]:match[3]]] = strings.TrimSpace(text[match[1]:end])
+	}
+
+	if reference := parts["EREF"]; reference != "" && reference != "NOTPROVIDED" {
+		line.Reference = reference
+	}
+	if line.CounterpartyIBAN == "" {
+		line.CounterpartyIBAN = parts["IBAN"]
+	}
+
+	remittance, ok := parts["SVWZ"]
+	if !ok {
+		remittance = strings.TrimSpace(text[:matches[0][0]])
+	}
+	line.Remittance = remittance
+}
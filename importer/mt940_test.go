@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMT940(t *testing.T) {
+	file, err := os.Open("testdata/mt940.sta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines, err := ParseMT940(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Len(t, lines, 4) {
+		return
+	}
+
+	assert.Equal(t, database.BankLine{
+		BookingDate:      date(2024, 3, 1),
+		ValueDate:        date(2024, 3, 1),
+		Amount:           -8900,
+		Counterparty:     "Stadtwerke Münster GmbH",
+		CounterpartyIBAN: "DE02120300000000202051",
+		Remittance:       "Abschlag Strom Maerz 2024",
+		Reference:        "SW-2024-03-000123",
+	}, lines[0])
+
+	assert.Equal(t, database.Money(119000), lines[1].Amount)
+	assert.Equal(t, "Muster KG", lines[1].Counterparty)
+	assert.Equal(t, "Rechnung 2024-17 Beratung", lines[1].Remittance)
+	assert.Equal(t, "RE-2024-17", lines[1].Reference)
+
+	// field 86 without subfields is kept as text
+	assert.Equal(t, database.Money(-45000), lines[2].Amount)
+	assert.Equal(t, "Rent March 2024", lines[2].Remittance)
+	assert.Equal(t, "LEASE-03", lines[2].Reference)
+
+	// booked in the new year with a value date in the old one
+	assert.Equal(t, date(2023, 12, 29), lines[3].ValueDate)
+	assert.Equal(t, date(2024, 1, 2), lines[3].BookingDate)
+	assert.Equal(t, "Zinsen 2023", lines[3].Remittance)
+}
+
+func TestParseMT940Invalid(t *testing.T) {
+	_, err := ParseMT940(strings.NewReader(":20:STARTUMS\n:61:240301C\n"))
+	assert.ErrorContains(t, err, "invalid statement line")
+
+	_, err = ParseMT940(strings.NewReader("Date;Amount\n"))
+	assert.Error(t, err)
+}
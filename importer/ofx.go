@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// ParseOFX reads the transactions of an OFX bank or credit card statement.
+// Both the SGML of OFX 1 and the XML of OFX 2 are read: every tag opens an
+// element, and the text up to the next tag is its value, whether it is
+// closed or not. FITID, the id the bank gives every transaction, is kept
+// as the reference, so a statement downloaded twice is recognised.
+func ParseOFX(r io.Reader) ([]database.BankLine, error) {
+	data, err := readText(r)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+
+	text := string(content)
+	start := strings.Index(strings.ToUpper(text), "<OFX>")
+	if start < 0 {
+		return nil, errors.New("not an OFX statement")
+	}
+	text = text[start:]
+
+	var lines []database.BankLine
+	var fields map[string]string
+	for {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '>')
+		if end < 0 {
+			break
+		}
+
+		tag := strings.ToUpper(text[open+1 : open+end])
+		text = text[open+end+1:]
+
+		value := text
+		if next := strings.IndexByte(text, '<'); next >= 0 {
+			value = text[:next]
+		}
+		value = html.UnescapeString(strings.TrimSpace(value))
+
+		switch {
+		case tag == "STMTTRN":
+			fields = make(map[string]string)
+		case tag == "/STMTTRN" && fields != nil:
+			line, err := ofxLine(fields)
+			if err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", len(lines)+1, err)
+			}
+			lines = append(lines, line)
+			fields = nil
+		case fields != nil && !strings.HasPrefix(tag, "/"):
+			// the first value wins, so the NAME of the transaction is not
+			// replaced by the NAME in a PAYEE aggregate
+			if _, ok := fields[tag]; !ok {
+				fields[tag] = value
+			}
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return lines, nil
+}
+
+func ofxLine(fields map[string]string) (database.BankLine, error) {
+	var line database.BankLine
+	var err error
+
+	line.BookingDate, err = parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return line, fmt.Errorf("posted date: %w", err)
+	}
+
+	if fields["DTAVAIL"] != "" {
+		line.ValueDate, err = parseOFXDate(fields["DTAVAIL"])
+		if err != nil {
+			return line, fmt.Errorf("available date: %w", err)
+		}
+	}
+
+	line.Amount, err = parseAnyAmount(fields["TRNAMT"])
+	if err != nil {
+		return line, err
+	}
+
+	line.Counterparty = fields["NAME"]
+	line.Remittance = fields["MEMO"]
+	line.Reference = fields["FITID"]
+	return line, nil
+}
+
+// parseOFXDate reads the date of an OFX datetime like 20240301120000.000[-5:EST];
+// the time of day is dropped.
+func parseOFXDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return time.Parse("20060102", value[:8])
+}
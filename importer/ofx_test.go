@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOFX(t *testing.T) {
+	file, err := os.Open("testdata/statement.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines, err := ParseOFX(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Len(t, lines, 3) {
+		return
+	}
+
+	assert.Equal(t, database.BankLine{
+		BookingDate:  date(2024, 3, 2),
+		Amount:       -4217,
+		Counterparty: "Corner Grocery & Deli",
+		Remittance:   "POS purchase",
+		Reference:    "202403020001",
+	}, lines[0])
+
+	// the name of the payee aggregate is used when there is no other
+	assert.Equal(t, "ACME Payroll", lines[1].Counterparty)
+	assert.Equal(t, date(2024, 3, 16), lines[1].ValueDate)
+	assert.Equal(t, database.Money(250000), lines[1].Amount)
+
+	// the file is Windows-1252
+	assert.Equal(t, "Café Rent LLC", lines[2].Counterparty)
+}
+
+func TestParseOFXVersion2(t *testing.T) {
+	statement := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240305</DTPOSTED><TRNAMT>-19.99</TRNAMT><FITID>A1</FITID><NAME>Streaming Service</NAME></STMTTRN>
+</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+	lines, err := ParseOFX(strings.NewReader(statement))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, database.Money(-1999), lines[0].Amount)
+		assert.Equal(t, "Streaming Service", lines[0].Counterparty)
+		assert.Equal(t, date(2024, 3, 5), lines[0].BookingDate)
+	}
+}
+
+func TestParseOFXInvalid(t *testing.T) {
+	_, err := ParseOFX(strings.NewReader("Date,Amount\n"))
+	assert.Error(t, err)
+
+	_, err = ParseOFX(strings.NewReader("<OFX><STMTTRN><DTPOSTED>2024<TRNAMT>1.00</STMTTRN></OFX>"))
+	assert.ErrorContains(t, err, "posted date")
+}
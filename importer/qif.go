@@ -0,0 +1,158 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+)
+
+// qifAccountTypes are the QIF sections that hold the transactions of one
+// account. Investment, category and memorized sections are skipped.
+var qifAccountTypes = map[string]bool{
+	"bank":  true,
+	"cash":  true,
+	"ccard": true,
+	"oth a": true,
+	"oth l": true,
+}
+
+// ParseQIF reads the transactions of a QIF export. QIF dates carry no
+// format, so dateOrder tells the order of month, day and year: MDY, the
+// default, DMY or YMD. Split lines are left out; a transaction is read with
+// its total amount.
+func ParseQIF(r io.Reader, dateOrder string) ([]database.BankLine, error) {
+	if dateOrder == "" {
+		dateOrder = "MDY"
+	}
+	if dateOrder != "MDY" && dateOrder != "DMY" && dateOrder != "YMD" {
+		return nil, fmt.Errorf("invalid date order %q; must be MDY, DMY or YMD", dateOrder)
+	}
+
+	text, err := readText(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []database.BankLine
+	var fields map[byte]string
+	inAccount := false
+	scanner := bufio.NewScanner(text)
+	for number := 1; scanner.Scan(); number++ {
+		value := strings.TrimRight(scanner.Text(), " \r")
+		if value == "" {
+			continue
+		}
+
+		if value[0] == '!' {
+			section, _ := strings.CutPrefix(value, "!Type:")
+			inAccount = qifAccountTypes[strings.ToLower(strings.TrimSpace(section))]
+			fields = nil
+			continue
+		}
+		if !inAccount {
+			continue
+		}
+
+		if value[0] == '^' {
+			if fields == nil {
+				continue
+			}
+
+			line, err := qifLine(fields, dateOrder)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			lines = append(lines, line)
+			fields = nil
+			continue
+		}
+
+		if fields == nil {
+			fields = make(map[byte]string)
+		}
+		// split lines repeat S, E and $, the first of every code is kept
+		if _, ok := fields[value[0]]; !ok {
+			fields[value[0]] = strings.TrimSpace(value[1:])
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrNoLines
+	}
+	return lines, nil
+}
+
+func qifLine(fields map[byte]string, dateOrder string) (database.BankLine, error) {
+	var line database.BankLine
+	var err error
+
+	line.BookingDate, err = parseQIFDate(fields['D'], dateOrder)
+	if err != nil {
+		return line, err
+	}
+
+	amount, ok := fields['T']
+	if !ok {
+		amount = fields['U']
+	}
+	line.Amount, err = parseAnyAmount(amount)
+	if err != nil {
+		return line, err
+	}
+
+	line.Counterparty = fields['P']
+	line.Remittance = fields['M']
+	line.Reference = fields['N']
+	return line, nil
+}
+
+// parseQIFDate reads the dates QIF exports write, like 3/ 1'24, 03/01/2024
+// or 01.03.24. Quicken marks years from 2000 on with an apostrophe; other
+// two-digit years below 70 are taken as 20xx as well.
+func parseQIFDate(value string, dateOrder string) (time.Time, error) {
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+
+	var year, month, day int
+	for i, field := range dateOrder {
+		number, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+
+		switch field {
+		case 'Y':
+			year = number
+			if len(parts[i]) <= 2 {
+				year += 1900
+				if number < 70 || strings.Contains(value, "'") {
+					year += 100
+				}
+			}
+		case 'M':
+			month = number
+		case 'D':
+			day = number
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || int(date.Month()) != month {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
@@ -0,0 +1,63 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQIF(t *testing.T) {
+	file, err := os.Open("testdata/statement.qif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines, err := ParseQIF(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the account and category sections are skipped
+	if !assert.Len(t, lines, 3) {
+		return
+	}
+
+	assert.Equal(t, database.BankLine{
+		BookingDate:  date(2024, 3, 2),
+		Amount:       -4217,
+		Counterparty: "Corner Grocery",
+		Remittance:   "POS purchase",
+	}, lines[0])
+
+	assert.Equal(t, database.Money(250000), lines[1].Amount)
+	assert.Equal(t, date(2024, 3, 15), lines[1].BookingDate)
+
+	// a split transaction is read with its total
+	assert.Equal(t, database.Money(-120000), lines[2].Amount)
+	assert.Equal(t, "1043", lines[2].Reference)
+}
+
+func TestParseQIFDateOrder(t *testing.T) {
+	lines, err := ParseQIF(strings.NewReader("!Type:Bank\nD01.03.24\nT-12,50\n^\n"), "DMY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, date(2024, 3, 1), lines[0].BookingDate)
+	assert.Equal(t, database.Money(-1250), lines[0].Amount)
+
+	_, err = ParseQIF(strings.NewReader("!Type:Bank\nD13/01/2024\nT1.00\n^\n"), "MDY")
+	assert.ErrorContains(t, err, "invalid date")
+
+	_, err = ParseQIF(strings.NewReader("!Type:Bank\n"), "DDMMYY")
+	assert.ErrorContains(t, err, "date order")
+}
+
+func TestParseQIFInvestment(t *testing.T) {
+	_, err := ParseQIF(strings.NewReader("!Type:Invst\nD3/1'24\nNBuy\nT100.00\n^\n"), "")
+	assert.ErrorIs(t, err, ErrNoLines)
+}
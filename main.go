@@ -1,14 +1,28 @@
 package main
 
 import (
+	"flag"
+	"log"
+
 	"github.com/LeRoid-hub/Bookholder-API/config"
 	"github.com/LeRoid-hub/Bookholder-API/database"
 	"github.com/LeRoid-hub/Bookholder-API/server"
 )
 
 func main() {
+	downgrade := flag.Int("migrate-down", -1, "take the database schema back to this version and exit")
+	flag.Parse()
+
 	env := config.Load()
 
+	if *downgrade >= 0 {
+		err := database.Downgrade(env, *downgrade)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	db := database.SetEnv(env)
 
 	server.Run(env, db)
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -34,7 +35,13 @@ func getAccount(c *gin.Context) {
 		return
 	}
 
-	acc, err := database.GetAccount(Database, idInt)
+	acc, err := currentStore(c).GetAccount(currentScope(c), idInt)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "account not found",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error" + err.Error(),
@@ -79,7 +86,20 @@ func newAccount(c *gin.Context) {
 		return
 	}
 
-	err = database.NewAccount(Database, acc)
+	if !database.ValidKind(acc.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": database.ErrInvalidKind.Error(),
+		})
+		return
+	}
+
+	err = currentStore(c).NewAccount(currentScope(c), acc)
+	if isAccountValidationError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid account: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -112,7 +132,26 @@ func updateAccount(c *gin.Context) {
 		return
 	}
 
-	err = database.UpdateAccount(Database, acc)
+	if !database.ValidKind(acc.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": database.ErrInvalidKind.Error(),
+		})
+		return
+	}
+
+	err = currentStore(c).UpdateAccount(currentScope(c), acc)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "account not found",
+		})
+		return
+	}
+	if isAccountValidationError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid account: " + err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -143,7 +182,19 @@ func deleteAccount(c *gin.Context) {
 		return
 	}
 
-	err = database.DeleteAccount(Database, idInt)
+	err = currentStore(c).DeleteAccount(currentScope(c), idInt)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "account not found",
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupHasChildren) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -155,3 +206,33 @@ func deleteAccount(c *gin.Context) {
 		"message": "account deleted",
 	})
 }
+
+func getAccounts(c *gin.Context) {
+	asOf, ok := parseDateQuery(c, "asOf")
+	if !ok {
+		return
+	}
+
+	tree, err := database.GetAccountTree(currentDatabase(c), currentScope(c), asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": tree,
+	})
+}
+
+// isAccountValidationError reports whether the database rejected an account
+// because of its content rather than because of a failure.
+func isAccountValidationError(err error) bool {
+	for _, target := range []error{database.ErrInvalidKind, database.ErrParentNotExists, database.ErrParentNotGroup, database.ErrAccountCycle, database.ErrGroupHasChildren, database.ErrAccountHasEntries} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
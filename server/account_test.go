@@ -1,16 +1,63 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/LeRoid-hub/Bookholder-API/database"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
+var accountScope = database.Scope{Ledger: 1, User: "c0ffee00-0000-0000-0000-000000000000", Role: database.RoleAccountant}
+
+// newAccountRouter returns a router whose handlers work on store in the
+// ledger of accountScope.
+func newAccountRouter(store database.Store) *gin.Engine {
+	r := gin.Default()
+	r.Use(useStore(store), func(c *gin.Context) {
+		c.Set("scope", accountScope)
+	})
+	return r
+}
+
+// newAccountStore returns a store holding a bank account.
+func newAccountStore(t *testing.T) *database.MemoryStore {
+	store := database.NewMemoryStore()
+	err := store.NewAccount(accountScope, database.Account{ID: 1200, Name: "Bank", Kind: database.KindAsset})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// brokenStore fails every change of an account like a lost connection.
+type brokenStore struct {
+	*database.MemoryStore
+}
+
+var errConnection = errors.New("connection refused")
+
+func (brokenStore) GetAccount(database.Scope, int) (database.Account, error) {
+	return database.Account{}, errConnection
+}
+
+func (brokenStore) NewAccount(database.Scope, database.Account) error {
+	return errConnection
+}
+
+func (brokenStore) UpdateAccount(database.Scope, database.Account) error {
+	return errConnection
+}
+
+func (brokenStore) DeleteAccount(database.Scope, int) error {
+	return errConnection
+}
+
 func TestGetAccountStringAsAccountID(t *testing.T) {
 	r := gin.Default()
 	r.GET("/Account/:AccountID", getAccount)
@@ -50,8 +97,47 @@ func TestGetAccountNegativID(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
 
-// TODO: TestGetAccountValidID
-// TODO: TestGetAccountInternalError
+func TestGetAccountValidID(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.GET("/Account/:AccountID", getAccount)
+
+	req, _ := http.NewRequest("GET", "/Account/1200", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestGetAccountNotFound(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.GET("/Account/:AccountID", getAccount)
+
+	req, _ := http.NewRequest("GET", "/Account/1300", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestGetAccountInternalError(t *testing.T) {
+	store := brokenStore{database.NewMemoryStore()}
+	r := newAccountRouter(store)
+	r.GET("/Account/:AccountID", getAccount)
+
+	req, _ := http.NewRequest("GET", "/Account/1200", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
 
 func TestNewAccountNegativeID(t *testing.T) {
 	r := gin.Default()
@@ -60,7 +146,7 @@ func TestNewAccountNegativeID(t *testing.T) {
 	accountJson := `{
 		"ID": -1,
 		"Name": "Test Account",
-		"Kind": "1000.00"
+		"Kind": "asset"
 	}`
 
 	req, _ := http.NewRequest("POST", "/NewAccount", strings.NewReader(accountJson))
@@ -90,8 +176,70 @@ func TestNewAccountWrongFormat(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
 
-// TODO: TestNewAccountInternalError
-// TODO: TestNewAccountValid
+func TestNewAccountInternalError(t *testing.T) {
+	store := brokenStore{database.NewMemoryStore()}
+	r := newAccountRouter(store)
+	r.POST("/NewAccount", newAccount)
+
+	accountJson := `{
+		"ID": 1200,
+		"Name": "Bank",
+		"Kind": "asset"
+	}`
+
+	req, _ := http.NewRequest("POST", "/NewAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestNewAccountValid(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.POST("/NewAccount", newAccount)
+
+	accountJson := `{
+		"ID": 1000,
+		"Name": "Cash",
+		"Kind": "asset"
+	}`
+
+	req, _ := http.NewRequest("POST", "/NewAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+
+	account, err := store.GetAccount(accountScope, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, "Cash", account.Name)
+}
+
+func TestNewAccountMissingParent(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.POST("/NewAccount", newAccount)
+
+	accountJson := `{
+		"ID": 1000,
+		"Name": "Cash",
+		"Kind": "asset",
+		"Parent": 100
+	}`
+
+	req, _ := http.NewRequest("POST", "/NewAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
 
 func TestUpdateAccountNegativeID(t *testing.T) {
 	r := gin.Default()
@@ -100,7 +248,7 @@ func TestUpdateAccountNegativeID(t *testing.T) {
 	accountJson := `{
 		"ID": -1,
 		"Name": "Test Account",
-		"Kind": "1000.00"
+		"Kind": "asset"
 	}`
 
 	req, _ := http.NewRequest("PUT", "/UpdateAccount", strings.NewReader(accountJson))
@@ -130,8 +278,69 @@ func TestUpdateAccountWrongFormat(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
 
-// TODO: TestUpdateAccountInternalError
-// TODO: TestUpdateAccountValid
+func TestUpdateAccountInternalError(t *testing.T) {
+	store := brokenStore{database.NewMemoryStore()}
+	r := newAccountRouter(store)
+	r.PUT("/UpdateAccount", updateAccount)
+
+	accountJson := `{
+		"ID": 1200,
+		"Name": "Bank",
+		"Kind": "asset"
+	}`
+
+	req, _ := http.NewRequest("PUT", "/UpdateAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestUpdateAccountValid(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.PUT("/UpdateAccount", updateAccount)
+
+	accountJson := `{
+		"ID": 1200,
+		"Name": "Checking Account",
+		"Kind": "asset"
+	}`
+
+	req, _ := http.NewRequest("PUT", "/UpdateAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	account, err := store.GetAccount(accountScope, 1200)
+	assert.NoError(t, err)
+	assert.Equal(t, "Checking Account", account.Name)
+}
+
+func TestUpdateAccountNotFound(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.PUT("/UpdateAccount", updateAccount)
+
+	accountJson := `{
+		"ID": 1300,
+		"Name": "Bank",
+		"Kind": "asset"
+	}`
+
+	req, _ := http.NewRequest("PUT", "/UpdateAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
 
 func TestDeleteAccountNegativeID(t *testing.T) {
 	r := gin.Default()
@@ -159,5 +368,66 @@ func TestDeleteAccountString(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
 }
 
-// TODO: TestDeleteAccountInternalError
-// TODO: TestDeleteAccountValid
+func TestDeleteAccountInternalError(t *testing.T) {
+	store := brokenStore{database.NewMemoryStore()}
+	r := newAccountRouter(store)
+	r.DELETE("/DeleteAccount/:AccountID", deleteAccount)
+
+	req, _ := http.NewRequest("DELETE", "/DeleteAccount/1200", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestDeleteAccountValid(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.DELETE("/DeleteAccount/:AccountID", deleteAccount)
+
+	req, _ := http.NewRequest("DELETE", "/DeleteAccount/1200", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	_, err := store.GetAccount(accountScope, 1200)
+	assert.ErrorIs(t, err, database.ErrAccountNotExists)
+}
+
+func TestDeleteAccountNotFound(t *testing.T) {
+	store := newAccountStore(t)
+	r := newAccountRouter(store)
+	r.DELETE("/DeleteAccount/:AccountID", deleteAccount)
+
+	req, _ := http.NewRequest("DELETE", "/DeleteAccount/1300", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestNewAccountInvalidKind(t *testing.T) {
+	r := gin.Default()
+	r.POST("/NewAccount", newAccount)
+
+	accountJson := `{
+		"ID": 1000,
+		"Name": "Test Account",
+		"Kind": "1000.00"
+	}`
+
+	req, _ := http.NewRequest("POST", "/NewAccount", strings.NewReader(accountJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
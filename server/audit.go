@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+var canAudit = requireRole(database.RoleOwner, database.RoleAuditor)
+
+// getAuditLog lists the audit log of the ledger. The optional entity, user,
+// from and to query parameters narrow it down; from and to are whole days.
+func getAuditLog(c *gin.Context) {
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	filter := database.AuditFilter{
+		Entity: c.Query("entity"),
+		User:   c.Query("user"),
+		From:   from,
+	}
+
+	if filter.User != "" && !uuidPattern.MatchString(filter.User) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid user; must be a user id",
+		})
+		return
+	}
+
+	if !to.IsZero() {
+		filter.To = to.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+
+	entries, err := database.GetAuditLog(currentDatabase(c), currentScope(c), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"audit": entries,
+	})
+}
+
+// verifyAuditLog checks the hash chain of the ledger.
+func verifyAuditLog(c *gin.Context) {
+	verification, err := database.VerifyAuditLog(currentDatabase(c), currentScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"verification": verification,
+	})
+}
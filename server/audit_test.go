@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuditLogInvalidUser(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Audit", getAuditLog)
+
+	req, _ := http.NewRequest("GET", "/Audit?user=someone", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetAuditLogInvalidPeriod(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Audit", getAuditLog)
+
+	req, _ := http.NewRequest("GET", "/Audit?from=2024-03-01&to=2024-02-01", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -15,6 +17,7 @@ import (
 type AuthInput struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Ledger   uint   `json:"ledger"`
 }
 
 func createUser(c *gin.Context) {
@@ -25,15 +28,14 @@ func createUser(c *gin.Context) {
 		return
 	}
 
-	var user database.User
-	user, err := database.GetUserByName(Database, authInput.Username)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// the stores answer names nobody has taken with sql.ErrNoRows
+	_, err := currentStore(c).GetUserByName(authInput.Username)
+	if err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User already exists"})
 		return
 	}
-
-	if user.ID != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User already exists"})
+	if !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -43,12 +45,13 @@ func createUser(c *gin.Context) {
 		return
 	}
 
-	user = database.User{
+	user := database.User{
 		Name:     authInput.Username,
 		Password: string(passwordHash),
 	}
 
-	err = database.NewUser(Database, user)
+	// every user starts with a ledger of their own
+	err = currentStore(c).NewUserWithLedger(user, "Default")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -66,7 +69,7 @@ func authenticateUser(c *gin.Context) {
 	}
 
 	var userFound database.User
-	userFound, err := database.GetUserByName(Database, authInput.Username)
+	userFound, err := currentStore(c).GetUserByName(authInput.Username)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -83,10 +86,22 @@ func authenticateUser(c *gin.Context) {
 		return
 	}
 
-	generateToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	claims := jwt.MapClaims{
 		"id":  userFound.ID,
 		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	})
+	}
+
+	// a token can be bound to one ledger, which then becomes its default
+	if authInput.Ledger != 0 {
+		_, err = currentStore(c).GetLedger(authInput.Ledger, userFound.ID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ledger not found"})
+			return
+		}
+		claims["ledger"] = authInput.Ledger
+	}
+
+	generateToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := generateToken.SignedString([]byte(Env["SECRET"]))
 	if err != nil {
@@ -126,7 +141,7 @@ func checkAuth(c *gin.Context) {
 		return []byte(Env["SECRET"]), nil
 	})
 
-	if err != nil || token.Valid {
+	if err != nil || !token.Valid {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
@@ -145,7 +160,7 @@ func checkAuth(c *gin.Context) {
 		return
 	}
 
-	user, err := database.GetUser(Database, string(claims["id"].(string)))
+	user, err := currentStore(c).GetUser(string(claims["id"].(string)))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		c.AbortWithStatus(http.StatusUnauthorized)
@@ -160,5 +175,9 @@ func checkAuth(c *gin.Context) {
 
 	c.Set("currentUser", user)
 
+	if ledger, ok := claims["ledger"].(float64); ok {
+		c.Set("tokenLedger", uint(ledger))
+	}
+
 	c.Next()
 }
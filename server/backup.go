@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/LeRoid-hub/Bookholder-API/exporter"
+	"github.com/LeRoid-hub/Bookholder-API/importer"
+	"github.com/gin-gonic/gin"
+)
+
+// maxArchiveSize limits uploaded ledger archives to 100 MB.
+const maxArchiveSize = 100 << 20
+
+// exportLedger answers with the backup of the ledger as a zip archive.
+func exportLedger(c *gin.Context) {
+	scope := currentScope(c)
+	backup, err := database.GetBackup(currentDatabase(c), scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	filename := "ledger-" + strconv.FormatUint(uint64(scope.Ledger), 10) + "-" + backup.Created.Format("20060102") + ".zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// the archive goes out as it is written, so a failure can only cut it short
+	err = exporter.WriteLedgerArchive(c.Writer, backup)
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// restoreLedger creates a new ledger owned by the current user from an
+// uploaded ledger archive. An archive of another version, or one whose
+// records do not fit together, is rejected as a whole, and one taken from a
+// newer schema conflicts with this database.
+func restoreLedger(c *gin.Context) {
+	upload, ok := readUpload(c, maxArchiveSize)
+	if !ok {
+		return
+	}
+	defer upload.Close()
+
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid archive: " + err.Error(),
+		})
+		return
+	}
+
+	backup, err := importer.ParseLedgerArchive(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid archive: " + err.Error(),
+		})
+		return
+	}
+
+	err = database.ValidateBackup(backup)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid archive: " + err.Error(),
+		})
+		return
+	}
+
+	user := c.MustGet("currentUser").(database.User)
+	result, err := database.RestoreBackup(currentDatabase(c), user.ID, backup)
+	if errors.Is(err, database.ErrBackupSchema) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "ledger restored",
+		"restore": result,
+	})
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestoreLedgerInvalidArchive(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Import/Ledger", restoreLedger)
+
+	req, _ := http.NewRequest("POST", "/Import/Ledger", strings.NewReader("not a zip archive"))
+	req.Header.Set("Content-Type", "application/zip")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid archive")
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/LeRoid-hub/Bookholder-API/exporter"
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// parseDateQuery reads an optional YYYY-MM-DD query parameter. It answers the
+// request itself and returns false when the value is malformed.
+func parseDateQuery(c *gin.Context, key string) (time.Time, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, true
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid " + key + "; must be a date like 2006-01-02",
+		})
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// parsePeriodQuery reads the optional from and to query parameters and makes
+// sure they form a valid period.
+func parsePeriodQuery(c *gin.Context) (time.Time, time.Time, bool) {
+	from, ok := parseDateQuery(c, "from")
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	to, ok := parseDateQuery(c, "to")
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid period; to must not be before from",
+		})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// parseAccountParam reads the AccountID path parameter. It answers the
+// request itself and returns false when the value is not a valid id.
+func parseAccountParam(c *gin.Context) (int, bool) {
+	return parseIDParam(c, "AccountID")
+}
+
+// parseIDParam reads a positive integer path parameter. It answers the
+// request itself and returns false when the value is not a valid id.
+func parseIDParam(c *gin.Context, key string) (int, bool) {
+	idInt, err := strconv.Atoi(c.Param(key))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid id; must be an integer",
+		})
+		return 0, false
+	}
+
+	if idInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid id; must be greater than 0",
+		})
+		return 0, false
+	}
+	return idInt, true
+}
+
+func getBalance(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	asOf, ok := parseDateQuery(c, "asOf")
+	if !ok {
+		return
+	}
+
+	balance, err := database.GetBalance(currentDatabase(c), currentScope(c), account, asOf)
+	if err != nil {
+		if errors.Is(err, database.ErrAccountNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "account not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance": balance,
+	})
+}
+
+func getAccountLedger(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c, true)
+	if !ok {
+		return
+	}
+	if format != "" && format != exporter.FormatPDF {
+		exportAccountLedger(c, format, account, from, to)
+		return
+	}
+
+	scope := currentScope(c)
+	ledger, err := database.GetAccountLedger(currentDatabase(c), scope, account, from, to)
+	if err != nil {
+		if errors.Is(err, database.ErrAccountNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "account not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	if format == exporter.FormatPDF {
+		details, err := currentStore(c).GetAccount(scope, account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "internal server error",
+			})
+			return
+		}
+
+		exportPDF(c, "statement-"+strconv.Itoa(account), func(w io.Writer, letterhead exporter.Letterhead) error {
+			return exporter.WriteAccountLedgerPDF(w, letterhead, details, ledger)
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ledger": ledger,
+	})
+}
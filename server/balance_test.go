@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBalanceInvalidDate(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Accounts/:AccountID/balance", getBalance)
+
+	req, _ := http.NewRequest("GET", "/Accounts/1200/balance?asOf=31.03.2024", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetAccountLedgerToBeforeFrom(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Accounts/:AccountID/ledger", getAccountLedger)
+
+	req, _ := http.NewRequest("GET", "/Accounts/1200/ledger?from=2024-04-01&to=2024-03-01", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+func getChartTemplates(c *gin.Context) {
+	templates, err := database.ChartTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+	})
+}
+
+func applyChartTemplate(c *gin.Context) {
+	name := c.Param("Template")
+
+	count, err := database.ApplyChartTemplate(currentDatabase(c), currentScope(c), name)
+	if err != nil {
+		if errors.Is(err, database.ErrChartTemplateNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "chart template not found",
+			})
+			return
+		}
+		if errors.Is(err, database.ErrLedgerNotEmpty) {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": "chart templates can only be applied to a ledger without accounts",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "chart template applied",
+		"accounts": count,
+	})
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/LeRoid-hub/Bookholder-API/exporter"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAccountLength is the length of the accounts of the SKR03 and SKR04
+// charts.
+const defaultAccountLength = 4
+
+// parseNumberQuery reads a required positive integer query parameter. It
+// answers the request itself and returns false when the value is missing or
+// malformed.
+func parseNumberQuery(c *gin.Context, key string) (int, bool) {
+	number, err := strconv.Atoi(c.Query(key))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid " + key + "; must be a number greater than 0",
+		})
+		return 0, false
+	}
+	return number, true
+}
+
+// datevExport reads the header of a DATEV export from the query and loads the
+// posted transactions and journal entries of the period. It answers the
+// request itself and returns false when it cannot.
+func datevExport(c *gin.Context) (exporter.DATEVHeader, []database.Transaction, []database.JournalEntry, bool) {
+	var header exporter.DATEVHeader
+
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return header, nil, nil, false
+	}
+	if from.IsZero() || to.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "from and to are required",
+		})
+		return header, nil, nil, false
+	}
+
+	advisor, ok := parseNumberQuery(c, "advisor")
+	if !ok {
+		return header, nil, nil, false
+	}
+
+	client, ok := parseNumberQuery(c, "client")
+	if !ok {
+		return header, nil, nil, false
+	}
+
+	accountLength := defaultAccountLength
+	if c.Query("accountLength") != "" {
+		accountLength, ok = parseNumberQuery(c, "accountLength")
+		if !ok {
+			return header, nil, nil, false
+		}
+	}
+
+	var clearing int
+	if c.Query("clearing") != "" {
+		clearing, ok = parseNumberQuery(c, "clearing")
+		if !ok {
+			return header, nil, nil, false
+		}
+	}
+
+	scope := currentScope(c)
+	start, err := database.FiscalYearStart(currentDatabase(c), scope, from)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return header, nil, nil, false
+	}
+
+	transactions, err := database.GetPostedTransactions(currentDatabase(c), scope, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return header, nil, nil, false
+	}
+
+	entries, err := database.GetPostedJournalEntries(currentDatabase(c), scope, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return header, nil, nil, false
+	}
+
+	header = exporter.DATEVHeader{
+		Advisor:         advisor,
+		Client:          client,
+		FiscalYearStart: start,
+		AccountLength:   accountLength,
+		Clearing:        uint(clearing),
+		From:            from,
+		To:              to,
+		Name:            c.DefaultQuery("name", "Bookholder"),
+		Initials:        c.Query("initials"),
+	}
+	return header, transactions, entries, true
+}
+
+// exportDATEV answers with the posted transactions and journal entries of the
+// period as a DATEV Buchungsstapel. An export DATEV would reject in part is
+// not written; the answer lists the problems instead.
+func exportDATEV(c *gin.Context) {
+	header, transactions, entries, ok := datevExport(c)
+	if !ok {
+		return
+	}
+
+	problems := exporter.ValidateDATEV(header, transactions, entries)
+	if len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"message":  "DATEV would reject the export",
+			"problems": problems,
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	err := exporter.WriteDATEV(&buf, header, transactions, entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	filename := "EXTF_Buchungsstapel_" + header.From.Format("20060102") + "_" + header.To.Format("20060102") + ".csv"
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, "text/csv; charset=windows-1252", buf.Bytes())
+}
+
+// validateDATEV lists the problems DATEV would have with the export of the
+// period, without writing it.
+func validateDATEV(c *gin.Context) {
+	header, transactions, entries, ok := datevExport(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions":   len(transactions),
+		"journalEntries": len(entries),
+		"problems":       exporter.ValidateDATEV(header, transactions, entries),
+	})
+}
+
+// exportFormat reads the format a listing or report is asked for: the format
+// query parameter, else the Accept header. An empty format means the usual
+// JSON answer; pdf is only offered by reports. It answers the request itself
+// and returns false when the format is not offered.
+func exportFormat(c *gin.Context, pdf bool) (string, bool) {
+	offered := func(format string) bool {
+		if format == exporter.FormatPDF {
+			return pdf
+		}
+		return exporter.ContentType(format) != ""
+	}
+
+	if format := c.Query("format"); format != "" {
+		if format == "json" {
+			return "", true
+		}
+		if !offered(format) {
+			message := "invalid format; must be one of json, csv, jsonl or xlsx"
+			if pdf {
+				message = "invalid format; must be one of json, csv, jsonl, xlsx or pdf"
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": message,
+			})
+			return "", false
+		}
+		return format, true
+	}
+
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accepted, ";")
+		if format, ok := exporter.FormatOf(strings.TrimSpace(mediaType)); ok && offered(format) {
+			return format, true
+		}
+	}
+	return "", true
+}
+
+// startExport starts a download of the table name in the format. The rows
+// go straight to the client as they are written.
+func startExport(c *gin.Context, format string, name string, columns []string) (exporter.Table, error) {
+	c.Header("Content-Type", exporter.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+	c.Status(http.StatusOK)
+	return exporter.NewTable(c.Writer, format, columns)
+}
+
+// finishExport closes the table of a download, or answers err. Once the first
+// rows are sent the status cannot change any more, so a later error cuts the
+// download short and is only recorded.
+func finishExport(c *gin.Context, table exporter.Table, err error) {
+	if err == nil {
+		err = table.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	if c.Writer.Written() {
+		c.Error(err)
+		return
+	}
+
+	c.Writer.Header().Del("Content-Disposition")
+	c.Writer.Header().Del("Content-Type")
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "account not found",
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"message": "internal server error",
+	})
+}
+
+var transactionColumns = []string{"ID", "Date", "Account", "OffsetAccount", "Debit", "Amount", "Description", "TaxCode", "Status", "Reverses", "Replaces", "PreparedBy"}
+
+// exportTransactions streams the transactions of an account in a year, or a
+// month when month is not 0, in status or in every status when it is "".
+func exportTransactions(c *gin.Context, format string, account int, year int, month int, status string) {
+	name := "transactions-" + strconv.Itoa(account) + "-" + strconv.Itoa(year)
+	if month != 0 {
+		name += "-" + strconv.Itoa(month)
+	}
+
+	table, err := startExport(c, format, name, transactionColumns)
+	if err == nil {
+		err = database.StreamTransactions(currentDatabase(c), currentScope(c), account, year, month, status, func(t database.Transaction) error {
+			return table.Write(t.ID, t.Date, t.Account, t.OffsetAccount, t.Debit, t.Amount, t.Description, t.TaxCode, t.Status, t.Reverses, t.Replaces, t.PreparedBy)
+		})
+	}
+	finishExport(c, table, err)
+}
+
+// exportAccountLedger streams the bookings of an account with their running
+// balance, after the balance brought forward into the period.
+func exportAccountLedger(c *gin.Context, format string, account int, from time.Time, to time.Time) {
+	scope := currentScope(c)
+
+	var opening database.Balance
+	var err error
+	if !from.IsZero() {
+		opening, err = database.GetBalance(currentDatabase(c), scope, account, from.AddDate(0, 0, -1))
+		if err != nil {
+			finishExport(c, nil, err)
+			return
+		}
+	}
+
+	table, err := startExport(c, format, "ledger-"+strconv.Itoa(account), []string{"Date", "Source", "EntryID", "Description", "Debit", "Credit", "Balance"})
+	if err == nil && !from.IsZero() {
+		err = table.Write(from, "", "", "Balance brought forward", "", "", opening.Balance)
+	}
+	if err == nil {
+		err = database.StreamAccountLedger(currentDatabase(c), scope, account, from, to, func(line database.AccountLedgerLine) error {
+			return table.Write(line.Date, line.Source, line.EntryID, line.Description, line.Debit, line.Credit, line.Balance)
+		})
+	}
+	finishExport(c, table, err)
+}
+
+// exportJournal streams every line of the posted bookings touching an account
+// in a year, or a month when month is not 0.
+func exportJournal(c *gin.Context, format string, account int, year int, month int) {
+	name := "journal-" + strconv.Itoa(account) + "-" + strconv.Itoa(year)
+	if month != 0 {
+		name += "-" + strconv.Itoa(month)
+	}
+
+	table, err := startExport(c, format, name, []string{"Date", "Source", "EntryID", "Description", "Account", "Debit", "Credit"})
+	if err == nil {
+		err = database.StreamJournal(currentDatabase(c), currentScope(c), account, year, month, func(row database.JournalRow) error {
+			return table.Write(row.Date, row.Source, row.EntryID, row.Description, row.Account, row.Debit, row.Credit)
+		})
+	}
+	finishExport(c, table, err)
+}
+
+func exportTrialBalance(c *gin.Context, format string, trialBalance database.TrialBalance) {
+	table, err := startExport(c, format, "trial-balance", []string{"Account", "Name", "Kind", "Parent", "Group", "Opening", "Debit", "Credit", "Closing"})
+	for _, line := range trialBalance.Lines {
+		if err != nil {
+			break
+		}
+		err = table.Write(line.Account, line.Name, line.Kind, line.Parent, line.Group, line.Opening, line.Debit, line.Credit, line.Closing)
+	}
+	if err == nil {
+		err = table.Write("", "Total", "", "", "", trialBalance.TotalOpening, trialBalance.TotalDebit, trialBalance.TotalCredit, trialBalance.TotalClosing)
+	}
+	finishExport(c, table, err)
+}
+
+// statementPart is a section of a financial statement followed by its total,
+// or only a total of the whole statement when it has no lines.
+type statementPart struct {
+	section   string
+	lines     []database.StatementLine
+	totalName string
+	total     database.Money
+}
+
+// exportStatement writes the sections of a balance sheet or profit and loss
+// statement one after the other. Group accounts come before their children,
+// which are one level deeper, and every part ends with its total.
+func exportStatement(c *gin.Context, format string, name string, parts []statementPart) {
+	table, err := startExport(c, format, name, []string{"Section", "Level", "Account", "Name", "Amount"})
+
+	var write func(section string, level int, lines []database.StatementLine)
+	write = func(section string, level int, lines []database.StatementLine) {
+		for _, line := range lines {
+			if err != nil {
+				return
+			}
+			err = table.Write(section, level, line.Account, line.Name, line.Amount)
+			write(section, level+1, line.Children)
+		}
+	}
+
+	for _, part := range parts {
+		if err != nil {
+			break
+		}
+		write(part.section, 0, part.lines)
+		if err == nil {
+			err = table.Write(part.section, 0, "", part.totalName, part.total)
+		}
+	}
+	finishExport(c, table, err)
+}
+
+// exportPDF answers with a report printed as PDF under the letterhead of the
+// ledger. A PDF is only written once the whole report is laid out, so unlike
+// the tables it is built in memory before the answer starts.
+func exportPDF(c *gin.Context, name string, write func(w io.Writer, letterhead exporter.Letterhead) error) {
+	scope := currentScope(c)
+	ledger, err := currentStore(c).GetLedger(scope.Ledger, scope.User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	letterhead := exporter.Letterhead{
+		Name:  ledger.Name,
+		Lines: []string{"Printed on " + time.Now().Format(time.DateOnly) + " by " + scope.User},
+	}
+
+	var buf bytes.Buffer
+	err = write(&buf, letterhead)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+name+`.pdf"`)
+	c.Data(http.StatusOK, exporter.ContentType(exporter.FormatPDF), buf.Bytes())
+}
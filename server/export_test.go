@@ -0,0 +1,102 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportDATEVMissingPeriod(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Exports/DATEV", exportDATEV)
+
+	req, _ := http.NewRequest("GET", "/Exports/DATEV?from=2024-03-01&advisor=29098&client=55003", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestValidateDATEVInvalidAdvisor(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Exports/DATEV/validate", validateDATEV)
+
+	req, _ := http.NewRequest("GET", "/Exports/DATEV/validate?from=2024-03-01&to=2024-03-31&advisor=abc&client=55003", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetTrialBalanceUnknownFormat(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Reports/TrialBalance", getTrialBalance)
+
+	req, _ := http.NewRequest("GET", "/Reports/TrialBalance?format=ods", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestExportFormatAccept(t *testing.T) {
+	r := gin.Default()
+	r.GET("/format", func(c *gin.Context) {
+		format, ok := exportFormat(c, false)
+		if ok {
+			c.String(http.StatusOK, format)
+		}
+	})
+
+	for accept, format := range map[string]string{
+		"text/csv; charset=utf-8":                                           "csv",
+		"application/json, */*":                                             "",
+		"text/html, application/x-ndjson":                                   "jsonl",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+	} {
+		req, _ := http.NewRequest("GET", "/format", nil)
+		req.Header.Set("Accept", accept)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		assert.Equal(t, format, resp.Body.String(), accept)
+	}
+}
+
+func TestExportFormatPDF(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Transactions/:AccountID/:year", getTransactions)
+
+	req, _ := http.NewRequest("GET", "/Transactions/1200/2024?format=pdf", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	r = gin.Default()
+	r.GET("/format", func(c *gin.Context) {
+		format, ok := exportFormat(c, true)
+		if ok {
+			c.String(http.StatusOK, format)
+		}
+	})
+
+	req, _ = http.NewRequest("GET", "/format", nil)
+	req.Header.Set("Accept", "application/pdf")
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, "pdf", resp.Body.String())
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+type FiscalYearInput struct {
+	Start string
+}
+
+type PeriodStatusInput struct {
+	Status string
+}
+
+type CloseFiscalYearInput struct {
+	RetainedEarnings uint
+}
+
+// isPeriodError reports whether a booking was rejected because its date lies
+// in a closed or locked period.
+func isPeriodError(err error) bool {
+	return errors.Is(err, database.ErrPeriodClosed) || errors.Is(err, database.ErrPeriodLocked)
+}
+
+func getFiscalYears(c *gin.Context) {
+	years, err := database.GetFiscalYears(currentDatabase(c), currentScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fiscalYears": years,
+	})
+}
+
+func newFiscalYear(c *gin.Context) {
+	var input FiscalYearInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	start, err := time.Parse(dateLayout, input.Start)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid start; must be a date like 2006-01-02",
+		})
+		return
+	}
+
+	year, err := database.NewFiscalYear(currentDatabase(c), currentScope(c), start)
+	if errors.Is(err, database.ErrFiscalYearOverlap) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "fiscal year created",
+		"fiscalYear": year,
+	})
+}
+
+func setPeriodStatus(c *gin.Context) {
+	id, ok := parseIDParam(c, "PeriodID")
+	if !ok {
+		return
+	}
+
+	var input PeriodStatusInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	if !database.ValidPeriodStatus(input.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": database.ErrInvalidPeriodStatus.Error(),
+		})
+		return
+	}
+
+	err = database.SetPeriodStatus(currentDatabase(c), currentScope(c), id, input.Status)
+	if errors.Is(err, database.ErrFiscalPeriodNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "fiscal period not found",
+		})
+		return
+	}
+	if errors.Is(err, database.ErrPeriodLocked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": "period is locked and cannot be reopened",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "period updated",
+	})
+}
+
+func closeFiscalYear(c *gin.Context) {
+	id, ok := parseIDParam(c, "YearID")
+	if !ok {
+		return
+	}
+
+	var input CloseFiscalYearInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	if input.RetainedEarnings < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid retained earnings account; must be greater than 0",
+		})
+		return
+	}
+
+	year, err := database.CloseFiscalYear(currentDatabase(c), currentScope(c), id, input.RetainedEarnings)
+	if err != nil {
+		if errors.Is(err, database.ErrFiscalYearNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "fiscal year not found",
+			})
+			return
+		}
+		if errors.Is(err, database.ErrFiscalYearClosed) {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, database.ErrAccountNotExists) || errors.Is(err, database.ErrRetainedEarnings) || errors.Is(err, database.ErrGroupAccount) || errors.Is(err, database.ErrFiscalYearOverlap) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "fiscal year closed",
+		"fiscalYear": year,
+	})
+}
+
+func getOpeningBalances(c *gin.Context) {
+	id, ok := parseIDParam(c, "YearID")
+	if !ok {
+		return
+	}
+
+	balances, err := database.GetOpeningBalances(currentDatabase(c), currentScope(c), id)
+	if errors.Is(err, database.ErrFiscalYearNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "fiscal year not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"openingBalances": balances,
+	})
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFiscalYearInvalidStart(t *testing.T) {
+	r := gin.Default()
+	r.POST("/FiscalYears", newFiscalYear)
+
+	req, _ := http.NewRequest("POST", "/FiscalYears", strings.NewReader(`{"Start": "01.01.2024"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestSetPeriodStatusInvalidStatus(t *testing.T) {
+	r := gin.Default()
+	r.PUT("/FiscalPeriods/:PeriodID", setPeriodStatus)
+
+	req, _ := http.NewRequest("PUT", "/FiscalPeriods/1", strings.NewReader(`{"Status": "closed"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestCloseFiscalYearMissingRetainedEarnings(t *testing.T) {
+	r := gin.Default()
+	r.POST("/FiscalYears/:YearID/close", closeFiscalYear)
+
+	req, _ := http.NewRequest("POST", "/FiscalYears/1/close", strings.NewReader(`{}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/LeRoid-hub/Bookholder-API/importer"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatementSize limits uploaded statements to 10 MB.
+const maxStatementSize = 10 << 20
+
+// BookBankLineInput is the body of the book endpoint. Without an offset
+// account or description, the ones suggested by the rule that fired for the
+// line are used, and else the text taken from the bank line.
+type BookBankLineInput struct {
+	OffsetAccount uint
+	Description   string
+}
+
+func getImportProfiles(c *gin.Context) {
+	profiles, err := database.GetImportProfiles(currentDatabase(c), currentScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profiles": profiles,
+	})
+}
+
+func newImportProfile(c *gin.Context) {
+	var profile database.ImportProfile
+	err := c.BindJSON(&profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	err = database.ValidateImportProfile(profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	id, err := database.NewImportProfile(currentDatabase(c), currentScope(c), profile)
+	if errors.Is(err, database.ErrImportProfileExists) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "import profile created",
+		"id":      id,
+	})
+}
+
+func deleteImportProfile(c *gin.Context) {
+	id, ok := parseIDParam(c, "ProfileID")
+	if !ok {
+		return
+	}
+
+	err := database.DeleteImportProfile(currentDatabase(c), currentScope(c), id)
+	if errors.Is(err, database.ErrImportProfileNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "import profile not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "import profile deleted",
+	})
+}
+
+// readUpload returns an uploaded file of at most limit bytes, either the file
+// field of a multipart form or the raw request body.
+func readUpload(c *gin.Context, limit int64) (io.ReadCloser, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "missing file",
+			})
+			return nil, false
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid file",
+			})
+			return nil, false
+		}
+		return file, true
+	}
+
+	return c.Request.Body, true
+}
+
+// importStatement stages the lines of an uploaded statement for the bank
+// account in the path. The format is csv, camt053, mt940, ofx or qif; CSV
+// statements need the id of an import profile in the profile query
+// parameter, QIF statements may name their date order in dateOrder. With
+// preview=true nothing is staged; the answer tells which lines would be and
+// the draft transactions they would turn into.
+func importStatement(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	format := c.Param("Format")
+	var parse func(io.Reader) ([]database.BankLine, error)
+	switch format {
+	case "csv":
+		id, err := strconv.Atoi(c.Query("profile"))
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid profile; must be the id of an import profile",
+			})
+			return
+		}
+
+		profile, err := database.GetImportProfile(currentDatabase(c), currentScope(c), id)
+		if errors.Is(err, database.ErrImportProfileNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "import profile not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "internal server error",
+			})
+			return
+		}
+
+		parse = func(r io.Reader) ([]database.BankLine, error) {
+			return importer.ParseCSV(r, profile)
+		}
+	case "camt053":
+		parse = importer.ParseCAMT053
+	case "mt940":
+		parse = importer.ParseMT940
+	case "ofx":
+		parse = importer.ParseOFX
+	case "qif":
+		dateOrder := c.Query("dateOrder")
+		parse = func(r io.Reader) ([]database.BankLine, error) {
+			return importer.ParseQIF(r, dateOrder)
+		}
+	default:
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "unknown statement format",
+		})
+		return
+	}
+
+	statement, ok := readUpload(c, maxStatementSize)
+	if !ok {
+		return
+	}
+	defer statement.Close()
+
+	lines, err := parse(statement)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid statement: " + err.Error(),
+		})
+		return
+	}
+
+	stage := database.StageBankLines
+	preview := c.Query("preview") == "true"
+	if preview {
+		stage = database.PreviewBankLines
+	}
+
+	result, err := stage(currentDatabase(c), currentScope(c), uint(account), format, lines)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	if preview {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "statement previewed",
+			"imported":     result.Imported,
+			"duplicates":   result.Duplicates,
+			"transactions": result.Bookings,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "statement imported",
+		"imported":     result.Imported,
+		"duplicates":   result.Duplicates,
+		"transactions": result.Bookings,
+	})
+}
+
+// getBankLines lists the staged lines of a bank account. With open=true only
+// lines that have not been booked are returned.
+func getBankLines(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	lines, err := database.GetBankLines(currentDatabase(c), currentScope(c), account, c.Query("open") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lines": lines,
+	})
+}
+
+func bookBankLine(c *gin.Context) {
+	id, ok := parseIDParam(c, "LineID")
+	if !ok {
+		return
+	}
+
+	var input BookBankLineInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	transaction, err := database.BookBankLine(currentDatabase(c), currentScope(c), id, input.OffsetAccount, input.Description)
+	if errors.Is(err, database.ErrBankLineNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "bank line not found",
+		})
+		return
+	}
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrBankLineBooked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) || errors.Is(err, database.ErrOffsetAccountRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "bank line booked",
+		"transaction": transaction,
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportStatementUnknownFormat(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Imports/:Format/:AccountID", importStatement)
+
+	req, _ := http.NewRequest("POST", "/Imports/pdf/1200", strings.NewReader("statement"))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestImportStatementMissingProfile(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Imports/:Format/:AccountID", importStatement)
+
+	req, _ := http.NewRequest("POST", "/Imports/csv/1200", strings.NewReader("Date,Amount\n2024-03-01,25.00\n"))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestImportStatementInvalidMT940(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Imports/:Format/:AccountID", importStatement)
+
+	req, _ := http.NewRequest("POST", "/Imports/mt940/1200", strings.NewReader(":20:STARTUMS\n:61:240301C\n"))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestImportStatementInvalidDateOrder(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Imports/:Format/:AccountID", importStatement)
+
+	req, _ := http.NewRequest("POST", "/Imports/qif/1200?dateOrder=DDMMYY&preview=true", strings.NewReader("!Type:Bank\nD01.03.24\nT-12,50\n^\n"))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestNewImportProfileInvalid(t *testing.T) {
+	r := gin.Default()
+	r.POST("/ImportProfiles", newImportProfile)
+
+	req, _ := http.NewRequest("POST", "/ImportProfiles", strings.NewReader(`{"Name": "Bank", "BookingDate": "Date"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
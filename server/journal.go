@@ -0,0 +1,241 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+func getJournalEntry(c *gin.Context) {
+	id := c.Param("EntryID")
+
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid id; must be an integer",
+		})
+		return
+	}
+
+	if idInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid id; must be greater than 0",
+		})
+		return
+	}
+
+	entry, err := database.GetJournalEntry(currentDatabase(c), currentScope(c), idInt)
+	if err != nil {
+		if errors.Is(err, database.ErrJournalEntryNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "journal entry not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entry": entry,
+	})
+}
+
+func getJournalEntries(c *gin.Context) {
+	year := c.Param("year")
+	month := c.Param("month")
+
+	yearInt, err := strconv.Atoi(year)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid year; must be an integer",
+		})
+		return
+	}
+
+	if yearInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid year; must be greater than 0",
+		})
+		return
+	}
+
+	monthInt := 0
+	if month != "" {
+		monthInt, err = strconv.Atoi(month)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid month; must be an integer",
+			})
+			return
+		}
+
+		if monthInt < 1 || monthInt > 12 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid month; must be between 1 and 12",
+			})
+			return
+		}
+	}
+
+	account := c.Param("AccountID")
+	accountInt, err := strconv.Atoi(account)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid account; must be an integer",
+		})
+		return
+	}
+
+	if accountInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid account; must be greater than 0",
+		})
+		return
+	}
+
+	status, ok := statusFilter(c)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c, false)
+	if !ok {
+		return
+	}
+	if format != "" {
+		// the journal export lists posted bookings only
+		if status != "" && status != database.StatusPosted {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid status; the journal can only be exported posted",
+			})
+			return
+		}
+		exportJournal(c, format, accountInt, yearInt, monthInt)
+		return
+	}
+
+	entries, err := database.GetJournalEntries(currentDatabase(c), currentScope(c), accountInt, yearInt, monthInt, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
+
+func newJournalEntry(c *gin.Context) {
+	var entry database.JournalEntry
+	err := c.BindJSON(&entry)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	err = database.ValidateJournalEntry(entry)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid journal entry: " + err.Error(),
+		})
+		return
+	}
+
+	// new journal entries wait for approval before they are posted
+	entry.Status = database.StatusDraft
+	id, err := database.NewJournalEntry(currentDatabase(c), currentScope(c), entry)
+	if isPeriodError(err) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "journal entry created",
+		"id":      id,
+	})
+}
+
+func submitJournalEntry(c *gin.Context) {
+	changeEntryStatus(c, "journal entry submitted", func(scope database.Scope, id int, _ string) error {
+		return database.SubmitJournalEntry(currentDatabase(c), scope, id)
+	})
+}
+
+func approveJournalEntry(c *gin.Context) {
+	changeEntryStatus(c, "journal entry approved", func(scope database.Scope, id int, comment string) error {
+		return database.ApproveJournalEntry(currentDatabase(c), scope, id, comment)
+	})
+}
+
+func rejectJournalEntry(c *gin.Context) {
+	changeEntryStatus(c, "journal entry rejected", func(scope database.Scope, id int, comment string) error {
+		return database.RejectJournalEntry(currentDatabase(c), scope, id, comment)
+	})
+}
+
+func postJournalEntry(c *gin.Context) {
+	changeEntryStatus(c, "journal entry posted", func(scope database.Scope, id int, _ string) error {
+		return database.PostJournalEntry(currentDatabase(c), scope, id)
+	})
+}
+
+// changeEntryStatus runs one step of the approval workflow on the journal
+// entry named in the path and answers the request.
+func changeEntryStatus(c *gin.Context, message string, change func(scope database.Scope, id int, comment string) error) {
+	changeBookingStatus(c, "EntryID", database.ErrJournalEntryNotExists, "journal entry not found", message, change)
+}
+
+func getJournalEntryApprovals(c *gin.Context) {
+	id, ok := parseIDParam(c, "EntryID")
+	if !ok {
+		return
+	}
+
+	approvals, err := database.GetJournalEntryApprovals(currentDatabase(c), currentScope(c), id)
+	if errors.Is(err, database.ErrJournalEntryNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "journal entry not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": approvals,
+	})
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJournalEntryUnbalanced(t *testing.T) {
+	r := gin.Default()
+	r.POST("/JournalEntries", newJournalEntry)
+
+	entryJson := `{
+		"Date": "2024-01-01T00:00:00Z",
+		"Lines": [
+			{"Account": 3400, "Amount": 100, "Debit": true},
+			{"Account": 1200, "Amount": 119, "Debit": false}
+		]
+	}`
+
+	req, _ := http.NewRequest("POST", "/JournalEntries", strings.NewReader(entryJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestNewJournalEntryWrongFormat(t *testing.T) {
+	r := gin.Default()
+	r.POST("/JournalEntries", newJournalEntry)
+
+	entryJson := `{
+		"Date": "2024-01-01T00:00:00Z",
+		"Lines": "none",
+	}`
+
+	req, _ := http.NewRequest("POST", "/JournalEntries", strings.NewReader(entryJson))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetJournalEntryInvalidID(t *testing.T) {
+	r := gin.Default()
+	r.GET("/JournalEntry/:EntryID", getJournalEntry)
+
+	req, _ := http.NewRequest("GET", "/JournalEntry/0", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetJournalEntriesInvalidMonth(t *testing.T) {
+	r := gin.Default()
+	r.GET("/JournalEntries/:AccountID/:year/:month", getJournalEntries)
+
+	req, _ := http.NewRequest("GET", "/JournalEntries/1/2024/13", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+// checkLedger resolves the ledger a request works on: the X-Ledger-ID header,
+// then the ledger claim of the token, then the oldest ledger of the user.
+// Ledgers the user is not a member of are answered like missing ones, with
+// 404.
+func checkLedger(c *gin.Context) {
+	user, ok := c.MustGet("currentUser").(database.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	var ledger database.Ledger
+	var err error
+	if header := c.GetHeader("X-Ledger-ID"); header != "" {
+		id, convErr := strconv.ParseUint(header, 10, 32)
+		if convErr != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "invalid X-Ledger-ID; must be an integer greater than 0"})
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		ledger, err = currentStore(c).GetLedger(uint(id), user.ID)
+	} else if id := c.GetUint("tokenLedger"); id != 0 {
+		ledger, err = currentStore(c).GetLedger(id, user.ID)
+	} else {
+		ledger, err = currentStore(c).DefaultLedger(user.ID)
+	}
+
+	if err != nil {
+		if errors.Is(err, database.ErrLedgerNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "ledger not found"})
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	c.Set("scope", database.Scope{Ledger: ledger.ID, User: user.ID, Role: ledger.Role})
+
+	c.Next()
+}
+
+// currentScope returns the scope checkLedger resolved for the request.
+func currentScope(c *gin.Context) database.Scope {
+	scope, _ := c.Get("scope")
+	s, _ := scope.(database.Scope)
+	return s
+}
+
+func getLedgers(c *gin.Context) {
+	user := c.MustGet("currentUser").(database.User)
+
+	ledgers, err := currentStore(c).GetLedgers(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ledgers": ledgers,
+	})
+}
+
+func newLedger(c *gin.Context) {
+	var ledger database.Ledger
+	err := c.BindJSON(&ledger)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	if ledger.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid name; must not be empty",
+		})
+		return
+	}
+
+	user := c.MustGet("currentUser").(database.User)
+	ledger.Owner = user.ID
+
+	id, err := currentStore(c).NewLedger(ledger)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "ledger created",
+		"id":      id,
+	})
+}
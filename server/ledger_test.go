@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLedgerInvalidHeader(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Accounts", func(c *gin.Context) {
+		c.Set("currentUser", database.User{ID: "c0ffee00-0000-0000-0000-000000000000"})
+	}, checkLedger, getAccounts)
+
+	req, _ := http.NewRequest("GET", "/Accounts", nil)
+	req.Header.Set("X-Ledger-ID", "abc")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestNewLedgerEmptyName(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Ledgers", newLedger)
+
+	req, _ := http.NewRequest("POST", "/Ledgers", strings.NewReader(`{"Name": ""}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestCreateUserStartsLedger(t *testing.T) {
+	store := database.NewMemoryStore()
+	r := gin.Default()
+	r.Use(useStore(store))
+	r.POST("/NewUser", createUser)
+
+	req, _ := http.NewRequest("POST", "/NewUser", strings.NewReader(`{"username": "anna", "password": "secret"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	user, err := store.GetUserByName("anna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ledger, err := store.DefaultLedger(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Default", ledger.Name)
+
+	// the name is taken now
+	req, _ = http.NewRequest("POST", "/NewUser", strings.NewReader(`{"username": "anna", "password": "other"}`))
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestCheckLedgerDefault(t *testing.T) {
+	store := database.NewMemoryStore()
+	user := database.User{ID: "c0ffee00-0000-0000-0000-000000000000"}
+	for _, name := range []string{"Books", "Other books"} {
+		_, err := store.NewLedger(database.Ledger{Name: name, Owner: user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.Default()
+	r.Use(useStore(store))
+	r.GET("/Ledger", func(c *gin.Context) {
+		c.Set("currentUser", user)
+	}, checkLedger, func(c *gin.Context) {
+		c.JSON(http.StatusOK, currentScope(c))
+	})
+
+	req, _ := http.NewRequest("GET", "/Ledger", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"Ledger": 1, "User": "c0ffee00-0000-0000-0000-000000000000", "Role": "owner"}`, resp.Body.String())
+
+	// ledgers of someone else are missing
+	req, _ = http.NewRequest("GET", "/Ledger", nil)
+	req.Header.Set("X-Ledger-ID", "3")
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
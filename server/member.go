@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// MemberInput is the body of the member endpoints. Name is only read when a
+// member is invited.
+type MemberInput struct {
+	Name string
+	Role string
+}
+
+// requireRole only lets members with one of the given roles through. It runs
+// after checkLedger, which resolves the role.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := currentScope(c).Role
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"message": "your role in this ledger does not allow this"})
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+var (
+	canBook    = requireRole(database.RoleOwner, database.RoleAccountant)
+	canApprove = requireRole(database.RoleOwner, database.RoleApprover)
+	canManage  = requireRole(database.RoleOwner)
+)
+
+func getMembers(c *gin.Context) {
+	members, err := database.GetMembers(currentDatabase(c), currentScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+	})
+}
+
+func addMember(c *gin.Context) {
+	var input MemberInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	if !database.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": database.ErrInvalidRole.Error(),
+		})
+		return
+	}
+
+	member, err := database.AddMember(currentDatabase(c), currentScope(c), input.Name, input.Role)
+	if err != nil {
+		if errors.Is(err, database.ErrUserNotExists) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "user not found",
+			})
+			return
+		}
+		if errors.Is(err, database.ErrMemberExists) {
+			c.JSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "member added",
+		"member":  member,
+	})
+}
+
+func updateMember(c *gin.Context) {
+	user := c.Param("UserID")
+	if !uuidPattern.MatchString(user) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid user id",
+		})
+		return
+	}
+
+	var input MemberInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	if !database.ValidRole(input.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": database.ErrInvalidRole.Error(),
+		})
+		return
+	}
+
+	err = database.UpdateMember(currentDatabase(c), currentScope(c), user, input.Role)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "member updated",
+	})
+}
+
+func removeMember(c *gin.Context) {
+	user := c.Param("UserID")
+	if !uuidPattern.MatchString(user) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid user id",
+		})
+		return
+	}
+
+	err := database.RemoveMember(currentDatabase(c), currentScope(c), user)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "member removed",
+	})
+}
+
+func respondMemberError(c *gin.Context, err error) {
+	if errors.Is(err, database.ErrMemberNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "member not found",
+		})
+		return
+	}
+	if errors.Is(err, database.ErrLastOwner) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"message": "internal server error",
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRoleViewerCannotBook(t *testing.T) {
+	r := gin.Default()
+	r.POST("/NewTransaction", func(c *gin.Context) {
+		c.Set("scope", database.Scope{Ledger: 1, Role: database.RoleViewer})
+	}, canBook, newTransaction)
+
+	req, _ := http.NewRequest("POST", "/NewTransaction", strings.NewReader(`{}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestRequireRoleAccountantCannotManage(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Members", func(c *gin.Context) {
+		c.Set("scope", database.Scope{Ledger: 1, Role: database.RoleAccountant})
+	}, canManage, addMember)
+
+	req, _ := http.NewRequest("POST", "/Members", strings.NewReader(`{"Name": "Bookkeeper", "Role": "owner"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestAddMemberInvalidRole(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Members", addMember)
+
+	req, _ := http.NewRequest("POST", "/Members", strings.NewReader(`{"Name": "Bookkeeper", "Role": "admin"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestRemoveMemberInvalidUserID(t *testing.T) {
+	r := gin.Default()
+	r.DELETE("/Members/:UserID", removeMember)
+
+	req, _ := http.NewRequest("DELETE", "/Members/1", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
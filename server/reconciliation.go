@@ -0,0 +1,200 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMatchWindow is how many days a bank line and a transaction may be
+// apart to be suggested as a match when no window is asked for.
+const defaultMatchWindow = 5
+
+// MatchInput is the body of the match endpoint: the ids of the bank lines and
+// of the transactions that record them.
+type MatchInput struct {
+	Lines        []uint
+	Transactions []uint
+}
+
+func getMatchSuggestions(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	window := defaultMatchWindow
+	if value := c.Query("window"); value != "" {
+		var err error
+		window, err = strconv.Atoi(value)
+		if err != nil || window < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid window; must be a number of days",
+			})
+			return
+		}
+	}
+
+	suggestions, err := database.GetMatchSuggestions(currentDatabase(c), currentScope(c), account, window)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions": suggestions,
+	})
+}
+
+func getMatches(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	matches, err := database.GetMatches(currentDatabase(c), currentScope(c), account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matches": matches,
+	})
+}
+
+func confirmMatch(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	var input MatchInput
+	err := c.BindJSON(&input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	id, err := database.ConfirmMatch(currentDatabase(c), currentScope(c), uint(account), input.Lines, input.Transactions)
+	if errors.Is(err, database.ErrAccountNotExists) || errors.Is(err, database.ErrBankLineNotExists) || errors.Is(err, database.ErrTransactionNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrMatchEmpty) || errors.Is(err, database.ErrMatchAmount) || errors.Is(err, database.ErrMatchTransaction) || errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrAlreadyMatched) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "match confirmed",
+		"id":      id,
+	})
+}
+
+func deleteMatch(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	id, ok := parseIDParam(c, "MatchID")
+	if !ok {
+		return
+	}
+
+	err := database.DeleteMatch(currentDatabase(c), currentScope(c), account, id)
+	if errors.Is(err, database.ErrMatchNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "match not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "match undone",
+	})
+}
+
+// getReconciliationReport compares the statement balance in the balance
+// query parameter with the balance of the bank account on the date.
+func getReconciliationReport(c *gin.Context) {
+	account, ok := parseAccountParam(c)
+	if !ok {
+		return
+	}
+
+	date, ok := parseDateQuery(c, "date")
+	if !ok {
+		return
+	}
+	if date.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "date is required",
+		})
+		return
+	}
+
+	balance, err := database.ParseMoney(c.Query("balance"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid balance; must be the closing balance of the statement like 1234.56",
+		})
+		return
+	}
+
+	report, err := database.GetReconciliationReport(currentDatabase(c), currentScope(c), account, date, balance)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report": report,
+	})
+}
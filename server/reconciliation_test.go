@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMatchSuggestionsInvalidWindow(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Reconciliation/:AccountID/suggestions", getMatchSuggestions)
+
+	req, _ := http.NewRequest("GET", "/Reconciliation/1200/suggestions?window=-1", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetReconciliationReportMissingBalance(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Reconciliation/:AccountID/report", getReconciliationReport)
+
+	req, _ := http.NewRequest("GET", "/Reconciliation/1200/report?date=2024-03-31", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetReconciliationReportMissingDate(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Reconciliation/:AccountID/report", getReconciliationReport)
+
+	req, _ := http.NewRequest("GET", "/Reconciliation/1200/report?balance=100.00", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/LeRoid-hub/Bookholder-API/exporter"
+	"github.com/gin-gonic/gin"
+)
+
+func getTrialBalance(c *gin.Context) {
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c, true)
+	if !ok {
+		return
+	}
+
+	trialBalance, err := database.GetTrialBalance(currentDatabase(c), currentScope(c), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	if format == exporter.FormatPDF {
+		exportPDF(c, "trial-balance", func(w io.Writer, letterhead exporter.Letterhead) error {
+			return exporter.WriteTrialBalancePDF(w, letterhead, trialBalance)
+		})
+		return
+	}
+	if format != "" {
+		exportTrialBalance(c, format, trialBalance)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trialBalance": trialBalance,
+	})
+}
+
+func getBalanceSheet(c *gin.Context) {
+	asOf, ok := parseDateQuery(c, "asOf")
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c, true)
+	if !ok {
+		return
+	}
+
+	balanceSheet, err := database.GetBalanceSheet(currentDatabase(c), currentScope(c), asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	if format == exporter.FormatPDF {
+		exportPDF(c, "balance-sheet", func(w io.Writer, letterhead exporter.Letterhead) error {
+			return exporter.WriteBalanceSheetPDF(w, letterhead, balanceSheet)
+		})
+		return
+	}
+	if format != "" {
+		exportStatement(c, format, "balance-sheet", []statementPart{
+			{section: "Assets", lines: balanceSheet.Assets.Lines, totalName: "Total assets", total: balanceSheet.Assets.Total},
+			{section: "Liabilities", lines: balanceSheet.Liabilities.Lines, totalName: "Total liabilities", total: balanceSheet.Liabilities.Total},
+			{section: "Equity", lines: balanceSheet.Equity.Lines, totalName: "Total equity", total: balanceSheet.Equity.Total},
+			{totalName: "Total liabilities and equity", total: balanceSheet.TotalLiabilitiesAndEquity},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balanceSheet": balanceSheet,
+	})
+}
+
+func getProfitAndLoss(c *gin.Context) {
+	from, to, ok := parsePeriodQuery(c)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c, true)
+	if !ok {
+		return
+	}
+
+	profitAndLoss, err := database.GetProfitAndLoss(currentDatabase(c), currentScope(c), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	if format == exporter.FormatPDF {
+		exportPDF(c, "profit-and-loss", func(w io.Writer, letterhead exporter.Letterhead) error {
+			return exporter.WriteProfitAndLossPDF(w, letterhead, profitAndLoss)
+		})
+		return
+	}
+	if format != "" {
+		exportStatement(c, format, "profit-and-loss", []statementPart{
+			{section: "Revenue", lines: profitAndLoss.Revenue.Lines, totalName: "Total revenue", total: profitAndLoss.Revenue.Total},
+			{section: "Expenses", lines: profitAndLoss.Expenses.Lines, totalName: "Total expenses", total: profitAndLoss.Expenses.Total},
+			{totalName: "Net income", total: profitAndLoss.NetIncome},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profitAndLoss": profitAndLoss,
+	})
+}
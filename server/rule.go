@@ -0,0 +1,193 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/LeRoid-hub/Bookholder-API/database"
+	"github.com/gin-gonic/gin"
+)
+
+func getRules(c *gin.Context) {
+	rules, err := database.GetRules(currentDatabase(c), currentScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules": rules,
+	})
+}
+
+func getRuleStatistics(c *gin.Context) {
+	statistics, err := database.GetRuleStatistics(currentDatabase(c), currentScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statistics": statistics,
+	})
+}
+
+// bindRule reads a rule from the body and validates it. It answers the
+// request itself and returns false when the rule is not valid.
+func bindRule(c *gin.Context) (database.Rule, bool) {
+	var rule database.Rule
+	err := c.BindJSON(&rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return rule, false
+	}
+
+	err = database.ValidateRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return rule, false
+	}
+	return rule, true
+}
+
+func newRule(c *gin.Context) {
+	rule, ok := bindRule(c)
+	if !ok {
+		return
+	}
+
+	id, err := database.NewRule(currentDatabase(c), currentScope(c), rule)
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "rule created",
+		"id":      id,
+	})
+}
+
+func updateRule(c *gin.Context) {
+	id, ok := parseIDParam(c, "RuleID")
+	if !ok {
+		return
+	}
+
+	rule, ok := bindRule(c)
+	if !ok {
+		return
+	}
+	rule.ID = uint(id)
+
+	err := database.UpdateRule(currentDatabase(c), currentScope(c), rule)
+	if errors.Is(err, database.ErrRuleNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "rule not found",
+		})
+		return
+	}
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "rule updated",
+	})
+}
+
+func deleteRule(c *gin.Context) {
+	id, ok := parseIDParam(c, "RuleID")
+	if !ok {
+		return
+	}
+
+	err := database.DeleteRule(currentDatabase(c), currentScope(c), id)
+	if errors.Is(err, database.ErrRuleNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "rule not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "rule deleted",
+	})
+}
+
+// testRules tells which rule would fire for the sample bank line in the body
+// and what its booking would look like. The statistics are not touched.
+func testRules(c *gin.Context) {
+	var line database.BankLine
+	err := c.BindJSON(&line)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid json",
+		})
+		return
+	}
+
+	rule, ok, err := database.FindRule(currentDatabase(c), currentScope(c), line)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "no rule fires for the line",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "rule " + rule.Name + " fires for the line",
+		"rule":        rule,
+		"transaction": database.BankLineBooking(line, rule, 0, ""),
+	})
+}
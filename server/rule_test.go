@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRuleInvalidCondition(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Rules", newRule)
+
+	req, _ := http.NewRequest("POST", "/Rules", strings.NewReader(`{"Name": "Telekom", "OffsetAccount": 4900, "Conditions": [{"Field": "payee", "Operator": "contains", "Value": "Telekom"}]}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestUpdateRuleInvalidID(t *testing.T) {
+	r := gin.Default()
+	r.PUT("/Rules/:RuleID", updateRule)
+
+	req, _ := http.NewRequest("PUT", "/Rules/abc", strings.NewReader(`{}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestTestRulesInvalidJSON(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Rules/test", testRules)
+
+	req, _ := http.NewRequest("POST", "/Rules/test", strings.NewReader(`{"Amount": "abc"}`))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
@@ -9,37 +9,185 @@ import (
 )
 
 /*
+	GET /Ledgers
+	GET /Members
 	GET /Account/:id
+	GET /Accounts?asOf=
+	GET /ChartTemplates
+	GET /Accounts/:AccountID/balance?asOf=
+	GET /Accounts/:AccountID/ledger?from=&to=
 	GET /Transaction/:TransactionID
-	GET /Transactions/:AccountID/:year
-	Get /Transactions/:AccountID/:year/:month
+	GET /Transaction/:TransactionID/history
+	GET /Transaction/:TransactionID/approvals
+	GET /Transactions/:AccountID/:year?status=
+	Get /Transactions/:AccountID/:year/:month?status=
+	GET /JournalEntry/:EntryID
+	GET /JournalEntry/:EntryID/approvals
+	GET /JournalEntries/:AccountID/:year?status=
+	GET /JournalEntries/:AccountID/:year/:month?status=
+	GET /FiscalYears
+	GET /FiscalYears/:YearID/openingBalances
 	GET /User/:UserID
+	GET /Reports/TrialBalance?from=&to=
+	GET /Reports/BalanceSheet?asOf=
+	GET /Reports/ProfitAndLoss?from=&to=
+	GET /ImportProfiles
+	GET /BankLines/:AccountID?open=
+	GET /Rules
+	GET /Rules/statistics
+	GET /Reconciliation/:AccountID/suggestions?window=
+	GET /Reconciliation/:AccountID/matches
+	GET /Reconciliation/:AccountID/report?date=&balance=
+	GET /Exports/DATEV?from=&to=&advisor=&client=&accountLength=&clearing=&name=&initials=
+	GET /Exports/DATEV/validate?from=&to=&advisor=&client=&accountLength=&clearing=
+	GET /Export/Ledger
+	GET /Audit?entity=&user=&from=&to=
+	GET /Audit/verify
+	POST /Ledgers
+	POST /Import/Ledger
+	POST /Members
 	POST /NewAccount
+	POST /ApplyChartTemplate/:Template
 	POST /NewTransaction
+	POST /Transaction/:TransactionID/submit
+	POST /Transaction/:TransactionID/approve
+	POST /Transaction/:TransactionID/reject
+	POST /Transaction/:TransactionID/post
+	POST /ImportProfiles
+	POST /Imports/:Format/:AccountID?profile=&dateOrder=&preview=
+	POST /BankLines/:LineID/book
+	POST /Rules
+	POST /Rules/test
+	POST /Reconciliation/:AccountID/matches
+	POST /JournalEntries
+	POST /JournalEntry/:EntryID/submit
+	POST /JournalEntry/:EntryID/approve
+	POST /JournalEntry/:EntryID/reject
+	POST /JournalEntry/:EntryID/post
+	POST /FiscalYears
+	POST /FiscalYears/:YearID/close
 	POST /NewUser
+	PUT /Members/:UserID
 	PUT /UpdateAccount/:AccountID
 	PUT /UpdateTransaction/:TransactionID
+	PUT /FiscalPeriods/:PeriodID
+	PUT /Rules/:RuleID
 	PUT /UpdateUser/:UserID
+	DELETE /Members/:UserID
 	DELETE /DeleteAccount/:AccountID
 	DELETE /DeleteTransaction/:TransactionID
+	DELETE /ImportProfiles/:ProfileID
+	DELETE /Rules/:RuleID
+	DELETE /Reconciliation/:AccountID/matches/:MatchID
 	DELETE /DeleteUser/:UserID
 
+	Every route except the user and ledger routes works on one ledger: the one
+	named by the X-Ledger-ID header, else the one the token was issued for,
+	else the oldest ledger of the user.
 
+	New transactions are drafts. They are submitted, approved or rejected by a
+	user other than the one who prepared them, and posted once approved; only
+	posted transactions count. Posted transactions are never changed: updating
+	one reverses it and starts a draft that replaces it, deleting one reverses
+	it. Transactions that are not posted yet are changed, which sends them back
+	to draft, and deleted in place. Journal entries go through the same steps;
+	as they cannot be changed, a rejected entry is replaced by a new one.
+
+	Imported statements are staged as bank lines for the bank account in the
+	path; duplicates of earlier imports are skipped. Booking a bank line
+	creates a draft transaction. The import format is csv, camt053 (ISO 20022
+	XML), mt940 (SWIFT), ofx or qif. CSV imports need an import profile that
+	maps the columns of the bank's export; QIF imports take the order of the
+	date fields, MDY, DMY or YMD, in dateOrder. With preview=true an import
+	writes nothing and answers with the lines it would stage and the draft
+	transactions they would be booked as.
+
+	Rules categorize bank lines by their counterparty, IBAN, remittance text,
+	reference or amount. The rule with the lowest priority that fires suggests
+	the offset account, description and tax code of the booking; rules that
+	assign book the lines of an import as drafts right away. New transactions
+	without an offset account, description or tax code are completed by the
+	rules as well. Rules/test shows which rule fires for a sample line without
+	counting it in the statistics.
+
+	Reconciliation matches the imported bank lines of a bank account with the
+	posted transactions on it: one line with one transaction, one line with
+	several, or several lines with one; both sides have to add up. Suggestions
+	pair lines and transactions of the same amount that are at most window
+	days apart, default 5, and prefer those sharing a reference. The report
+	compares the statement balance on a date with the ledger balance and lists
+	the open lines and transactions that explain the difference.
+
+	The transaction and journal listings, the account ledger and the reports
+	are also downloads: format=csv, jsonl or xlsx, or an Accept header of
+	text/csv, application/x-ndjson or the XLSX media type, picks the format;
+	format=json keeps the usual answer. Listings are written row by row as
+	they are read from the database. The reports and the account ledger are
+	also printed with format=pdf or an Accept header of application/pdf: a
+	paged statement under the name of the ledger, with subtotals.
+
+	Exports/DATEV writes the posted transactions of a period as a DATEV
+	Buchungsstapel (EXTF) for the tax advisor's advisor and client number.
+	Tax codes become BU keys; accounts have accountLength digits, default 4.
+	Posted journal entries follow line by line against the clearing account;
+	closing entries are left out.
+	An export DATEV would reject is answered with the problems instead;
+	Exports/DATEV/validate lists them without exporting.
+
+	Export/Ledger backs a ledger up as a zip archive: a manifest.json with
+	the version of the format, and a JSON Lines file each for the accounts,
+	transactions, journal entries, fiscal years, opening balances, members,
+	rules and import profiles. Import/Ledger restores such an archive as a
+	new ledger of the current user, all of it in one database transaction or
+	nothing. Archives of another version are rejected; members are matched to
+	the users of this installation by name.
+
+	Reading needs any role in the ledger. Booking and changing accounts needs
+	owner or accountant, approving needs owner or approver, managing members
+	and fiscal years and backing the ledger up needs owner. The audit log is
+	open to owners and auditors.
 */
 
-var (
-	Database *sql.DB
-	Env      map[string]string
-)
+var Env map[string]string
+
+// useStore hands store to the handlers of every request; they reach it
+// through currentStore.
+func useStore(store database.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("store", store)
+		c.Next()
+	}
+}
+
+// currentStore returns the store useStore set for the request.
+func currentStore(c *gin.Context) database.Store {
+	return c.MustGet("store").(database.Store)
+}
+
+// useDatabase hands db to the handlers of every request whose work is not
+// part of the Store, such as the workflow, reports, imports and backups; they
+// reach it through currentDatabase. Only the routes of ledgers, accounts,
+// transactions and users can therefore run on a MemoryStore alone.
+func useDatabase(db *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("database", db)
+		c.Next()
+	}
+}
+
+// currentDatabase returns the database useDatabase set for the request.
+func currentDatabase(c *gin.Context) *sql.DB {
+	return c.MustGet("database").(*sql.DB)
+}
 
 func Run(env map[string]string, db *database.DB) {
 	dbase, err := database.New()
 	if err != nil {
 		panic(err)
 	}
-	Database = dbase
 
 	r := gin.Default()
+	r.Use(useStore(database.NewSQLStore(dbase)), useDatabase(dbase))
 
 	// Index
 	r.GET("/", welcome)
@@ -49,19 +197,96 @@ func Run(env map[string]string, db *database.DB) {
 		//Welcome
 		v1.GET("/", welcome)
 
+		//Ledger
+		v1.GET("/Ledgers", checkAuth, getLedgers)
+		v1.POST("/Ledgers", checkAuth, newLedger)
+		v1.GET("/Export/Ledger", checkAuth, checkLedger, canManage, exportLedger)
+		v1.POST("/Import/Ledger", checkAuth, restoreLedger)
+
+		//Member
+		v1.GET("/Members", checkAuth, checkLedger, getMembers)
+		v1.POST("/Members", checkAuth, checkLedger, canManage, addMember)
+		v1.PUT("/Members/:UserID", checkAuth, checkLedger, canManage, updateMember)
+		v1.DELETE("/Members/:UserID", checkAuth, checkLedger, canManage, removeMember)
+
 		//Account
-		v1.GET("/Account/:AccountID", checkAuth, getAccount)
-		v1.POST("/NewAccount", checkAuth, newAccount)
-		v1.PUT("/UpdateAccount", checkAuth, updateAccount)
-		v1.DELETE("/DeleteAccount/:AccountID", checkAuth, deleteAccount)
+		v1.GET("/Account/:AccountID", checkAuth, checkLedger, getAccount)
+		v1.GET("/Accounts", checkAuth, checkLedger, getAccounts)
+		v1.POST("/NewAccount", checkAuth, checkLedger, canBook, newAccount)
+		v1.PUT("/UpdateAccount", checkAuth, checkLedger, canBook, updateAccount)
+		v1.DELETE("/DeleteAccount/:AccountID", checkAuth, checkLedger, canBook, deleteAccount)
+		v1.GET("/Accounts/:AccountID/balance", checkAuth, checkLedger, getBalance)
+		v1.GET("/Accounts/:AccountID/ledger", checkAuth, checkLedger, getAccountLedger)
+		v1.GET("/ChartTemplates", checkAuth, getChartTemplates)
+		v1.POST("/ApplyChartTemplate/:Template", checkAuth, checkLedger, canBook, applyChartTemplate)
 
 		//Transaction
-		v1.GET("/Transaction/:TransactionID", checkAuth, getTransaction)
-		v1.GET("/Transactions/:AccountID/:year", checkAuth, getTransactions)
-		v1.GET("/Transactions/:AccountID/:year/:month", checkAuth, getTransactions)
-		v1.POST("/NewTransaction", checkAuth, newTransaction)
-		v1.PUT("/UpdateTransaction/:TransactionID", checkAuth, updateTransaction)
-		v1.DELETE("/DeleteTransaction/:TransactionID", checkAuth, deleteTransaction)
+		v1.GET("/Transaction/:TransactionID", checkAuth, checkLedger, getTransaction)
+		v1.GET("/Transaction/:TransactionID/history", checkAuth, checkLedger, getTransactionHistory)
+		v1.GET("/Transaction/:TransactionID/approvals", checkAuth, checkLedger, getApprovals)
+		v1.GET("/Transactions/:AccountID/:year", checkAuth, checkLedger, getTransactions)
+		v1.GET("/Transactions/:AccountID/:year/:month", checkAuth, checkLedger, getTransactions)
+		v1.POST("/NewTransaction", checkAuth, checkLedger, canBook, newTransaction)
+		v1.POST("/Transaction/:TransactionID/submit", checkAuth, checkLedger, canBook, submitTransaction)
+		v1.POST("/Transaction/:TransactionID/approve", checkAuth, checkLedger, canApprove, approveTransaction)
+		v1.POST("/Transaction/:TransactionID/reject", checkAuth, checkLedger, canApprove, rejectTransaction)
+		v1.POST("/Transaction/:TransactionID/post", checkAuth, checkLedger, canBook, postTransaction)
+		v1.PUT("/UpdateTransaction/:TransactionID", checkAuth, checkLedger, canBook, updateTransaction)
+		v1.DELETE("/DeleteTransaction/:TransactionID", checkAuth, checkLedger, canBook, deleteTransaction)
+
+		//Journal
+		v1.GET("/JournalEntry/:EntryID", checkAuth, checkLedger, getJournalEntry)
+		v1.GET("/JournalEntries/:AccountID/:year", checkAuth, checkLedger, getJournalEntries)
+		v1.GET("/JournalEntries/:AccountID/:year/:month", checkAuth, checkLedger, getJournalEntries)
+		v1.GET("/JournalEntry/:EntryID/approvals", checkAuth, checkLedger, getJournalEntryApprovals)
+		v1.POST("/JournalEntries", checkAuth, checkLedger, canBook, newJournalEntry)
+		v1.POST("/JournalEntry/:EntryID/submit", checkAuth, checkLedger, canBook, submitJournalEntry)
+		v1.POST("/JournalEntry/:EntryID/approve", checkAuth, checkLedger, canApprove, approveJournalEntry)
+		v1.POST("/JournalEntry/:EntryID/reject", checkAuth, checkLedger, canApprove, rejectJournalEntry)
+		v1.POST("/JournalEntry/:EntryID/post", checkAuth, checkLedger, canBook, postJournalEntry)
+
+		//Fiscal years
+		v1.GET("/FiscalYears", checkAuth, checkLedger, getFiscalYears)
+		v1.POST("/FiscalYears", checkAuth, checkLedger, canManage, newFiscalYear)
+		v1.POST("/FiscalYears/:YearID/close", checkAuth, checkLedger, canManage, closeFiscalYear)
+		v1.GET("/FiscalYears/:YearID/openingBalances", checkAuth, checkLedger, getOpeningBalances)
+		v1.PUT("/FiscalPeriods/:PeriodID", checkAuth, checkLedger, canManage, setPeriodStatus)
+
+		//Reports
+		v1.GET("/Reports/TrialBalance", checkAuth, checkLedger, getTrialBalance)
+		v1.GET("/Reports/BalanceSheet", checkAuth, checkLedger, getBalanceSheet)
+		v1.GET("/Reports/ProfitAndLoss", checkAuth, checkLedger, getProfitAndLoss)
+
+		//Imports
+		v1.GET("/ImportProfiles", checkAuth, checkLedger, getImportProfiles)
+		v1.POST("/ImportProfiles", checkAuth, checkLedger, canBook, newImportProfile)
+		v1.DELETE("/ImportProfiles/:ProfileID", checkAuth, checkLedger, canBook, deleteImportProfile)
+		v1.POST("/Imports/:Format/:AccountID", checkAuth, checkLedger, canBook, importStatement)
+		v1.GET("/BankLines/:AccountID", checkAuth, checkLedger, getBankLines)
+		v1.POST("/BankLines/:LineID/book", checkAuth, checkLedger, canBook, bookBankLine)
+
+		//Rules
+		v1.GET("/Rules", checkAuth, checkLedger, getRules)
+		v1.GET("/Rules/statistics", checkAuth, checkLedger, getRuleStatistics)
+		v1.POST("/Rules", checkAuth, checkLedger, canBook, newRule)
+		v1.POST("/Rules/test", checkAuth, checkLedger, testRules)
+		v1.PUT("/Rules/:RuleID", checkAuth, checkLedger, canBook, updateRule)
+		v1.DELETE("/Rules/:RuleID", checkAuth, checkLedger, canBook, deleteRule)
+
+		//Reconciliation
+		v1.GET("/Reconciliation/:AccountID/suggestions", checkAuth, checkLedger, getMatchSuggestions)
+		v1.GET("/Reconciliation/:AccountID/matches", checkAuth, checkLedger, getMatches)
+		v1.GET("/Reconciliation/:AccountID/report", checkAuth, checkLedger, getReconciliationReport)
+		v1.POST("/Reconciliation/:AccountID/matches", checkAuth, checkLedger, canBook, confirmMatch)
+		v1.DELETE("/Reconciliation/:AccountID/matches/:MatchID", checkAuth, checkLedger, canBook, deleteMatch)
+
+		//Exports
+		v1.GET("/Exports/DATEV", checkAuth, checkLedger, exportDATEV)
+		v1.GET("/Exports/DATEV/validate", checkAuth, checkLedger, validateDATEV)
+
+		//Audit
+		v1.GET("/Audit", checkAuth, checkLedger, canAudit, getAuditLog)
+		v1.GET("/Audit/verify", checkAuth, checkLedger, canAudit, verifyAuditLog)
 
 		//User
 		v1.GET("/User/", checkAuth, getUserProfile)
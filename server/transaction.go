@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -27,7 +28,13 @@ func getTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := database.GetTransaction(Database, idInt)
+	transaction, err := currentStore(c).GetTransaction(currentScope(c), idInt)
+	if errors.Is(err, database.ErrTransactionNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "transaction not found",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -55,6 +62,20 @@ func getTransaction(c *gin.Context) {
 	})
 }
 
+// statusFilter reads the status the bookings of a listing are limited to
+// from the query, "" for every status. It answers a status that does not
+// exist itself and returns false.
+func statusFilter(c *gin.Context) (string, bool) {
+	status := c.Query("status")
+	if status != "" && !database.ValidStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": "invalid status; must be one of draft, submitted, approved, rejected or posted",
+		})
+		return "", false
+	}
+	return status, true
+}
+
 func getTransactions(c *gin.Context) {
 	year := c.Param("year")
 	month := c.Param("month")
@@ -105,7 +126,21 @@ func getTransactions(c *gin.Context) {
 		return
 	}
 
-	transactions, err := database.GetTransactions(Database, accountInt, yearInt, monthInt)
+	status, ok := statusFilter(c)
+	if !ok {
+		return
+	}
+
+	format, ok := exportFormat(c, false)
+	if !ok {
+		return
+	}
+	if format != "" {
+		exportTransactions(c, format, accountInt, yearInt, monthInt, status)
+		return
+	}
+
+	transactions, err := currentStore(c).GetTransactions(currentScope(c), accountInt, yearInt, monthInt, status)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -143,16 +178,57 @@ func newTransaction(c *gin.Context) {
 		return
 	}
 
-	err = database.NewTransaction(Database, transaction)
+	// the rules fill in what the transaction leaves open
+	var rule uint
+	if transaction.OffsetAccount == 0 || transaction.Description == "" || transaction.TaxCode == "" {
+		transaction, rule, err = database.CategorizeTransaction(currentDatabase(c), currentScope(c), transaction)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"message": "internal server error",
+			})
+			return
+		}
+	}
+
+	if transaction.OffsetAccount == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": database.ErrOffsetAccountRequired.Error(),
+		})
+		return
+	}
+
+	// new transactions wait for approval before they are posted
+	transaction.Status = database.StatusDraft
+	id, err := currentStore(c).NewTransaction(currentScope(c), transaction)
+	if isPeriodError(err) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"message": "internal server error: " + err.Error(),
+			"message": "internal server error",
 		})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "transaction created",
+		"id":      id,
+		"rule":    rule,
 	})
 }
 
@@ -173,7 +249,31 @@ func updateTransaction(c *gin.Context) {
 		return
 	}
 
-	err = database.UpdateTransaction(Database, transaction)
+	id, err := currentStore(c).UpdateTransaction(currentScope(c), transaction)
+	if isPeriodError(err) || isReversalError(err) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrTransactionNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "transaction not found",
+		})
+		return
+	}
+	if errors.Is(err, database.ErrAccountNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrGroupAccount) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -183,6 +283,7 @@ func updateTransaction(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "transaction updated",
+		"id":      id,
 	})
 }
 
@@ -204,7 +305,19 @@ func deleteTransaction(c *gin.Context) {
 		return
 	}
 
-	err = database.DeleteTransaction(Database, idInt)
+	reversal, err := currentStore(c).DeleteTransaction(currentScope(c), idInt)
+	if isPeriodError(err) || isReversalError(err) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrTransactionNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "transaction not found",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"message": "internal server error",
@@ -212,7 +325,157 @@ func deleteTransaction(c *gin.Context) {
 		return
 	}
 
+	// posted transactions are not deleted but reversed
+	if reversal != 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "transaction reversed",
+			"reversal": reversal,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "transaction deleted",
 	})
 }
+
+func getTransactionHistory(c *gin.Context) {
+	id, ok := parseIDParam(c, "TransactionID")
+	if !ok {
+		return
+	}
+
+	history, err := database.GetTransactionHistory(currentDatabase(c), currentScope(c), id)
+	if errors.Is(err, database.ErrTransactionNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "transaction not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+	})
+}
+
+// isReversalError reports whether err is a correction of a transaction that
+// cannot be corrected any more.
+func isReversalError(err error) bool {
+	return errors.Is(err, database.ErrTransactionReversed) || errors.Is(err, database.ErrTransactionReversal)
+}
+
+// ApprovalInput is the optional body of the approve and reject endpoints.
+type ApprovalInput struct {
+	Comment string
+}
+
+func submitTransaction(c *gin.Context) {
+	changeTransactionStatus(c, "transaction submitted", func(scope database.Scope, id int, _ string) error {
+		return database.SubmitTransaction(currentDatabase(c), scope, id)
+	})
+}
+
+func approveTransaction(c *gin.Context) {
+	changeTransactionStatus(c, "transaction approved", func(scope database.Scope, id int, comment string) error {
+		return database.ApproveTransaction(currentDatabase(c), scope, id, comment)
+	})
+}
+
+func rejectTransaction(c *gin.Context) {
+	changeTransactionStatus(c, "transaction rejected", func(scope database.Scope, id int, comment string) error {
+		return database.RejectTransaction(currentDatabase(c), scope, id, comment)
+	})
+}
+
+func postTransaction(c *gin.Context) {
+	changeTransactionStatus(c, "transaction posted", func(scope database.Scope, id int, _ string) error {
+		return database.PostTransaction(currentDatabase(c), scope, id)
+	})
+}
+
+// changeTransactionStatus runs one step of the approval workflow on the
+// transaction named in the path and answers the request.
+func changeTransactionStatus(c *gin.Context, message string, change func(scope database.Scope, id int, comment string) error) {
+	changeBookingStatus(c, "TransactionID", database.ErrTransactionNotExists, "transaction not found", message, change)
+}
+
+// changeBookingStatus runs one step of the approval workflow on the booking
+// whose id is in param; notExists is the error for a missing one.
+func changeBookingStatus(c *gin.Context, param string, notExists error, notFound string, message string, change func(scope database.Scope, id int, comment string) error) {
+	id, ok := parseIDParam(c, param)
+	if !ok {
+		return
+	}
+
+	var input ApprovalInput
+	if c.Request.ContentLength > 0 {
+		err := c.BindJSON(&input)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"message": "invalid json",
+			})
+			return
+		}
+	}
+
+	err := change(currentScope(c), id, input.Comment)
+	if errors.Is(err, notExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": notFound,
+		})
+		return
+	}
+	if errors.Is(err, database.ErrOwnApproval) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, database.ErrStatusTransition) || isPeriodError(err) {
+		c.JSON(http.StatusConflict, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
+
+func getApprovals(c *gin.Context) {
+	id, ok := parseIDParam(c, "TransactionID")
+	if !ok {
+		return
+	}
+
+	approvals, err := database.GetApprovals(currentDatabase(c), currentScope(c), id)
+	if errors.Is(err, database.ErrTransactionNotExists) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "transaction not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"message": "internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": approvals,
+	})
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTransactionHistoryInvalidID(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Transaction/:TransactionID/history", getTransactionHistory)
+
+	req, _ := http.NewRequest("GET", "/Transaction/abc/history", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestApproveTransactionInvalidID(t *testing.T) {
+	r := gin.Default()
+	r.POST("/Transaction/:TransactionID/approve", approveTransaction)
+
+	req, _ := http.NewRequest("POST", "/Transaction/0/approve", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestGetTransactionsInvalidStatus(t *testing.T) {
+	r := gin.Default()
+	r.GET("/Transactions/:AccountID/:year/:month", getTransactions)
+
+	req, _ := http.NewRequest("GET", "/Transactions/1200/2024/3?status=booked", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	fmt.Println(resp.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}